@@ -0,0 +1,156 @@
+// Package analysis defines the interface between a modular static
+// analysis and an analysis driver program, for tgo packages.
+//
+// It mirrors golang.org/x/tools/go/analysis, whose analyzers cannot
+// consume the syntax trees and type information of the ast and types
+// packages of this module.
+//
+// An [Analyzer] describes an analysis function and its options, it is
+// applied to a single package at a time by a driver (see package
+// checker). The analysis function is given a [Pass] holding the syntax
+// trees and the type information of the package, it reports
+// [Diagnostic]s and optionally returns a result, that other analyzers
+// of the same package might depend on (see Analyzer.Requires).
+//
+// Analyzers communicate across packages with facts, see [Fact].
+package analysis
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// An Analyzer describes an analysis function and its options.
+type Analyzer struct {
+	// Name of the analyzer, a valid Go identifier that is
+	// unique among the analyzers run together.
+	Name string
+
+	// Doc is the documentation of the analyzer. The first
+	// sentence is a summary.
+	Doc string
+
+	// URL holds an optional link to the documentation of the analyzer.
+	URL string
+
+	// Flags defines the command line flags of the analyzer.
+	Flags flag.FlagSet
+
+	// Run applies the analyzer to a package. It returns an error if
+	// the analyzer failed, otherwise its result, of type ResultType.
+	//
+	// Run must not modify the syntax trees and the type information
+	// of the package, they are shared with other analyzers.
+	Run func(*Pass) (any, error)
+
+	// Requires is the set of analyzers that must run successfully
+	// before this one on the same package. Their results are
+	// available in Pass.ResultOf.
+	Requires []*Analyzer
+
+	// ResultType is the type of the result of Run, nil when
+	// the analyzer has no result.
+	ResultType reflect.Type
+
+	// FactTypes lists the types of facts that the analyzer imports
+	// and exports, each is a pointer type. An analyzer with facts is
+	// also applied to the dependencies of the analyzed packages.
+	FactTypes []Fact
+}
+
+func (a *Analyzer) String() string { return a.Name }
+
+// A Pass provides information to the Run function that applies
+// a specific analyzer to a single package.
+type Pass struct {
+	Analyzer *Analyzer // the identity of the current analyzer
+
+	Fset       *token.FileSet // file position information
+	Files      []*ast.File    // the syntax trees of the package
+	Pkg        *types.Package // the type information of the package
+	TypesInfo  *types.Info    // type information about the syntax trees
+	TypesSizes types.Sizes    // function for computing sizes of types
+
+	// Report reports a Diagnostic, a finding about a specific location
+	// in the analyzed source code.
+	Report func(Diagnostic)
+
+	// ResultOf provides the results of the required analyzers.
+	ResultOf map[*Analyzer]any
+
+	// ImportObjectFact retrieves the fact of the type of fact about obj,
+	// it copies its value to fact and reports whether it exists.
+	ImportObjectFact func(obj types.Object, fact Fact) bool
+
+	// ImportPackageFact retrieves the fact of the type of fact about
+	// pkg, it copies its value to fact and reports whether it exists.
+	ImportPackageFact func(pkg *types.Package, fact Fact) bool
+
+	// ExportObjectFact associates fact with obj, that must belong
+	// to the current package.
+	ExportObjectFact func(obj types.Object, fact Fact)
+
+	// ExportPackageFact associates fact with the current package.
+	ExportPackageFact func(fact Fact)
+
+	// AllObjectFacts returns the object facts of the types of
+	// Analyzer.FactTypes, from this package and its dependencies.
+	AllObjectFacts func() []ObjectFact
+
+	// AllPackageFacts returns the package facts of the types of
+	// Analyzer.FactTypes, from this package and its dependencies.
+	AllPackageFacts func() []PackageFact
+}
+
+// Reportf is a helper function that reports a Diagnostic
+// using the specified position and formatted message.
+func (pass *Pass) Reportf(pos token.Pos, format string, args ...any) {
+	pass.Report(Diagnostic{Pos: pos, Message: fmt.Sprintf(format, args...)})
+}
+
+// A Range represents a range of source code, an ast.Node satisfies it.
+type Range interface {
+	Pos() token.Pos // position of the first character
+	End() token.Pos // position immediately after the last character
+}
+
+// ReportRangef is a helper function that reports a Diagnostic
+// using the range provided and formatted message.
+func (pass *Pass) ReportRangef(rng Range, format string, args ...any) {
+	pass.Report(Diagnostic{Pos: rng.Pos(), End: rng.End(), Message: fmt.Sprintf(format, args...)})
+}
+
+func (pass *Pass) String() string {
+	return fmt.Sprintf("%s@%s", pass.Analyzer.Name, pass.Pkg.Path())
+}
+
+// A Fact is an intermediate fact produced during analysis.
+//
+// Facts are associated with objects or packages, an analyzer exports
+// the facts about the current package, and imports the facts about
+// the packages it depends on. Facts are the only way for analyzers
+// of different packages to communicate.
+//
+// A Fact type must be a pointer type. The driver copies the value
+// of a fact on import, so facts should not hold references to
+// mutable state.
+type Fact interface {
+	AFact() // dummy method to avoid type errors
+}
+
+// An ObjectFact is a fact associated with an object.
+type ObjectFact struct {
+	Object types.Object
+	Fact   Fact
+}
+
+// A PackageFact is a fact associated with a package.
+type PackageFact struct {
+	Package *types.Package
+	Fact    Fact
+}
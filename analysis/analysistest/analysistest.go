@@ -0,0 +1,398 @@
+// Package analysistest provides utilities for testing analyzers.
+//
+// The packages under test are described by a txtar archive (see package
+// internal/txtar), each file belongs to the package named by the
+// directory of the file, e.g. the files "a/a.go" and "a/b.tgo" form the
+// package with the import path "a". Packages of the archive may import
+// each other, as well as the standard library and the tgo package.
+//
+// Expected diagnostics and facts are described by "want" comments in
+// the files of the archive, see [Run].
+package analysistest
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/mateusz834/tgoast/analysis"
+	"github.com/mateusz834/tgoast/analysis/checker"
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/importer"
+	"github.com/mateusz834/tgoast/internal/diff"
+	"github.com/mateusz834/tgoast/internal/tgoimporter"
+	"github.com/mateusz834/tgoast/internal/txtar"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// Testing is an abstraction of a *testing.T.
+type Testing interface {
+	Errorf(format string, args ...any)
+}
+
+// A Result holds the result of the application of an analyzer to
+// a package of the archive.
+type Result = checker.Action
+
+// Run applies the analyzer a to the named packages of the txtar archive
+// in the file archive, and checks the diagnostics and the facts against
+// the "want" comments of the files of the packages.
+//
+// A "want" comment holds a list of expectations about its line, either
+// a quoted regular expression matching the message of a diagnostic
+// reported at the line, or an identifier followed by a colon and a
+// quoted regular expression, matching the string form (as by fmt.Sprint)
+// of a fact exported about the object of that name declared at the line:
+//
+//	f() // want "call of f"
+//	func bad() {} // want bad:"isBad"
+//
+// Each diagnostic and fact must match an expectation, and each
+// expectation must be matched.
+func Run(t Testing, archive string, a *analysis.Analyzer, pkgs ...string) []*Result {
+	l, err := load(archive, pkgs)
+	if err != nil {
+		t.Errorf("%v", err)
+		return nil
+	}
+
+	g, err := checker.Analyze([]*analysis.Analyzer{a}, l.roots)
+	if err != nil {
+		t.Errorf("%v", err)
+		return nil
+	}
+
+	for _, act := range g.Roots {
+		if act.Err != nil {
+			t.Errorf("error analyzing %s: %v", act, act.Err)
+			continue
+		}
+		l.check(t, act)
+	}
+	return g.Roots
+}
+
+// RunWithSuggestedFixes behaves like Run, but additionally applies the
+// suggested fixes of all reported diagnostics, and compares the result
+// with the file of the archive, named as the fixed file, with the
+// ".golden" suffix. Files without a golden file are not checked.
+func RunWithSuggestedFixes(t Testing, archive string, a *analysis.Analyzer, pkgs ...string) []*Result {
+	results := Run(t, archive, a, pkgs...)
+	if results == nil {
+		return nil
+	}
+
+	ar, err := txtar.ParseFile(archive)
+	if err != nil {
+		t.Errorf("%v", err)
+		return nil
+	}
+	files := make(map[string][]byte)
+	for _, f := range ar.Files {
+		files[f.Name] = f.Data
+	}
+
+	edits := make(map[string][]analysis.TextEdit)
+	for _, act := range results {
+		fset := act.Package.Fset
+		for _, d := range act.Diagnostics {
+			for _, fix := range d.SuggestedFixes {
+				for _, edit := range fix.TextEdits {
+					name := fset.File(edit.Pos).Name()
+					edits[name] = append(edits[name], edit)
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		golden, ok := files[name+".golden"]
+		if !ok {
+			continue
+		}
+		got, err := applyEdits(results[0].Package.Fset, files[name], edits[name])
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, golden) {
+			t.Errorf("suggested fixes of %s do not match the golden file:\n%s", name, diff.Diff(name+".golden", golden, name, got))
+		}
+	}
+	return results
+}
+
+// applyEdits applies the edits to src, identical edits are applied once.
+func applyEdits(fset *token.FileSet, src []byte, edits []analysis.TextEdit) ([]byte, error) {
+	slices.SortStableFunc(edits, func(a, b analysis.TextEdit) int {
+		return cmp.Or(cmp.Compare(a.Pos, b.Pos), cmp.Compare(a.End, b.End))
+	})
+	edits = slices.CompactFunc(edits, func(a, b analysis.TextEdit) bool {
+		return a.Pos == b.Pos && a.End == b.End && bytes.Equal(a.NewText, b.NewText)
+	})
+
+	var out []byte
+	last := 0
+	for _, edit := range edits {
+		start, end := fset.Position(edit.Pos).Offset, fset.Position(edit.End).Offset
+		if edit.End == token.NoPos {
+			end = start
+		}
+		if start < last || end < start || end > len(src) {
+			return nil, fmt.Errorf("invalid or overlapping edit at %v", fset.Position(edit.Pos))
+		}
+		out = append(out, src[last:start]...)
+		out = append(out, edit.NewText...)
+		last = end
+	}
+	return append(out, src[last:]...), nil
+}
+
+// A loader type-checks the packages of an archive.
+type loader struct {
+	fset     *token.FileSet
+	files    map[string][]*ast.File // by package path
+	pkgs     map[string]*checker.Package
+	roots    []*checker.Package
+	fallback types.ImporterFrom
+}
+
+func load(archive string, paths []string) (*loader, error) {
+	ar, err := txtar.ParseFile(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{
+		fset:     token.NewFileSet(),
+		files:    make(map[string][]*ast.File),
+		pkgs:     make(map[string]*checker.Package),
+		fallback: &tgoimporter.TgoDefaultImporter{I: importer.Default().(types.ImporterFrom)},
+	}
+	for _, f := range ar.Files {
+		if ext := path.Ext(f.Name); ext != ".go" && ext != ".tgo" {
+			continue
+		}
+		file, err := parser.ParseFile(l.fset, f.Name, f.Data, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(f.Name)
+		l.files[dir] = append(l.files[dir], file)
+	}
+
+	for _, p := range paths {
+		pkg, err := l.loadPackage(p, nil)
+		if err != nil {
+			return nil, err
+		}
+		l.roots = append(l.roots, pkg)
+	}
+	return l, nil
+}
+
+// loadPackage type-checks the package of the archive with the path p,
+// stack holds the paths of the packages being loaded.
+func (l *loader) loadPackage(p string, stack []string) (*checker.Package, error) {
+	if pkg, ok := l.pkgs[p]; ok {
+		return pkg, nil
+	}
+	files, ok := l.files[p]
+	if !ok {
+		return nil, fmt.Errorf("package %q not found in the archive", p)
+	}
+	if slices.Contains(stack, p) {
+		return nil, fmt.Errorf("import cycle: %s -> %s", strings.Join(stack, " -> "), p)
+	}
+	stack = append(stack, p)
+
+	pkg := &checker.Package{
+		Fset:  l.fset,
+		Files: files,
+		TypesInfo: &types.Info{
+			Types:          make(map[ast.Expr]types.TypeAndValue),
+			Instances:      make(map[*ast.Ident]types.Instance),
+			Defs:           make(map[*ast.Ident]types.Object),
+			Uses:           make(map[*ast.Ident]types.Object),
+			Implicits:      make(map[ast.Node]types.Object),
+			Selections:     make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:         make(map[ast.Node]*types.Scope),
+			FileVersions:   make(map[*ast.File]string),
+			Interpolations: make(map[*ast.TemplateLiteralPart]types.Interpolation),
+		},
+		TypesSizes: types.SizesFor("gc", "amd64"),
+	}
+
+	for _, f := range files {
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if _, ok := l.files[path]; !ok {
+				continue
+			}
+			imp, err := l.loadPackage(path, stack)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(pkg.Imports, imp) {
+				pkg.Imports = append(pkg.Imports, imp)
+			}
+		}
+	}
+
+	conf := types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if imp, ok := l.pkgs[path]; ok {
+				return imp.Types, nil
+			}
+			return l.fallback.ImportFrom(path, "", 0)
+		}),
+		Sizes: pkg.TypesSizes,
+	}
+	var err error
+	pkg.Types, err = conf.Check(p, l.fset, files, pkg.TypesInfo)
+	if err != nil {
+		return nil, err
+	}
+	l.pkgs[p] = pkg
+	return pkg, nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// An expectation is an expected diagnostic (name == "") or fact.
+type expectation struct {
+	name string
+	re   *regexp.Regexp
+}
+
+type lineKey struct {
+	file string
+	line int
+}
+
+// check checks the diagnostics and the facts of act against
+// the "want" comments of its package.
+func (l *loader) check(t Testing, act *checker.Action) {
+	want := make(map[lineKey][]expectation)
+	for _, f := range act.Package.Files {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				text, ok := strings.CutPrefix(c.Text, "//")
+				if !ok {
+					text = strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+				}
+				text, ok = strings.CutPrefix(strings.TrimSpace(text), "want ")
+				if !ok {
+					continue
+				}
+				posn := l.fset.Position(c.Pos())
+				exps, err := parseExpectations(text)
+				if err != nil {
+					t.Errorf("%s: in want comment: %v", posn, err)
+					continue
+				}
+				k := lineKey{posn.Filename, posn.Line}
+				want[k] = append(want[k], exps...)
+			}
+		}
+	}
+
+	match := func(posn token.Position, name, text string) bool {
+		k := lineKey{posn.Filename, posn.Line}
+		exps := want[k]
+		for i, exp := range exps {
+			if exp.name == name && exp.re.MatchString(text) {
+				want[k] = slices.Delete(exps, i, i+1)
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range act.Diagnostics {
+		posn := l.fset.Position(d.Pos)
+		if !match(posn, "", d.Message) {
+			t.Errorf("%v: unexpected diagnostic: %v", posn, d.Message)
+		}
+	}
+
+	facts := act.ObjectFacts()
+	slices.SortFunc(facts, func(a, b analysis.ObjectFact) int {
+		return cmp.Compare(a.Object.Pos(), b.Object.Pos())
+	})
+	for _, f := range facts {
+		if f.Object.Pkg() != act.Package.Types {
+			continue
+		}
+		posn := l.fset.Position(f.Object.Pos())
+		if !match(posn, f.Object.Name(), fmt.Sprint(f.Fact)) {
+			t.Errorf("%v: unexpected fact about %s: %v", posn, f.Object.Name(), f.Fact)
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(want), func(a, b lineKey) int {
+		return cmp.Or(strings.Compare(a.file, b.file), cmp.Compare(a.line, b.line))
+	})
+	for _, k := range keys {
+		for _, exp := range want[k] {
+			if exp.name == "" {
+				t.Errorf("%s:%d: no diagnostic was reported matching %#q", k.file, k.line, exp.re)
+			} else {
+				t.Errorf("%s:%d: no fact was exported about %s matching %#q", k.file, k.line, exp.name, exp.re)
+			}
+		}
+	}
+}
+
+// parseExpectations parses the text of a want comment (after "want").
+func parseExpectations(text string) ([]expectation, error) {
+	var s scanner.Scanner
+	s.Init(strings.NewReader(text))
+	s.Mode = scanner.ScanIdents | scanner.ScanStrings | scanner.ScanRawStrings
+	s.Error = func(*scanner.Scanner, string) {}
+
+	var exps []expectation
+	for {
+		var name string
+		tok := s.Scan()
+		switch tok {
+		case scanner.EOF:
+			if exps == nil {
+				return nil, fmt.Errorf("no expectations")
+			}
+			return exps, nil
+		case scanner.Ident:
+			name = s.TokenText()
+			if s.Scan() != ':' {
+				return nil, fmt.Errorf("want ':' after %s, got %s", name, s.TokenText())
+			}
+			tok = s.Scan()
+		}
+		if tok != scanner.String && tok != scanner.RawString {
+			return nil, fmt.Errorf("want a quoted regular expression, got %s", s.TokenText())
+		}
+		pattern, err := strconv.Unquote(s.TokenText())
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		exps = append(exps, expectation{name, re})
+	}
+}
@@ -0,0 +1,102 @@
+package analysistest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/analysis"
+	"github.com/mateusz834/tgoast/analysis/analysistest"
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// isBad is a fact about functions that are bad, either their name
+// starts with "bad", or they call a bad function.
+type isBad struct{ cause string }
+
+func (*isBad) AFact() {}
+
+func (f *isBad) String() string { return "isBad(" + f.cause + ")" }
+
+var badcall = &analysis.Analyzer{
+	Name:      "badcall",
+	Doc:       "reports calls of bad functions",
+	FactTypes: []analysis.Fact{new(isBad)},
+	Run: func(pass *analysis.Pass) (any, error) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				fn := pass.TypesInfo.Defs[decl.Name]
+				if strings.HasPrefix(fn.Name(), "bad") {
+					pass.ExportObjectFact(fn, &isBad{"name"})
+				}
+				ast.Inspect(decl.Body, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					var id *ast.Ident
+					switch fun := call.Fun.(type) {
+					case *ast.Ident:
+						id = fun
+					case *ast.SelectorExpr:
+						id = fun.Sel
+					default:
+						return true
+					}
+					callee, ok := pass.TypesInfo.Uses[id].(*types.Func)
+					if !ok || !pass.ImportObjectFact(callee, new(isBad)) {
+						return true
+					}
+					if !strings.HasPrefix(fn.Name(), "bad") {
+						pass.ExportObjectFact(fn, &isBad{"calls " + callee.Name()})
+					}
+					pass.Report(analysis.Diagnostic{
+						Pos:     call.Pos(),
+						End:     call.End(),
+						Message: fmt.Sprintf("call of bad function %s", callee.Name()),
+						SuggestedFixes: []analysis.SuggestedFix{{
+							Message: "Remove the call",
+							TextEdits: []analysis.TextEdit{{
+								Pos: call.Pos(),
+								End: call.End(),
+							}},
+						}},
+					})
+					return true
+				})
+			}
+		}
+		return nil, nil
+	},
+}
+
+func TestRun(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, "testdata/badcall.txtar", badcall, "b")
+}
+
+type recorder struct {
+	errs []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestRunMismatch(t *testing.T) {
+	var r recorder
+	analysistest.Run(&r, "testdata/mismatch.txtar", badcall, "a")
+	want := []string{
+		`a/a.go:7:2: unexpected diagnostic: call of bad function badF`,
+		`a/a.go:5:6: unexpected fact about g: isBad(calls badF)`,
+		"a/a.go:11: no diagnostic was reported matching `other`",
+		"a/a.go:11: no fact was exported about h matching `isBad`",
+	}
+	if strings.Join(r.errs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(r.errs, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,277 @@
+// Package checker provides an analysis driver, it applies analyzers to
+// type-checked packages and their dependencies.
+//
+// The packages are type-checked by the caller, facts are shared across
+// packages by the identity of their objects, so the type information of
+// a package must refer to the same *types.Package objects as the type
+// information of the packages in its Imports.
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/mateusz834/tgoast/analysis"
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// A Package is a type-checked package.
+type Package struct {
+	Fset       *token.FileSet
+	Files      []*ast.File
+	Types      *types.Package
+	TypesInfo  *types.Info
+	TypesSizes types.Sizes
+
+	// Imports are the packages imported by this package, that are
+	// analyzed by the analyzers with facts. Imported packages that
+	// are not listed here, e.g. the standard library, have no facts.
+	Imports []*Package
+}
+
+// An Action represents the application of an analyzer to a package.
+type Action struct {
+	Analyzer *analysis.Analyzer
+	Package  *Package
+
+	// IsRoot reports whether the action was requested by the
+	// caller of Analyze, rather than being a dependency of one.
+	IsRoot bool
+
+	Result      any   // the result of Analyzer.Run, valid when Err == nil
+	Err         error // the error returned by Analyzer.Run or a dependency
+	Diagnostics []analysis.Diagnostic
+
+	// Deps are the actions that must complete before this one, the
+	// actions of Analyzer.Requires on the same package, followed by
+	// the actions of Analyzer on Package.Imports, if it has facts.
+	Deps []*Action
+
+	done         bool
+	objectFacts  map[objectFactKey]analysis.Fact
+	packageFacts map[packageFactKey]analysis.Fact
+}
+
+type objectFactKey struct {
+	obj types.Object
+	typ reflect.Type
+}
+
+type packageFactKey struct {
+	pkg *types.Package
+	typ reflect.Type
+}
+
+func (act *Action) String() string {
+	return fmt.Sprintf("%s@%s", act.Analyzer, act.Package.Types.Path())
+}
+
+// A Graph holds the actions of an analysis.
+type Graph struct {
+	// Roots are the actions requested by the caller of Analyze, one
+	// per analyzer and package, in the order of the packages and,
+	// for each package, of the analyzers.
+	Roots []*Action
+}
+
+// Analyze applies the analyzers to the packages, and the analyzers
+// with facts also to their dependencies. It returns an error only
+// when the analyzers are misconfigured, the errors of the analyzers
+// are recorded in the actions.
+func Analyze(analyzers []*analysis.Analyzer, pkgs []*Package) (*Graph, error) {
+	if err := analysis.Validate(analyzers); err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		a   *analysis.Analyzer
+		pkg *Package
+	}
+	actions := make(map[key]*Action)
+	var mkAction func(a *analysis.Analyzer, pkg *Package) *Action
+	mkAction = func(a *analysis.Analyzer, pkg *Package) *Action {
+		k := key{a, pkg}
+		if act, ok := actions[k]; ok {
+			return act
+		}
+		act := &Action{Analyzer: a, Package: pkg}
+		actions[k] = act
+		for _, req := range a.Requires {
+			act.Deps = append(act.Deps, mkAction(req, pkg))
+		}
+		if len(a.FactTypes) > 0 {
+			for _, imp := range pkg.Imports {
+				act.Deps = append(act.Deps, mkAction(a, imp))
+			}
+		}
+		return act
+	}
+
+	g := new(Graph)
+	for _, pkg := range pkgs {
+		for _, a := range analyzers {
+			act := mkAction(a, pkg)
+			act.IsRoot = true
+			g.Roots = append(g.Roots, act)
+		}
+	}
+	for _, act := range g.Roots {
+		act.exec()
+	}
+	return g, nil
+}
+
+// exec executes the action after its dependencies.
+func (act *Action) exec() {
+	if act.done {
+		return
+	}
+	act.done = true
+
+	var failed []string
+	for _, dep := range act.Deps {
+		dep.exec()
+		if dep.Err != nil {
+			failed = append(failed, dep.String())
+		}
+	}
+	if failed != nil {
+		slices.Sort(failed)
+		act.Err = fmt.Errorf("failed prerequisites: %s", strings.Join(failed, ", "))
+		return
+	}
+
+	act.objectFacts = make(map[objectFactKey]analysis.Fact)
+	act.packageFacts = make(map[packageFactKey]analysis.Fact)
+	resultOf := make(map[*analysis.Analyzer]any)
+	for _, dep := range act.Deps {
+		if dep.Package == act.Package {
+			resultOf[dep.Analyzer] = dep.Result
+			continue
+		}
+		// Inherit the facts of the dependency.
+		for k, v := range dep.objectFacts {
+			act.objectFacts[k] = v
+		}
+		for k, v := range dep.packageFacts {
+			act.packageFacts[k] = v
+		}
+	}
+
+	pkg := act.Package
+	pass := &analysis.Pass{
+		Analyzer:   act.Analyzer,
+		Fset:       pkg.Fset,
+		Files:      pkg.Files,
+		Pkg:        pkg.Types,
+		TypesInfo:  pkg.TypesInfo,
+		TypesSizes: pkg.TypesSizes,
+		ResultOf:   resultOf,
+		Report: func(d analysis.Diagnostic) {
+			act.Diagnostics = append(act.Diagnostics, d)
+		},
+		ImportObjectFact:  act.importObjectFact,
+		ImportPackageFact: act.importPackageFact,
+		ExportObjectFact:  act.exportObjectFact,
+		ExportPackageFact: act.exportPackageFact,
+		AllObjectFacts:    act.allObjectFacts,
+		AllPackageFacts:   act.allPackageFacts,
+	}
+
+	act.Result, act.Err = act.Analyzer.Run(pass)
+	if act.Err == nil {
+		if got, want := reflect.TypeOf(act.Result), act.Analyzer.ResultType; got != want {
+			act.Err = fmt.Errorf("internal error: on package %s, analyzer %s returned a result of type %v, but declared ResultType %v",
+				pkg.Types.Path(), act.Analyzer, got, want)
+		}
+	}
+}
+
+// factType returns the type of fact, it panics when the analyzer
+// does not declare it.
+func (act *Action) factType(fact analysis.Fact) reflect.Type {
+	t := reflect.TypeOf(fact)
+	for _, f := range act.Analyzer.FactTypes {
+		if reflect.TypeOf(f) == t {
+			return t
+		}
+	}
+	panic(fmt.Sprintf("analyzer %s: invalid Fact type: got %T, want one of %T", act.Analyzer, fact, act.Analyzer.FactTypes))
+}
+
+// copyFact copies the value of the fact src to dst.
+func copyFact(dst, src analysis.Fact) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
+
+func (act *Action) importObjectFact(obj types.Object, fact analysis.Fact) bool {
+	if obj == nil {
+		panic("nil object")
+	}
+	if v, ok := act.objectFacts[objectFactKey{obj, act.factType(fact)}]; ok {
+		copyFact(fact, v)
+		return true
+	}
+	return false
+}
+
+func (act *Action) exportObjectFact(obj types.Object, fact analysis.Fact) {
+	if obj.Pkg() != act.Package.Types {
+		panic(fmt.Sprintf("analyzer %s: cannot export a fact about %s, that does not belong to %s",
+			act.Analyzer, obj, act.Package.Types.Path()))
+	}
+	act.objectFacts[objectFactKey{obj, act.factType(fact)}] = fact
+}
+
+func (act *Action) importPackageFact(pkg *types.Package, fact analysis.Fact) bool {
+	if pkg == nil {
+		panic("nil package")
+	}
+	if v, ok := act.packageFacts[packageFactKey{pkg, act.factType(fact)}]; ok {
+		copyFact(fact, v)
+		return true
+	}
+	return false
+}
+
+func (act *Action) exportPackageFact(fact analysis.Fact) {
+	act.packageFacts[packageFactKey{act.Package.Types, act.factType(fact)}] = fact
+}
+
+func (act *Action) allObjectFacts() []analysis.ObjectFact {
+	facts := make([]analysis.ObjectFact, 0, len(act.objectFacts))
+	for k, fact := range act.objectFacts {
+		facts = append(facts, analysis.ObjectFact{Object: k.obj, Fact: fact})
+	}
+	return facts
+}
+
+func (act *Action) allPackageFacts() []analysis.PackageFact {
+	facts := make([]analysis.PackageFact, 0, len(act.packageFacts))
+	for k, fact := range act.packageFacts {
+		facts = append(facts, analysis.PackageFact{Package: k.pkg, Fact: fact})
+	}
+	return facts
+}
+
+// ObjectFacts returns the object facts exported by the action and
+// inherited from its dependencies.
+func (act *Action) ObjectFacts() []analysis.ObjectFact {
+	return act.allObjectFacts()
+}
+
+// Errors returns the errors of the root actions of g, joined.
+func (g *Graph) Errors() error {
+	var errs []error
+	for _, act := range g.Roots {
+		if act.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", act, act.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
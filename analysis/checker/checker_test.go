@@ -0,0 +1,91 @@
+package checker_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mateusz834/tgoast/analysis"
+	"github.com/mateusz834/tgoast/analysis/checker"
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+func TestAnalyze(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", "package a\n\nfunc f() {}\nfunc g() {}\n", parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	pkg, err := new(types.Config).Check("a", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := &analysis.Analyzer{
+		Name:       "funcs",
+		Doc:        "counts the functions",
+		ResultType: reflect.TypeFor[int](),
+		Run: func(pass *analysis.Pass) (any, error) {
+			n := 0
+			for _, f := range pass.Files {
+				for _, d := range f.Decls {
+					if _, ok := d.(*ast.FuncDecl); ok {
+						n++
+					}
+				}
+			}
+			return n, nil
+		},
+	}
+	report := &analysis.Analyzer{
+		Name:     "report",
+		Doc:      "reports the number of functions",
+		Requires: []*analysis.Analyzer{funcs},
+		Run: func(pass *analysis.Pass) (any, error) {
+			pass.Reportf(pass.Files[0].Package, "%d functions", pass.ResultOf[funcs].(int))
+			return nil, nil
+		},
+	}
+	errFailed := errors.New("failed")
+	failing := &analysis.Analyzer{
+		Name: "failing",
+		Doc:  "fails",
+		Run:  func(*analysis.Pass) (any, error) { return nil, errFailed },
+	}
+	dependent := &analysis.Analyzer{
+		Name:     "dependent",
+		Doc:      "requires failing",
+		Requires: []*analysis.Analyzer{failing},
+		Run: func(*analysis.Pass) (any, error) {
+			t.Error("dependent analyzer ran after a failed prerequisite")
+			return nil, nil
+		},
+	}
+
+	g, err := checker.Analyze([]*analysis.Analyzer{report, dependent}, []*checker.Package{{
+		Fset:      fset,
+		Files:     []*ast.File{f},
+		Types:     pkg,
+		TypesInfo: info,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.Roots) != 2 {
+		t.Fatalf("len(g.Roots) = %d; want 2", len(g.Roots))
+	}
+	if act := g.Roots[0]; act.Err != nil || len(act.Diagnostics) != 1 || act.Diagnostics[0].Message != "2 functions" {
+		t.Errorf("report: err = %v, diagnostics = %v", act.Err, act.Diagnostics)
+	}
+	if err, want := g.Roots[1].Err, "failed prerequisites: failing@a"; err == nil || err.Error() != want {
+		t.Errorf("dependent: err = %v; want %v", err, want)
+	}
+	if err := g.Errors(); err == nil || err.Error() != "dependent@a: failed prerequisites: failing@a" {
+		t.Errorf("g.Errors() = %v", err)
+	}
+}
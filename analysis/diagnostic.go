@@ -0,0 +1,53 @@
+package analysis
+
+import "github.com/mateusz834/tgoast/token"
+
+// A Diagnostic is a message associated with a source location or range.
+//
+// An Analyzer may return a variety of diagnostics, Category can be used
+// to distinguish them, it should be a valid Go identifier.
+type Diagnostic struct {
+	Pos      token.Pos
+	End      token.Pos // optional
+	Category string    // optional
+	Message  string
+
+	// URL is the optional location of a web page that
+	// explains the diagnostic, it defaults to Analyzer.URL.
+	URL string
+
+	// SuggestedFixes lists the alternative fixes of the diagnostic.
+	SuggestedFixes []SuggestedFix
+
+	// Related lists the locations related to the diagnostic.
+	Related []RelatedInformation
+}
+
+// RelatedInformation contains information related to a diagnostic,
+// e.g. the location of a previous declaration.
+type RelatedInformation struct {
+	Pos     token.Pos
+	End     token.Pos // optional
+	Message string
+}
+
+// A SuggestedFix is a code change associated with a Diagnostic,
+// that a user can choose to apply to their code. Usually the
+// suggested fix is meant to resolve the diagnostic.
+//
+// The TextEdits must not overlap.
+type SuggestedFix struct {
+	// Message describes the fix, e.g. "Remove the attribute".
+	Message   string
+	TextEdits []TextEdit
+}
+
+// A TextEdit represents the replacement of the code
+// between Pos and End with the new text.
+// Each TextEdit should apply to a single file.
+// End should not be earlier in the file than Pos.
+type TextEdit struct {
+	Pos     token.Pos
+	End     token.Pos // equal to Pos for insertions
+	NewText []byte
+}
@@ -0,0 +1,295 @@
+// Package a11y defines analyzers that report accessibility problems
+// in tgo markup.
+//
+// The analyzers inspect elements with static tag names, the attributes
+// of an element are tracked across the control-flow paths of its open
+// tag, so an attribute set only in one branch of an if statement is
+// reported as missing on some paths.
+package a11y
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mateusz834/tgoast/analysis"
+	"github.com/mateusz834/tgoast/ast"
+)
+
+// Analyzers are all analyzers of the package.
+var Analyzers = []*analysis.Analyzer{
+	ImgAlt,
+	FormLabel,
+	AnchorHref,
+	HeadingOrder,
+	HTMLLang,
+	NestedInteractive,
+}
+
+// ImgAlt reports <img> elements without the @alt attribute.
+var ImgAlt = &analysis.Analyzer{
+	Name: "imgalt",
+	Doc: `report <img> elements without @alt
+
+An image without a text alternative is not accessible to screen reader
+users, decorative images should have an empty @alt attribute.`,
+	Run: func(pass *analysis.Pass) (any, error) {
+		requireAttr(pass, "img", "alt", `""`)
+		return nil, nil
+	},
+}
+
+// AnchorHref reports <a> elements without the @href attribute.
+var AnchorHref = &analysis.Analyzer{
+	Name: "anchorhref",
+	Doc: `report <a> elements without @href
+
+An anchor without @href is not focusable and is not announced as a link,
+use a <button> for actions.`,
+	Run: func(pass *analysis.Pass) (any, error) {
+		requireAttr(pass, "a", "href", "")
+		return nil, nil
+	},
+}
+
+// HTMLLang reports <html> elements without the @lang attribute.
+var HTMLLang = &analysis.Analyzer{
+	Name: "htmllang",
+	Doc: `report <html> elements without @lang
+
+Screen readers use the language of the document to pick
+the pronunciation.`,
+	Run: func(pass *analysis.Pass) (any, error) {
+		requireAttr(pass, "html", "lang", `"en"`)
+		return nil, nil
+	},
+}
+
+// requireAttr reports the elements named tag without the attribute
+// attr. A suggested fix adds the attribute with the value fixValue,
+// unless it is empty.
+func requireAttr(pass *analysis.Pass, tag, attr, fixValue string) {
+	inspectElements(pass, func(t *ast.OpenTag, _ []*ast.OpenTag) {
+		if tagName(t) != tag {
+			return
+		}
+		attrs := openTagAttrs(t)
+		switch {
+		case attrs.has(attr):
+		case attrs.mayHave(attr):
+			pass.ReportRangef(t.Name, "@%s attribute of <%s> element is not set on all control-flow paths", attr, tag)
+		default:
+			d := analysis.Diagnostic{
+				Pos:     t.Name.Pos(),
+				End:     t.Name.End(),
+				Message: fmt.Sprintf("<%s> element without @%s attribute", tag, attr),
+			}
+			if fixValue != "" {
+				d.SuggestedFixes = []analysis.SuggestedFix{{
+					Message: fmt.Sprintf("Add @%s=%s", attr, fixValue),
+					TextEdits: []analysis.TextEdit{{
+						Pos:     t.Name.End(),
+						End:     t.Name.End(),
+						NewText: []byte(fmt.Sprintf(" @%s=%s", attr, fixValue)),
+					}},
+				}}
+			}
+			pass.Report(d)
+		}
+	})
+}
+
+// FormLabel reports form controls without an associated <label>.
+var FormLabel = &analysis.Analyzer{
+	Name: "formlabel",
+	Doc: `report form controls without an associated <label>
+
+A form control (<input> or <textarea>) is associated with a <label>
+when it is nested inside of the label. A @title attribute is accepted
+as the accessible name of the control as well.`,
+	Run: runFormLabel,
+}
+
+func runFormLabel(pass *analysis.Pass) (any, error) {
+	inspectElements(pass, func(t *ast.OpenTag, stack []*ast.OpenTag) {
+		tag := tagName(t)
+		if !isFormControl(t) {
+			return
+		}
+		for _, outer := range stack {
+			if tagName(outer) == "label" {
+				return
+			}
+		}
+		if openTagAttrs(t).has("title") {
+			return
+		}
+		pass.ReportRangef(t.Name, "<%s> form control without an associated <label>", tag)
+	})
+	return nil, nil
+}
+
+// isFormControl reports whether t is the open tag of a labelable form control.
+func isFormControl(t *ast.OpenTag) bool {
+	switch tagName(t) {
+	case "input", "textarea":
+		return true
+	}
+	return false
+}
+
+// HeadingOrder reports headings that skip levels.
+var HeadingOrder = &analysis.Analyzer{
+	Name: "headingorder",
+	Doc: `report headings that skip levels
+
+Within a function, a heading (<h1> to <h6>) must not be more than
+one level deeper than the previous heading, e.g. an <h4> must not
+follow an <h2>. Screen reader users navigate pages by headings.`,
+	Run: func(pass *analysis.Pass) (any, error) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				if decl, ok := decl.(*ast.FuncDecl); ok && decl.Body != nil {
+					headingOrder(pass, decl.Body)
+				}
+			}
+		}
+		return nil, nil
+	},
+}
+
+func headingOrder(pass *analysis.Pass, body *ast.BlockStmt) {
+	prev := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		el, ok := n.(*ast.ElementBlockStmt)
+		if !ok {
+			return true
+		}
+		level := headingLevel(tagName(el.OpenTag))
+		if level == 0 {
+			return true
+		}
+		if prev != 0 && level > prev+1 {
+			want := fmt.Sprintf("h%d", prev+1)
+			edits := []analysis.TextEdit{{
+				Pos:     el.OpenTag.Name.Pos(),
+				End:     el.OpenTag.Name.End(),
+				NewText: []byte(want),
+			}}
+			if name := el.EndTag.Name; name != nil {
+				edits = append(edits, analysis.TextEdit{
+					Pos:     name.Pos(),
+					End:     name.End(),
+					NewText: []byte(want),
+				})
+			}
+			pass.Report(analysis.Diagnostic{
+				Pos:     el.OpenTag.Name.Pos(),
+				End:     el.OpenTag.Name.End(),
+				Message: fmt.Sprintf("heading level skipped: <h%d> follows <h%d>", level, prev),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message:   fmt.Sprintf("Change to <%s>", want),
+					TextEdits: edits,
+				}},
+			})
+		}
+		prev = level
+		return true
+	})
+}
+
+// headingLevel returns the level of the heading tag, or 0.
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && '1' <= tag[1] && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+// NestedInteractive reports interactive elements nested
+// inside of interactive elements.
+var NestedInteractive = &analysis.Analyzer{
+	Name: "nestedinteractive",
+	Doc: `report interactive elements nested inside of interactive elements
+
+Interactive content (e.g. <button> inside of <a>) must not be nested,
+browsers and assistive technologies handle it inconsistently. Form
+controls nested inside of a <label> are permitted.`,
+	Run: func(pass *analysis.Pass) (any, error) {
+		inspectElements(pass, func(t *ast.OpenTag, stack []*ast.OpenTag) {
+			if !isInteractive(t) {
+				return
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				outer := stack[i]
+				if !isInteractive(outer) {
+					continue
+				}
+				if tagName(outer) == "label" && isLabelable(tagName(t)) {
+					return
+				}
+				pass.ReportRangef(t.Name, "interactive element <%s> nested inside of interactive element <%s>", tagName(t), tagName(outer))
+				return
+			}
+		})
+		return nil, nil
+	},
+}
+
+// isInteractive reports whether t is the open tag of interactive content.
+func isInteractive(t *ast.OpenTag) bool {
+	switch tag := tagName(t); tag {
+	case "a", "button", "details", "embed", "iframe", "input", "label", "select", "textarea":
+		return true
+	}
+	return false
+}
+
+// isLabelable reports whether the elements named tag can be
+// associated with a <label>.
+func isLabelable(tag string) bool {
+	switch tag {
+	case "button", "input", "meter", "output", "progress", "select", "textarea":
+		return true
+	}
+	return false
+}
+
+// tagName returns the lower-cased static tag name of the open tag t,
+// or "" for dynamic tag names.
+func tagName(t *ast.OpenTag) string {
+	if t.Name == nil {
+		return ""
+	}
+	return strings.ToLower(t.Name.Name)
+}
+
+// inspectElements calls f for the open tag of each element of the files
+// of the package, including the void elements (e.g. <img>) without an
+// end tag, with the open tags of the enclosing elements (outermost first)
+// of the same function.
+func inspectElements(pass *analysis.Pass, f func(t *ast.OpenTag, stack []*ast.OpenTag)) {
+	for _, file := range pass.Files {
+		ast.Walk(elementVisitor{f: f}, file)
+	}
+}
+
+type elementVisitor struct {
+	stack []*ast.OpenTag
+	f     func(t *ast.OpenTag, stack []*ast.OpenTag)
+}
+
+func (v elementVisitor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		return elementVisitor{f: v.f}
+	case *ast.ElementBlockStmt:
+		v.f(n.OpenTag, v.stack)
+		return elementVisitor{stack: append(v.stack[:len(v.stack):len(v.stack)], n.OpenTag), f: v.f}
+	case *ast.OpenTag:
+		if len(v.stack) == 0 || v.stack[len(v.stack)-1] != n {
+			// A void element.
+			v.f(n, v.stack)
+		}
+	}
+	return v
+}
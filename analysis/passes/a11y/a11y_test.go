@@ -0,0 +1,16 @@
+package a11y_test
+
+import (
+	"testing"
+
+	"github.com/mateusz834/tgoast/analysis/analysistest"
+	"github.com/mateusz834/tgoast/analysis/passes/a11y"
+)
+
+func TestAnalyzers(t *testing.T) {
+	for _, a := range a11y.Analyzers {
+		t.Run(a.Name, func(t *testing.T) {
+			analysistest.RunWithSuggestedFixes(t, "testdata/"+a.Name+".txtar", a, "a")
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package a11y
+
+import (
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+)
+
+// attrs describes the attributes of an open tag.
+type attrs struct {
+	// must holds the names of the attributes that are set
+	// on all control-flow paths through the open tag.
+	must names
+
+	// may maps the names of the attributes that are set on some
+	// control-flow path through the open tag to their first statement.
+	may map[string]*ast.AttributeStmt
+}
+
+// has reports whether the attribute is set on all control-flow paths.
+func (a *attrs) has(name string) bool {
+	return a.must[name]
+}
+
+// mayHave reports whether the attribute is set on some control-flow path.
+func (a *attrs) mayHave(name string) bool {
+	return a.may[name] != nil
+}
+
+// attrName returns the lower-cased name of attr.
+func attrName(attr *ast.AttributeStmt) string {
+	if id, ok := attr.AttrName.(*ast.Ident); ok {
+		return strings.ToLower(id.Name)
+	}
+	return ""
+}
+
+// names is a set of attribute names.
+type names map[string]bool
+
+func (n names) with(name string) names {
+	if n[name] {
+		return n
+	}
+	m := make(names, len(n)+1)
+	for k := range n {
+		m[k] = true
+	}
+	m[name] = true
+	return m
+}
+
+// intersect returns the names in both n and m. A nil set stands
+// for no control-flow paths, it is the identity of intersect.
+func intersect(n, m names) names {
+	if n == nil {
+		return m
+	}
+	if m == nil {
+		return n
+	}
+	r := make(names)
+	for k := range n {
+		if m[k] {
+			r[k] = true
+		}
+	}
+	return r
+}
+
+// openTagAttrs determines the attributes of tag, by tracking the
+// attributes set on the control-flow paths of its body.
+//
+// The analysis is conservative, goto and labeled branch statements
+// are not followed, in their presence no attribute is considered to
+// be set on all paths.
+func openTagAttrs(tag *ast.OpenTag) *attrs {
+	a := &attrs{may: make(map[string]*ast.AttributeStmt)}
+	complex := false
+	ast.Inspect(&ast.BlockStmt{List: tag.Body}, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AttributeStmt:
+			name := attrName(n)
+			if a.may[name] == nil {
+				a.may[name] = n
+			}
+		case *ast.BranchStmt:
+			if n.Tok == token.GOTO || n.Label != nil {
+				complex = true
+			}
+		}
+		return true
+	})
+
+	if complex {
+		a.must = names{}
+		return a
+	}
+	f := stmtList(tag.Body, names{})
+	a.must = f.out
+	if a.must == nil {
+		a.must = names{}
+	}
+	return a
+}
+
+// A flow describes the attributes set on the control-flow paths
+// leaving a statement.
+type flow struct {
+	out names // set on all paths that complete normally; nil if there are none
+	brk names // set on all paths that break; nil if there are none
+}
+
+func stmtList(list []ast.Stmt, in names) flow {
+	var f flow
+	cur := in
+	for _, s := range list {
+		r := stmt(s, cur)
+		f.brk = intersect(f.brk, r.brk)
+		cur = r.out
+		if cur == nil {
+			break
+		}
+	}
+	f.out = cur
+	return f
+}
+
+// join joins the flows of alternative paths. When consumeBreaks is
+// set, the paths that break complete the joined statement normally.
+func join(consumeBreaks bool, flows ...flow) flow {
+	var f flow
+	for _, r := range flows {
+		f.out = intersect(f.out, r.out)
+		if consumeBreaks {
+			f.out = intersect(f.out, r.brk)
+		} else {
+			f.brk = intersect(f.brk, r.brk)
+		}
+	}
+	return f
+}
+
+func stmt(s ast.Stmt, in names) flow {
+	switch s := s.(type) {
+	case *ast.AttributeStmt:
+		return flow{out: in.with(attrName(s))}
+	case *ast.BlockStmt:
+		return stmtList(s.List, in)
+	case *ast.LabeledStmt:
+		return stmt(s.Stmt, in)
+	case *ast.IfStmt:
+		body := stmtList(s.Body.List, in)
+		els := flow{out: in}
+		if s.Else != nil {
+			els = stmt(s.Else, in)
+		}
+		return join(false, body, els)
+	case *ast.SwitchStmt:
+		return switchClauses(s.Body, in, false)
+	case *ast.TypeSwitchStmt:
+		return switchClauses(s.Body, in, false)
+	case *ast.SelectStmt:
+		return switchClauses(s.Body, in, true)
+	case *ast.ForStmt:
+		if s.Cond == nil && !hasBreak(s.Body) {
+			return flow{} // infinite loop
+		}
+		return flow{out: in}
+	case *ast.RangeStmt:
+		return flow{out: in}
+	case *ast.BranchStmt:
+		if s.Tok == token.BREAK {
+			return flow{brk: in}
+		}
+		// continue leaves the loop body, loops do not
+		// contribute attributes, fallthrough is handled by
+		// switchClauses.
+		return flow{}
+	case *ast.ReturnStmt:
+		return flow{}
+	}
+	return flow{out: in}
+}
+
+// switchClauses returns the flow of the clauses of a switch or select
+// statement, all is set when one of the clauses is always executed.
+// A clause ending with a fallthrough statement does not complete
+// normally, its path continues in the next clause, that starts with
+// (at least) the attributes of in.
+func switchClauses(body *ast.BlockStmt, in names, all bool) flow {
+	flows := make([]flow, 0, len(body.List)+1)
+	hasDefault := false
+	for _, c := range body.List {
+		switch c := c.(type) {
+		case *ast.CaseClause:
+			hasDefault = hasDefault || c.List == nil
+			flows = append(flows, stmtList(c.Body, in))
+		case *ast.CommClause:
+			flows = append(flows, stmtList(c.Body, in))
+		}
+	}
+	if !all && !hasDefault {
+		flows = append(flows, flow{out: in})
+	}
+	return join(true, flows...)
+}
+
+// hasBreak reports whether body has a break statement
+// that breaks out of the loop with the body.
+func hasBreak(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			return false
+		case *ast.BranchStmt:
+			if n.Tok == token.BREAK {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,41 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	run := func(*Pass) (any, error) { return nil, nil }
+	var (
+		a = &Analyzer{Name: "a", Doc: "a", Run: run}
+		b = &Analyzer{Name: "b", Doc: "b", Run: run, Requires: []*Analyzer{a}}
+
+		cycleA = &Analyzer{Name: "cycleA", Doc: "cycleA", Run: run}
+		cycleB = &Analyzer{Name: "cycleB", Doc: "cycleB", Run: run, Requires: []*Analyzer{cycleA}}
+	)
+	cycleA.Requires = []*Analyzer{cycleB}
+
+	tests := []struct {
+		analyzers []*Analyzer
+		err       string
+	}{
+		{[]*Analyzer{a, b}, ""},
+		{[]*Analyzer{{Name: "1a", Doc: "doc", Run: run}}, `invalid analyzer name "1a"`},
+		{[]*Analyzer{{Name: "noDoc", Run: run}}, `analyzer "noDoc" is undocumented`},
+		{[]*Analyzer{{Name: "noRun", Doc: "doc"}}, `analyzer "noRun" has nil Run`},
+		{[]*Analyzer{a, a}, "duplicate analyzer: a"},
+		{[]*Analyzer{cycleB}, "cycle detected involving the following analyzers: cycleA cycleB"},
+		{[]*Analyzer{{Name: "fact", Doc: "doc", Run: run, FactTypes: []Fact{nil}}}, "analyzer fact has nil FactType"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.analyzers)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.err) || (tt.err == "") != (got == "") {
+			t.Errorf("Validate(%v) = %q; want %q", tt.analyzers, got, tt.err)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package astutil_test
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/ast/astutil"
+	"github.com/mateusz834/tgoast/format"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+var rewriteTests = []struct {
+	name       string
+	orig, want string
+	pre, post  astutil.ApplyFunc
+}{
+	{name: "nop", orig: "package p\n", want: "package p\n"},
+
+	{name: "replace",
+		orig: `package p
+
+var x int
+`,
+		want: `package p
+
+var t T
+`,
+		post: func(c *astutil.Cursor) bool {
+			if _, ok := c.Node().(*ast.ValueSpec); ok {
+				c.Replace(valspec("t", "T"))
+				return false
+			}
+			return true
+		},
+	},
+
+	{name: "set doc strings",
+		orig: `package p
+
+const z = 0
+
+type T struct{}
+
+var x int
+`,
+		want: `package p
+// a foo is a foo
+const z = 0
+// a foo is a foo
+type T struct{}
+// a foo is a foo
+var x int
+`,
+		post: func(c *astutil.Cursor) bool {
+			if _, ok := c.Parent().(*ast.GenDecl); ok && c.Name() == "Doc" && c.Node() == nil {
+				c.Replace(&ast.CommentGroup{List: []*ast.Comment{{Text: "// a foo is a foo"}}})
+			}
+			return true
+		},
+	},
+
+	{name: "insert",
+		orig: `package p
+
+var (
+	x int
+	y int
+)
+`,
+		want: `package p
+
+var before1 int
+var before2 int
+
+var (
+	x int
+	y int
+)
+var after2 int
+var after1 int
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if _, ok := c.Node().(*ast.GenDecl); ok {
+				c.InsertBefore(vardecl("before1", "int"))
+				c.InsertAfter(vardecl("after1", "int"))
+				c.InsertAfter(vardecl("after2", "int"))
+				c.InsertBefore(vardecl("before2", "int"))
+			}
+			return true
+		},
+	},
+
+	{name: "delete",
+		orig: `package p
+
+var x int
+var y int
+var z int
+`,
+		want: `package p
+
+var y int
+var z int
+`,
+		pre: func(c *astutil.Cursor) bool {
+			n := c.Node()
+			if d, ok := n.(*ast.GenDecl); ok && d.Specs[0].(*ast.ValueSpec).Names[0].Name == "x" {
+				c.Delete()
+			}
+			return true
+		},
+	},
+
+	{name: "rename attribute",
+		orig: `package p
+
+func f() {
+	<div
+		@class="a"
+		@id="b"
+	>
+		<span
+			@class="c"
+		>
+		</span>
+	</div>
+}
+`,
+		want: `package p
+
+func f() {
+	<div
+		@className="a"
+		@id="b"
+	>
+		<span
+			@className="c"
+		>
+		</span>
+	</div>
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if id, ok := c.Node().(*ast.Ident); ok && c.Name() == "AttrName" && id.Name == "class" {
+				c.Replace(ast.NewIdent("className"))
+			}
+			return true
+		},
+	},
+
+	{name: "delete attribute",
+		orig: `package p
+
+func f() {
+	<div
+		@class="a"
+		@id="b"
+	>
+	</div>
+}
+`,
+		want: `package p
+
+func f() {
+	<div
+
+		@id="b"
+	>
+	</div>
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if a, ok := c.Node().(*ast.AttributeStmt); ok && a.AttrName.(*ast.Ident).Name == "class" {
+				if _, ok := c.Parent().(*ast.OpenTag); !ok || c.Name() != "Body" {
+					panic("unexpected parent")
+				}
+				c.Delete()
+			}
+			return true
+		},
+	},
+
+	{name: "insert into element body",
+		orig: `package p
+
+func f() {
+	<div>
+		"b"
+	</div>
+}
+`,
+		want: `package p
+
+func f() {
+	<div>
+		"a"
+		"b"
+		"c"
+	</div>
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if _, ok := c.Parent().(*ast.ElementBlockStmt); ok && c.Name() == "Body" {
+				c.InsertBefore(&ast.ExprStmt{X: &ast.BasicLit{Kind: token.STRING, Value: `"a"`}})
+				c.InsertAfter(&ast.ExprStmt{X: &ast.BasicLit{Kind: token.STRING, Value: `"c"`}})
+			}
+			return true
+		},
+	},
+
+	{name: "delete template literal part",
+		orig: `package p
+
+func f() {
+	"a \{x} b \{y} c"
+}
+`,
+		want: `package p
+
+func f() {
+	"a  b \{y} c"
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if p, ok := c.Node().(*ast.TemplateLiteralPart); ok && p.X.(*ast.Ident).Name == "x" {
+				c.Delete()
+			}
+			return true
+		},
+	},
+
+	{name: "delete all template literal parts",
+		orig: `package p
+
+func f() {
+	"a \{x} b \{y} c"
+}
+`,
+		want: `package p
+
+func f() {
+	"a  b  c"
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if _, ok := c.Node().(*ast.TemplateLiteralPart); ok {
+				c.Delete()
+			}
+			return true
+		},
+	},
+
+	{name: "insert template literal parts",
+		orig: `package p
+
+func f() {
+	"a \{x} b"
+}
+`,
+		want: `package p
+
+func f() {
+	"a \{before}\{x}\{after} b"
+}
+`,
+		pre: func(c *astutil.Cursor) bool {
+			if _, ok := c.Node().(*ast.TemplateLiteralPart); ok {
+				c.InsertBefore(&ast.TemplateLiteralPart{X: ast.NewIdent("before")})
+				c.InsertAfter(&ast.TemplateLiteralPart{X: ast.NewIdent("after")})
+			}
+			return true
+		},
+	},
+
+	{name: "replace template literal part expression",
+		orig: `package p
+
+func f() {
+	<a
+		@href="/users/\{id}"
+	>
+		"\{name}"
+	</a>
+}
+`,
+		want: `package p
+
+func f() {
+	<a
+		@href="/users/\{user.ID}"
+	>
+		"\{user.Name}"
+	</a>
+}
+`,
+		post: func(c *astutil.Cursor) bool {
+			if id, ok := c.Node().(*ast.Ident); ok && c.Name() == "X" {
+				if _, ok := c.Parent().(*ast.TemplateLiteralPart); ok {
+					c.Replace(&ast.SelectorExpr{
+						X:   ast.NewIdent("user"),
+						Sel: ast.NewIdent(map[string]string{"id": "ID", "name": "Name"}[id.Name]),
+					})
+				}
+			}
+			return true
+		},
+	},
+}
+
+func valspec(name, typ string) *ast.ValueSpec {
+	return &ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(name)},
+		Type: ast.NewIdent(typ),
+	}
+}
+
+func vardecl(name, typ string) *ast.GenDecl {
+	return &ast.GenDecl{
+		Tok:   token.VAR,
+		Specs: []ast.Spec{valspec(name, typ)},
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	t.Run("*", func(t *testing.T) {
+		for _, test := range rewriteTests {
+			test := test
+			t.Run(test.name, func(t *testing.T) {
+				t.Parallel()
+				fset := token.NewFileSet()
+				f, err := parser.ParseFile(fset, test.name, test.orig, parser.ParseComments)
+				if err != nil {
+					t.Fatal(err)
+				}
+				n := astutil.Apply(f, test.pre, test.post)
+				var buf bytes.Buffer
+				if err := format.Node(&buf, fset, n); err != nil {
+					t.Fatal(err)
+				}
+				got := buf.String()
+				if got != test.want {
+					t.Errorf("got:\n\n%s\nwant:\n\n%s\n", got, test.want)
+				}
+			})
+		}
+	})
+}
+
+func TestApplyTemplateLiteralInvariant(t *testing.T) {
+	const src = `package p
+
+func f() {
+	"\{a}\{b}\{c}"
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lit *ast.TemplateLiteralExpr
+	astutil.Apply(f, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.TemplateLiteralExpr:
+			lit = n
+		case *ast.TemplateLiteralPart:
+			switch n.X.(*ast.Ident).Name {
+			case "a":
+				c.InsertAfter(&ast.TemplateLiteralPart{X: ast.NewIdent("x")})
+			case "b":
+				c.Delete()
+			}
+		}
+		return true
+	}, nil)
+
+	if len(lit.Strings) != len(lit.Parts)+1 {
+		t.Fatalf("len(Strings) = %v; want = len(Parts)+1 = %v", len(lit.Strings), len(lit.Parts)+1)
+	}
+	var names []string
+	for _, p := range lit.Parts {
+		names = append(names, p.X.(*ast.Ident).Name)
+	}
+	if got, want := names, []string{"a", "x", "c"}; !slices.Equal(got, want) {
+		t.Errorf("parts = %v; want = %v", got, want)
+	}
+}
+
+var sink ast.Node
+
+func BenchmarkRewrite(b *testing.B) {
+	for _, test := range rewriteTests {
+		b.Run(test.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				fset := token.NewFileSet()
+				f, err := parser.ParseFile(fset, test.name, test.orig, parser.ParseComments)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				sink = astutil.Apply(f, test.pre, test.post)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package astutil
+
+import (
+	"slices"
+
+	"github.com/mateusz834/tgoast/ast"
+)
+
+func (a *application) applyTgo(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.ElementBlockStmt:
+		a.apply(n, "OpenTag", nil, n.OpenTag)
+		a.applyList(n, "Body")
+		a.apply(n, "EndTag", nil, n.EndTag)
+	case *ast.OpenTag:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "DynamicName", nil, n.DynamicName)
+		a.applyList(n, "Body")
+	case *ast.EndTag:
+		a.apply(n, "Name", nil, n.Name)
+		a.apply(n, "DynamicName", nil, n.DynamicName)
+	case *ast.AttributeStmt:
+		a.apply(n, "AttrName", nil, n.AttrName)
+		a.apply(n, "Value", nil, n.Value)
+	case *ast.TemplateLiteralExpr:
+		a.applyList(n, "Parts")
+	case *ast.TemplateLiteralPart:
+		a.apply(n, "X", nil, n.X)
+		a.apply(n, "Format", nil, n.Format)
+	case *ast.FormatVerb:
+	default:
+		return false
+	}
+	return true
+}
+
+// templateLiteral returns the template literal, when the
+// current node is an element of TemplateLiteralExpr.Parts.
+func (c *Cursor) templateLiteral() *templateLiteral {
+	if c.name != "Parts" {
+		return nil
+	}
+	lit, _ := c.parent.(*ast.TemplateLiteralExpr)
+	return (*templateLiteral)(lit)
+}
+
+// templateLiteral keeps the len(Strings) == len(Parts)+1
+// invariant of an [ast.TemplateLiteralExpr], after its
+// Parts were modified by the Cursor.
+type templateLiteral ast.TemplateLiteralExpr
+
+// deletePart merges the strings that surrounded the deleted part i.
+func (t *templateLiteral) deletePart(i int) {
+	t.Strings[i] += t.Strings[i+1]
+	t.Strings = slices.Delete(t.Strings, i+1, i+2)
+}
+
+// insertPart adds an empty string next to the part inserted at i or i+1.
+func (t *templateLiteral) insertPart(i int) {
+	t.Strings = slices.Insert(t.Strings, i+1, "")
+}
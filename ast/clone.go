@@ -0,0 +1,81 @@
+package ast
+
+import (
+	"reflect"
+)
+
+// Clone returns a deep copy of the AST rooted at node.
+//
+// Nodes, comments and slices are copied. Nodes that are referenced
+// multiple times in the original AST (for example comment groups,
+// that are both part of [File.Comments] and a Doc field) are also
+// shared in the copy. The deprecated [Object] and [Scope] values
+// are not copied, the copy refers to the same values as node.
+func Clone[N Node](node N) N {
+	c := cloner{seen: make(map[clonerKey]reflect.Value)}
+	v := reflect.ValueOf(&node).Elem()
+	return c.clone(v).Interface().(N)
+}
+
+type clonerKey struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+type cloner struct {
+	seen map[clonerKey]reflect.Value
+}
+
+var (
+	objectPtrType = reflect.TypeFor[*Object]()
+	scopePtrType  = reflect.TypeFor[*Scope]()
+)
+
+func (c *cloner) clone(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || v.Type() == objectPtrType || v.Type() == scopePtrType {
+			return v
+		}
+		key := clonerKey{v.Type(), v.Pointer()}
+		if n, ok := c.seen[key]; ok {
+			return n
+		}
+		n := reflect.New(v.Type().Elem())
+		c.seen[key] = n
+		n.Elem().Set(c.clone(v.Elem()))
+		return n
+	case reflect.Interface:
+		n := reflect.New(v.Type()).Elem()
+		if !v.IsNil() {
+			n.Set(c.clone(v.Elem()))
+		}
+		return n
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			n.Index(i).Set(c.clone(v.Index(i)))
+		}
+		return n
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		n := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			n.SetMapIndex(iter.Key(), c.clone(iter.Value()))
+		}
+		return n
+	case reflect.Struct:
+		n := reflect.New(v.Type()).Elem()
+		for i := range v.NumField() {
+			n.Field(i).Set(c.clone(v.Field(i)))
+		}
+		return n
+	default:
+		return v
+	}
+}
@@ -130,6 +130,17 @@ func (s *nodeStack) pop(pos token.Pos) (top Node) {
 //   - g starts before n and is not associated to the node before n
 //     via the previous rules
 //
+// Comment groups inside of tgo nodes, that are not placed between
+// attributes or statements, are associated with the enclosing tgo node:
+//
+//   - g starts inside of an open tag, before its name or after its
+//     last attribute, and not on the same line as that attribute ends:
+//     g is associated with the [OpenTag]
+//   - g starts inside of an end tag: g is associated with the [EndTag]
+//   - g starts after the last statement of an element body (and not on
+//     the same line as that statement ends) and before its end tag:
+//     g is associated with the [ElementBlockStmt]
+//
 // NewCommentMap tries to associate a comment group to the "largest"
 // node possible: For instance, if the comment is a line comment
 // trailing an assignment, the comment is associated with the entire
@@ -150,6 +161,7 @@ func NewCommentMap(fset *token.FileSet, node Node, comments []*CommentGroup) Com
 
 	// create node list in lexical order
 	nodes := nodeList(node)
+	tgoRanges := newTgoCommentRanges(fset, nodes)
 	nodes = append(nodes, nil) // append sentinel
 
 	// set up iteration variables
@@ -185,8 +197,10 @@ func NewCommentMap(fset *token.FileSet, node Node, comments []*CommentGroup) Com
 			// if that fails, try to associate it with the most recent
 			// node.
 			// TODO(gri) try to simplify the logic below
-			var assoc Node
+			assoc := tgoRanges.lookup(r.comment.Pos())
 			switch {
+			case assoc != nil:
+				// comment inside of a tgo node; see newTgoCommentRanges
 			case pg != nil &&
 				(pgend.Line == r.pos.Line ||
 					pgend.Line+1 == r.pos.Line && r.end.Line+1 < qpos.Line):
@@ -8,6 +8,7 @@ package ast_test
 
 import (
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"testing"
@@ -140,6 +141,118 @@ func TestCommentMap(t *testing.T) {
 	}
 }
 
+const tgoSrc = `package p
+
+func f1() {
+	// associated with div
+	<div
+		// associated with @a
+		@a="a" // also associated with @a
+		// associated with @b
+		@b="b"
+		// associated with div open tag
+	>
+		"text" // associated with text
+		// associated with div element
+	</div /* associated with div end tag */>
+	<span // associated with span open tag
+	>
+	</span>
+}
+
+func f2() {
+	<p>
+		// associated with p element
+	</p>
+}
+`
+
+// tgoRes maps a key of the form "line number: node type"
+// to the associated comments' text.
+var tgoRes = map[string]string{
+	" 5: *ast.OpenTag":          "associated with div open tag\n",
+	" 7: *ast.AttributeStmt":    "associated with @a\nalso associated with @a\n",
+	" 9: *ast.AttributeStmt":    "associated with @b\n",
+	"12: *ast.ExprStmt":         "associated with text\n",
+	"14: *ast.ElementBlockStmt": "associated with div\nassociated with div element\n",
+	"14: *ast.EndTag":           " associated with div end tag\n",
+	"15: *ast.OpenTag":          "associated with span open tag\n",
+	"23: *ast.ElementBlockStmt": "associated with p element\n",
+}
+
+func TestCommentMapTgo(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", tgoSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmap := NewCommentMap(fset, f, f.Comments)
+
+	got := make(map[string]string)
+	for n, list := range cmap {
+		line := fset.Position(n.Pos()).Line
+		if e, ok := n.(*ElementBlockStmt); ok {
+			// distinguish the element from its open tag
+			line = fset.Position(e.EndTag.Pos()).Line
+		}
+		got[fmt.Sprintf("%2d: %T", line, n)] = ctext(list)
+	}
+
+	for key, want := range tgoRes {
+		if got[key] != want {
+			t.Errorf("%s: got %q; want %q", key, got[key], want)
+		}
+	}
+	for key := range got {
+		if _, ok := tgoRes[key]; !ok {
+			t.Errorf("%s: unexpected comments %q", key, got[key])
+		}
+	}
+
+	// verify that no comments got lost
+	if n := len(cmap.Comments()); n != len(f.Comments) {
+		t.Errorf("got %d comment groups in map; want %d", n, len(f.Comments))
+	}
+}
+
+func TestFilterTgo(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", tgoSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmap := NewCommentMap(fset, f, f.Comments)
+
+	// move the <div> element from f1 into f2 and remove f1
+	f1 := f.Decls[0].(*FuncDecl)
+	f2 := f.Decls[1].(*FuncDecl)
+	div := f1.Body.List[0].(*ElementBlockStmt)
+	f2.Body.List[0].(*ElementBlockStmt).Body = []Stmt{div}
+	if !FilterFile(f, func(name string) bool { return name == "f2" }) {
+		t.Fatal("FilterFile removed all declarations")
+	}
+
+	cc := cmap.Filter(f)
+	var got []string
+	for _, g := range cc.Comments() {
+		got = append(got, g.Text())
+	}
+	want := []string{
+		"associated with div\n",
+		"associated with @a\n",
+		"also associated with @a\n",
+		"associated with @b\n",
+		"associated with div open tag\n",
+		"associated with text\n",
+		"associated with div element\n",
+		" associated with div end tag\n",
+		"associated with p element\n",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got comments %q; want %q", got, want)
+	}
+}
+
 func TestFilter(t *testing.T) {
 	fset := token.NewFileSet()
 	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
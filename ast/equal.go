@@ -0,0 +1,110 @@
+package ast
+
+import (
+	"reflect"
+
+	"github.com/mateusz834/tgoast/token"
+)
+
+// The EqualMode flags control the behavior of [Equal].
+type EqualMode uint
+
+const (
+	// If set, positions are not compared, only their validity
+	// (token.Pos.IsValid) is, as the presence of some positions
+	// (for example CallExpr.Ellipsis) carries syntactic meaning.
+	IgnorePositions EqualMode = 1 << iota
+	// If set, comment groups attached to nodes (Doc and
+	// Comment fields) and File.Comments are not compared.
+	IgnoreComments
+)
+
+// Equal reports whether the ASTs rooted at x and y are structurally
+// equal. The deprecated object resolution fields (Ident.Obj,
+// File.Scope, File.Unresolved and Package.Scope, Package.Imports)
+// are never compared.
+func Equal(x, y Node, mode EqualMode) bool {
+	return equal(reflect.ValueOf(&x).Elem(), reflect.ValueOf(&y).Elem(), mode)
+}
+
+var (
+	posType              = reflect.TypeFor[token.Pos]()
+	commentGroupPtrType  = reflect.TypeFor[*CommentGroup]()
+	commentGroupListType = reflect.TypeFor[[]*CommentGroup]()
+	objectMapType        = reflect.TypeFor[map[string]*Object]()
+	fileType             = reflect.TypeFor[File]()
+)
+
+// ignoreField reports whether the field f of a struct
+// should be ignored by Equal in the specified mode.
+func ignoreField(t reflect.Type, f reflect.StructField, mode EqualMode) bool {
+	switch f.Type {
+	case objectPtrType, scopePtrType, objectMapType:
+		return true
+	case commentGroupPtrType, commentGroupListType:
+		return mode&IgnoreComments != 0
+	}
+	return t == fileType && f.Name == "Unresolved"
+}
+
+func equal(x, y reflect.Value, mode EqualMode) bool {
+	if x.Type() != y.Type() {
+		return false
+	}
+	if x.Type() == posType {
+		if mode&IgnorePositions != 0 {
+			return token.Pos(x.Int()).IsValid() == token.Pos(y.Int()).IsValid()
+		}
+		return x.Int() == y.Int()
+	}
+
+	switch x.Kind() {
+	case reflect.Pointer:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		if x.Pointer() == y.Pointer() {
+			return true
+		}
+		return equal(x.Elem(), y.Elem(), mode)
+	case reflect.Interface:
+		if x.IsNil() || y.IsNil() {
+			return x.IsNil() == y.IsNil()
+		}
+		return equal(x.Elem(), y.Elem(), mode)
+	case reflect.Slice:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for i := range x.Len() {
+			if !equal(x.Index(i), y.Index(i), mode) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if x.Len() != y.Len() {
+			return false
+		}
+		for iter := x.MapRange(); iter.Next(); {
+			yv := y.MapIndex(iter.Key())
+			if !yv.IsValid() || !equal(iter.Value(), yv, mode) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		t := x.Type()
+		for i := range t.NumField() {
+			if ignoreField(t, t.Field(i), mode) {
+				continue
+			}
+			if !equal(x.Field(i), y.Field(i), mode) {
+				return false
+			}
+		}
+		return true
+	default:
+		return x.Equal(y)
+	}
+}
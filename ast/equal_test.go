@@ -0,0 +1,446 @@
+package ast_test
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	. "github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+// allNodeTypes contains all node types of the ast package.
+var allNodeTypes = []Node{
+	// Comments and fields
+	(*Comment)(nil), (*CommentGroup)(nil), (*Field)(nil), (*FieldList)(nil),
+
+	// Expressions
+	(*BadExpr)(nil), (*Ident)(nil), (*Ellipsis)(nil), (*BasicLit)(nil),
+	(*FuncLit)(nil), (*CompositeLit)(nil), (*ParenExpr)(nil), (*SelectorExpr)(nil),
+	(*IndexExpr)(nil), (*IndexListExpr)(nil), (*SliceExpr)(nil), (*TypeAssertExpr)(nil),
+	(*CallExpr)(nil), (*StarExpr)(nil), (*UnaryExpr)(nil), (*BinaryExpr)(nil),
+	(*KeyValueExpr)(nil),
+
+	// Types
+	(*ArrayType)(nil), (*StructType)(nil), (*FuncType)(nil), (*InterfaceType)(nil),
+	(*MapType)(nil), (*ChanType)(nil),
+
+	// Statements
+	(*BadStmt)(nil), (*DeclStmt)(nil), (*EmptyStmt)(nil), (*LabeledStmt)(nil),
+	(*ExprStmt)(nil), (*SendStmt)(nil), (*IncDecStmt)(nil), (*AssignStmt)(nil),
+	(*GoStmt)(nil), (*DeferStmt)(nil), (*ReturnStmt)(nil), (*BranchStmt)(nil),
+	(*BlockStmt)(nil), (*IfStmt)(nil), (*CaseClause)(nil), (*SwitchStmt)(nil),
+	(*TypeSwitchStmt)(nil), (*CommClause)(nil), (*SelectStmt)(nil), (*ForStmt)(nil),
+	(*RangeStmt)(nil),
+
+	// Declarations
+	(*ImportSpec)(nil), (*ValueSpec)(nil), (*TypeSpec)(nil),
+	(*BadDecl)(nil), (*GenDecl)(nil), (*FuncDecl)(nil),
+
+	// Files and packages
+	(*File)(nil), (*Package)(nil),
+
+	// Tgo
+	(*ElementBlockStmt)(nil), (*OpenTag)(nil), (*EndTag)(nil), (*AttributeStmt)(nil),
+	(*TemplateLiteralExpr)(nil), (*TemplateLiteralPart)(nil), (*FormatVerb)(nil),
+}
+
+// TestAllNodeTypes verifies that allNodeTypes contains
+// every type with a Pos method declared in the ast package.
+func TestAllNodeTypes(t *testing.T) {
+	want := make(map[string]bool)
+	for _, filename := range []string{"ast.go", "tgo.go"} {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range f.Decls {
+			if fd, ok := d.(*FuncDecl); ok && fd.Recv != nil && fd.Name.Name == "Pos" {
+				if star, ok := fd.Recv.List[0].Type.(*StarExpr); ok {
+					want[star.X.(*Ident).Name] = true
+				}
+			}
+		}
+	}
+
+	got := make(map[string]bool)
+	for _, n := range allNodeTypes {
+		got[reflect.TypeOf(n).Elem().Name()] = true
+	}
+	for name := range want {
+		if !got[name] {
+			t.Errorf("allNodeTypes is missing *%v", name)
+		}
+	}
+	for name := range got {
+		if !want[name] {
+			t.Errorf("allNodeTypes contains unknown *%v", name)
+		}
+	}
+}
+
+var (
+	posType    = reflect.TypeFor[token.Pos]()
+	objectType = reflect.TypeFor[*Object]()
+	scopeType  = reflect.TypeFor[*Scope]()
+)
+
+// interfaceImpls maps interface types to the
+// concrete types used by the filler to populate them.
+var interfaceImpls = map[reflect.Type]reflect.Type{
+	reflect.TypeFor[Node](): reflect.TypeFor[*Ident](),
+	reflect.TypeFor[Expr](): reflect.TypeFor[*BinaryExpr](),
+	reflect.TypeFor[Stmt](): reflect.TypeFor[*ElementBlockStmt](),
+	reflect.TypeFor[Decl](): reflect.TypeFor[*GenDecl](),
+	reflect.TypeFor[Spec](): reflect.TypeFor[*ValueSpec](),
+}
+
+// filler populates every field of a node with distinct non-zero values.
+type filler struct {
+	n int
+}
+
+const maxFillDepth = 3
+
+func (f *filler) fill(v reflect.Value, depth int) {
+	f.n++
+	switch {
+	case v.Type() == posType:
+		v.SetInt(int64(f.n))
+		return
+	case v.Type() == objectType || v.Type() == scopeType:
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Uint:
+		if v.CanInt() {
+			v.SetInt(int64(f.n))
+		} else {
+			v.SetUint(uint64(f.n))
+		}
+	case reflect.String:
+		v.SetString(fmt.Sprintf("s%v", f.n))
+	case reflect.Pointer:
+		if depth > maxFillDepth {
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		f.fill(p.Elem(), depth+1)
+		v.Set(p)
+	case reflect.Interface:
+		if depth > maxFillDepth {
+			return
+		}
+		impl, ok := interfaceImpls[v.Type()]
+		if !ok {
+			panic(fmt.Sprintf("no implementation for %v", v.Type()))
+		}
+		p := reflect.New(impl.Elem())
+		f.fill(p.Elem(), depth+1)
+		v.Set(p)
+	case reflect.Slice:
+		if depth > maxFillDepth {
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), 2, 2)
+		for i := range s.Len() {
+			f.fill(s.Index(i), depth)
+		}
+		v.Set(s)
+	case reflect.Map:
+		if depth > maxFillDepth {
+			return
+		}
+		m := reflect.MakeMap(v.Type())
+		val := reflect.New(v.Type().Elem()).Elem()
+		f.fill(val, depth)
+		m.SetMapIndex(reflect.ValueOf(fmt.Sprintf("key%v", f.n)), val)
+		v.Set(m)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).Name == "Unresolved" {
+				continue
+			}
+			f.fill(v.Field(i), depth)
+		}
+	default:
+		panic(fmt.Sprintf("unexpected kind: %v", v.Kind()))
+	}
+}
+
+func newFilledNode(typ reflect.Type) Node {
+	var f filler
+	p := reflect.New(typ.Elem())
+	f.fill(p.Elem(), 0)
+	return p.Interface().(Node)
+}
+
+// leaf is a mutable value reachable from a node.
+type leaf struct {
+	path      string
+	v         reflect.Value
+	inComment bool
+}
+
+// leaves returns all the mutable values reachable from v.
+func leaves(v reflect.Value, path string, inComment bool, out []leaf) []leaf {
+	if v.Type() == objectType || v.Type() == scopeType {
+		return out
+	}
+	if v.Type() == posType {
+		return append(out, leaf{path, v, inComment})
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return out
+		}
+		out = append(out, leaf{path, v, inComment})
+		return leaves(v.Elem(), path, inComment, out)
+	case reflect.Interface:
+		if v.IsNil() {
+			return out
+		}
+		out = append(out, leaf{path, v, inComment})
+		// the dynamic value is not addressable, but
+		// the value it points to is.
+		return leaves(v.Elem().Elem(), path, inComment, out)
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return out
+		}
+		out = append(out, leaf{path, v, inComment})
+		for i := range v.Len() {
+			out = leaves(v.Index(i), fmt.Sprintf("%v[%v]", path, i), inComment, out)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			// map values are not addressable, but the
+			// values they point to are.
+			if iter.Value().IsNil() {
+				continue
+			}
+			out = leaves(iter.Value().Elem(), fmt.Sprintf("%v[%q]", path, iter.Key()), inComment, out)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if f.Name == "Unresolved" {
+				continue
+			}
+			isComment := f.Type == reflect.TypeFor[*CommentGroup]() || f.Type == reflect.TypeFor[[]*CommentGroup]()
+			out = leaves(v.Field(i), path+"."+f.Name, inComment || isComment, out)
+		}
+	default:
+		out = append(out, leaf{path, v, inComment})
+	}
+	return out
+}
+
+// mutate changes the value of l and returns a function that restores it.
+func (l leaf) mutate(ignorePositions bool) (restore func()) {
+	old := reflect.New(l.v.Type()).Elem()
+	old.Set(l.v)
+	restore = func() { l.v.Set(old) }
+
+	if l.v.Type() == posType {
+		if ignorePositions {
+			l.v.SetInt(l.v.Int() + 1000)
+		} else {
+			l.v.SetInt(0)
+		}
+		return
+	}
+	switch l.v.Kind() {
+	case reflect.Bool:
+		l.v.SetBool(!l.v.Bool())
+	case reflect.Int:
+		l.v.SetInt(l.v.Int() + 1)
+	case reflect.Uint:
+		l.v.SetUint(l.v.Uint() + 1)
+	case reflect.String:
+		l.v.SetString(l.v.String() + "x")
+	case reflect.Pointer, reflect.Interface, reflect.Slice:
+		l.v.SetZero()
+	default:
+		panic(fmt.Sprintf("unexpected kind: %v", l.v.Kind()))
+	}
+	return
+}
+
+// pointers collects all pointers (except to objects and scopes) reachable from v.
+func pointers(v reflect.Value, out map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() || v.Type() == objectType || v.Type() == scopeType {
+			return
+		}
+		out[v.Pointer()] = true
+		pointers(v.Elem(), out)
+	case reflect.Interface:
+		if !v.IsNil() {
+			pointers(v.Elem(), out)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			pointers(v.Index(i), out)
+		}
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			pointers(iter.Value(), out)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			pointers(v.Field(i), out)
+		}
+	}
+}
+
+func TestCloneAllNodeTypes(t *testing.T) {
+	for _, n := range allNodeTypes {
+		typ := reflect.TypeOf(n)
+		t.Run(typ.Elem().Name(), func(t *testing.T) {
+			orig := newFilledNode(typ)
+			clone := Clone(orig)
+
+			if reflect.TypeOf(clone) != typ {
+				t.Fatalf("Clone() returned %T; want %v", clone, typ)
+			}
+			if !reflect.DeepEqual(orig, clone) {
+				t.Fatalf("Clone() is not deeply equal to the original")
+			}
+			if !Equal(orig, clone, 0) {
+				t.Fatalf("Equal(orig, Clone(orig), 0) = false; want = true")
+			}
+
+			origPtrs, clonePtrs := make(map[uintptr]bool), make(map[uintptr]bool)
+			pointers(reflect.ValueOf(orig), origPtrs)
+			pointers(reflect.ValueOf(clone), clonePtrs)
+			for p := range clonePtrs {
+				if origPtrs[p] {
+					t.Fatalf("Clone() shares memory with the original")
+				}
+			}
+		})
+	}
+}
+
+func TestEqualAllNodeTypes(t *testing.T) {
+	for _, n := range allNodeTypes {
+		typ := reflect.TypeOf(n)
+		t.Run(typ.Elem().Name(), func(t *testing.T) {
+			orig := newFilledNode(typ)
+			clone := Clone(orig)
+			for _, l := range leaves(reflect.ValueOf(&clone).Elem(), typ.Elem().Name(), false, nil) {
+				if l.path == typ.Elem().Name() {
+					continue // the root itself
+				}
+				isPos := l.v.Type() == posType
+
+				restore := l.mutate(false)
+				if Equal(orig, clone, 0) {
+					t.Errorf("%v: Equal(0) = true after modification; want = false", l.path)
+				}
+				if got, want := Equal(orig, clone, IgnoreComments), l.inComment; got != want {
+					t.Errorf("%v: Equal(IgnoreComments) = %v after modification; want = %v", l.path, got, want)
+				}
+				restore()
+
+				if isPos {
+					restore := l.mutate(true)
+					if Equal(orig, clone, 0) {
+						t.Errorf("%v: Equal(0) = true after position change; want = false", l.path)
+					}
+					if !Equal(orig, clone, IgnorePositions) {
+						t.Errorf("%v: Equal(IgnorePositions) = false after position change; want = true", l.path)
+					}
+					restore()
+				}
+			}
+			if !Equal(orig, clone, 0) {
+				t.Fatalf("Equal(orig, clone, 0) = false after restoring all modifications")
+			}
+		})
+	}
+}
+
+func TestEqualParsed(t *testing.T) {
+	const src1 = `package p
+
+// f renders a div.
+func f(tgo.Ctx) error {
+	<div @class="a">
+		"\{name}"
+	</div>
+	return nil
+}
+`
+	const src2 = `package p
+func f(tgo.Ctx) error {
+	<div @class="a">"\{name}"</div>
+	return nil
+}
+`
+	const src3 = `package p
+func f(tgo.Ctx) error {
+	<div @class="b">"\{name}"</div>
+	return nil
+}
+`
+	parse := func(src string) *File {
+		f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	f1, f2, f3 := parse(src1), parse(src2), parse(src3)
+
+	tests := []struct {
+		x, y Node
+		mode EqualMode
+		want bool
+	}{
+		{f1, f1, 0, true},
+		{f1, f2, 0, false},
+		{f1, f2, IgnorePositions, false},
+		{f1, f2, IgnorePositions | IgnoreComments, true},
+		{f2, f3, IgnorePositions | IgnoreComments, false},
+		{f1.Decls[0], f2.Decls[0], IgnorePositions | IgnoreComments, true},
+		{f1.Decls[0], Clone(f1.Decls[0]), 0, true},
+	}
+	for i, tt := range tests {
+		if got := Equal(tt.x, tt.y, tt.mode); got != tt.want {
+			t.Errorf("%v: Equal() = %v; want = %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCloneSharedComments(t *testing.T) {
+	const src = `package p
+
+// f is a function.
+func f() {}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := Clone(f)
+	if clone.Comments[0] != clone.Decls[0].(*FuncDecl).Doc {
+		t.Errorf("Clone() does not preserve sharing of comment groups")
+	}
+	if slices.Contains(f.Comments, clone.Comments[0]) {
+		t.Errorf("Clone() does not copy comment groups")
+	}
+}
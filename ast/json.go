@@ -0,0 +1,464 @@
+package ast
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mateusz834/tgoast/token"
+)
+
+// JSONVersion is the version of the JSON schema used by [MarshalJSON]
+// and [UnmarshalJSON]. It is incremented on every incompatible change
+// of the schema.
+//
+// The schema of version 1 is:
+//
+//	Document = {"version": 1, "files": [File...], "node": Node}
+//	File     = {"name": string, "size": int, "lines": [int...]}
+//	Node     = {"kind": string, field: Value...} | null
+//	Position = {"file": int, "line": int, "column": int} | null
+//
+// Every node is encoded as an object, where "kind" is the name
+// of the Go type (e.g. "Ident", "ElementBlockStmt") and the other
+// members are the exported fields of that type, with the same names
+// as in Go. Positions are encoded as 1-based line and column numbers
+// (in bytes) in the file with the "file" index in the files list, the
+// "file" member is omitted for the first file. Tokens are encoded as
+// strings (e.g. "+", "STRING", or "token(N)" for tokens without
+// a name), lists as arrays and maps as objects.
+// The deprecated object resolution fields (Ident.Obj, File.Scope,
+// File.Unresolved, Package.Scope and Package.Imports) are not encoded.
+const JSONVersion = 1
+
+type jsonDocument struct {
+	Version int             `json:"version"`
+	Files   []jsonFile      `json:"files"`
+	Node    json.RawMessage `json:"node"`
+}
+
+type jsonFile struct {
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+	Lines []int  `json:"lines"`
+}
+
+type jsonPosition struct {
+	File   int `json:"file,omitempty"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// jsonNodeTypes maps the "kind" of a node to its type.
+var jsonNodeTypes = sync.OnceValue(func() map[string]reflect.Type {
+	m := make(map[string]reflect.Type)
+	for _, n := range []Node{
+		(*Comment)(nil), (*CommentGroup)(nil), (*Field)(nil), (*FieldList)(nil),
+		(*BadExpr)(nil), (*Ident)(nil), (*Ellipsis)(nil), (*BasicLit)(nil),
+		(*FuncLit)(nil), (*CompositeLit)(nil), (*ParenExpr)(nil), (*SelectorExpr)(nil),
+		(*IndexExpr)(nil), (*IndexListExpr)(nil), (*SliceExpr)(nil), (*TypeAssertExpr)(nil),
+		(*CallExpr)(nil), (*StarExpr)(nil), (*UnaryExpr)(nil), (*BinaryExpr)(nil),
+		(*KeyValueExpr)(nil), (*ArrayType)(nil), (*StructType)(nil), (*FuncType)(nil),
+		(*InterfaceType)(nil), (*MapType)(nil), (*ChanType)(nil), (*BadStmt)(nil),
+		(*DeclStmt)(nil), (*EmptyStmt)(nil), (*LabeledStmt)(nil), (*ExprStmt)(nil),
+		(*SendStmt)(nil), (*IncDecStmt)(nil), (*AssignStmt)(nil), (*GoStmt)(nil),
+		(*DeferStmt)(nil), (*ReturnStmt)(nil), (*BranchStmt)(nil), (*BlockStmt)(nil),
+		(*IfStmt)(nil), (*CaseClause)(nil), (*SwitchStmt)(nil), (*TypeSwitchStmt)(nil),
+		(*CommClause)(nil), (*SelectStmt)(nil), (*ForStmt)(nil), (*RangeStmt)(nil),
+		(*ImportSpec)(nil), (*ValueSpec)(nil), (*TypeSpec)(nil), (*BadDecl)(nil),
+		(*GenDecl)(nil), (*FuncDecl)(nil), (*File)(nil), (*Package)(nil),
+		(*ElementBlockStmt)(nil), (*OpenTag)(nil), (*EndTag)(nil), (*AttributeStmt)(nil),
+		(*TemplateLiteralExpr)(nil), (*TemplateLiteralPart)(nil), (*FormatVerb)(nil),
+	} {
+		t := reflect.TypeOf(n)
+		m[t.Elem().Name()] = t
+	}
+	return m
+})
+
+// jsonTokens maps the string representation of a token to the token.
+var jsonTokens = sync.OnceValue(func() map[string]token.Token {
+	m := make(map[string]token.Token)
+	for tok := token.ILLEGAL; tok < 256; tok++ {
+		if s := tok.String(); !strings.HasPrefix(s, "token(") {
+			m[s] = tok
+		}
+	}
+	for _, tok := range []token.Token{token.END_TAG, token.STRING_TEMPLATE, token.AT, token.TEMPLATE_LBRACE} {
+		m[tok.String()] = tok
+	}
+	return m
+})
+
+var tokenType = reflect.TypeFor[token.Token]()
+
+// jsonIgnoreField reports whether the field f of the struct type t is not encoded.
+func jsonIgnoreField(t reflect.Type, f reflect.StructField) bool {
+	switch f.Type {
+	case objectPtrType, scopePtrType, objectMapType:
+		return true
+	}
+	return t == fileType && f.Name == "Unresolved"
+}
+
+// MarshalJSON returns the JSON encoding of the AST rooted at node, as
+// described by [JSONVersion]. Positions are resolved using fset, all
+// valid positions in the AST must belong to a file in fset.
+func MarshalJSON(fset *token.FileSet, node Node) ([]byte, error) {
+	e := jsonEncoder{fset: fset, fileIndex: make(map[*token.File]int)}
+	if err := e.value(reflect.ValueOf(&node).Elem()); err != nil {
+		return nil, err
+	}
+
+	doc := jsonDocument{Version: JSONVersion, Files: []jsonFile{}, Node: e.buf.Bytes()}
+	for _, f := range e.files {
+		doc.Files = append(doc.Files, jsonFile{Name: f.Name(), Size: f.Size(), Lines: f.Lines()})
+	}
+	return json.Marshal(&doc)
+}
+
+type jsonEncoder struct {
+	fset      *token.FileSet
+	files     []*token.File
+	fileIndex map[*token.File]int
+	buf       bytes.Buffer
+}
+
+func (e *jsonEncoder) value(v reflect.Value) error {
+	switch v.Type() {
+	case posType:
+		return e.pos(token.Pos(v.Int()))
+	case tokenType:
+		e.string(token.Token(v.Int()).String())
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			return e.value(v.Elem())
+		}
+		return e.node(v)
+	case reflect.Slice:
+		e.buf.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case reflect.Map:
+		e.buf.WriteByte('{')
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.string(key.String())
+			e.buf.WriteByte(':')
+			if err := e.value(v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte('}')
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Bool:
+		e.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int:
+		e.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	default:
+		return fmt.Errorf("ast.MarshalJSON: unexpected value of type %v", v.Type())
+	}
+	return nil
+}
+
+func (e *jsonEncoder) node(v reflect.Value) error {
+	t := v.Type().Elem()
+	if jsonNodeTypes()[t.Name()] != v.Type() {
+		return fmt.Errorf("ast.MarshalJSON: unexpected node type %v", v.Type())
+	}
+	v = v.Elem()
+
+	e.buf.WriteString(`{"kind":`)
+	e.string(t.Name())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if jsonIgnoreField(t, f) {
+			continue
+		}
+		e.buf.WriteByte(',')
+		e.string(f.Name)
+		e.buf.WriteByte(':')
+		if err := e.value(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+func (e *jsonEncoder) pos(pos token.Pos) error {
+	if !pos.IsValid() {
+		e.buf.WriteString("null")
+		return nil
+	}
+	f := e.fset.File(pos)
+	if f == nil {
+		return fmt.Errorf("ast.MarshalJSON: position %v does not belong to a file in the file set", pos)
+	}
+	i, ok := e.fileIndex[f]
+	if !ok {
+		i = len(e.files)
+		e.fileIndex[f] = i
+		e.files = append(e.files, f)
+	}
+	p := f.PositionFor(pos, false)
+	b, err := json.Marshal(jsonPosition{File: i, Line: p.Line, Column: p.Column})
+	if err != nil {
+		return err
+	}
+	e.buf.Write(b)
+	return nil
+}
+
+func (e *jsonEncoder) string(s string) {
+	b, _ := json.Marshal(s)
+	e.buf.Write(b)
+}
+
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	return keys
+}
+
+// UnmarshalJSON decodes an AST encoded by [MarshalJSON]. The files
+// of the encoded AST are added to fset, positions in the returned AST
+// refer to them.
+//
+// Comment groups in Doc and Comment fields of nodes inside of a [File]
+// are the same comment groups (pointers) as in [File.Comments], as in
+// the ASTs returned by the parser.
+func UnmarshalJSON(fset *token.FileSet, data []byte) (Node, error) {
+	var doc jsonDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("ast.UnmarshalJSON: unsupported version %v", doc.Version)
+	}
+
+	d := jsonDecoder{}
+	for _, f := range doc.Files {
+		file := fset.AddFile(f.Name, -1, f.Size)
+		if !file.SetLines(f.Lines) {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: invalid lines of file %q", f.Name)
+		}
+		d.files = append(d.files, file)
+	}
+
+	var node Node
+	if err := d.value(reflect.ValueOf(&node).Elem(), doc.Node); err != nil {
+		return nil, err
+	}
+	switch n := node.(type) {
+	case *File:
+		shareComments(n)
+	case *Package:
+		for _, f := range n.Files {
+			if f != nil {
+				shareComments(f)
+			}
+		}
+	}
+	return node, nil
+}
+
+type jsonDecoder struct {
+	files []*token.File
+}
+
+func (d *jsonDecoder) value(v reflect.Value, data json.RawMessage) error {
+	switch v.Type() {
+	case posType:
+		return d.pos(v, data)
+	case tokenType:
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		tok, ok := jsonTokens()[s]
+		if !ok {
+			// Tokens without a name are encoded by token.Token.String as "token(N)".
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(s, "token("), ")"))
+			if err != nil || s != "token("+strconv.Itoa(n)+")" {
+				return fmt.Errorf("ast.UnmarshalJSON: unknown token %q", s)
+			}
+			tok = token.Token(n)
+		}
+		v.SetInt(int64(tok))
+		return nil
+	}
+
+	if string(data) == "null" {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return nil
+		}
+		return fmt.Errorf("ast.UnmarshalJSON: unexpected null value of type %v", v.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return d.node(v, data)
+	case reflect.Slice:
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, data := range list {
+			if err := d.value(s.Index(i), data); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Map:
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+		mv := reflect.MakeMapWithSize(v.Type(), len(m))
+		for key, data := range m {
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := d.value(val, data); err != nil {
+				return err
+			}
+			mv.SetMapIndex(reflect.ValueOf(key), val)
+		}
+		v.Set(mv)
+	case reflect.String, reflect.Bool, reflect.Int:
+		return json.Unmarshal(data, v.Addr().Interface())
+	default:
+		return fmt.Errorf("ast.UnmarshalJSON: unexpected value of type %v", v.Type())
+	}
+	return nil
+}
+
+func (d *jsonDecoder) node(v reflect.Value, data json.RawMessage) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var kind string
+	if err := json.Unmarshal(fields["kind"], &kind); err != nil {
+		return fmt.Errorf("ast.UnmarshalJSON: invalid node kind: %v", err)
+	}
+	typ, ok := jsonNodeTypes()[kind]
+	if !ok {
+		return fmt.Errorf("ast.UnmarshalJSON: unknown node kind %q", kind)
+	}
+	if !typ.AssignableTo(v.Type()) {
+		return fmt.Errorf("ast.UnmarshalJSON: node of kind %q is not assignable to %v", kind, v.Type())
+	}
+
+	n := reflect.New(typ.Elem())
+	t := typ.Elem()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if jsonIgnoreField(t, f) {
+			continue
+		}
+		data, ok := fields[f.Name]
+		if !ok {
+			return fmt.Errorf("ast.UnmarshalJSON: missing field %v.%v", kind, f.Name)
+		}
+		if err := d.value(n.Elem().Field(i), data); err != nil {
+			return err
+		}
+	}
+	v.Set(n)
+	return nil
+}
+
+func (d *jsonDecoder) pos(v reflect.Value, data json.RawMessage) error {
+	if string(data) == "null" {
+		v.SetInt(int64(token.NoPos))
+		return nil
+	}
+	var p jsonPosition
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.File < 0 || p.File >= len(d.files) {
+		return fmt.Errorf("ast.UnmarshalJSON: invalid file index %v", p.File)
+	}
+	f := d.files[p.File]
+	if p.Line < 1 || p.Line > f.LineCount() || p.Column < 1 {
+		return fmt.Errorf("ast.UnmarshalJSON: invalid position %v:%v:%v", f.Name(), p.Line, p.Column)
+	}
+	offset := f.Offset(f.LineStart(p.Line)) + p.Column - 1
+	if offset > f.Size() {
+		return fmt.Errorf("ast.UnmarshalJSON: invalid position %v:%v:%v", f.Name(), p.Line, p.Column)
+	}
+	v.SetInt(int64(f.Pos(offset)))
+	return nil
+}
+
+// shareComments replaces the comment groups in Doc and Comment
+// fields of nodes in f, with the groups from f.Comments that start
+// at the same position.
+func shareComments(f *File) {
+	groups := make(map[token.Pos]*CommentGroup, len(f.Comments))
+	for _, g := range f.Comments {
+		if g != nil && len(g.List) > 0 {
+			groups[g.Pos()] = g
+		}
+	}
+	v := reflect.ValueOf(f).Elem()
+	for i := range v.NumField() {
+		if v.Type().Field(i).Name != "Comments" {
+			shareCommentsValue(v.Field(i), groups)
+		}
+	}
+}
+
+func shareCommentsValue(v reflect.Value, groups map[token.Pos]*CommentGroup) {
+	if v.Type() == commentGroupPtrType {
+		if g := v.Interface().(*CommentGroup); g != nil && len(g.List) > 0 {
+			if s, ok := groups[g.Pos()]; ok {
+				v.Set(reflect.ValueOf(s))
+			}
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			shareCommentsValue(v.Elem(), groups)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			shareCommentsValue(v.Index(i), groups)
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			shareCommentsValue(v.Field(i), groups)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package ast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+func TestJSONAllNodeTypes(t *testing.T) {
+	for _, n := range allNodeTypes {
+		typ := reflect.TypeOf(n)
+		t.Run(typ.Elem().Name(), func(t *testing.T) {
+			node := newFilledNode(typ)
+
+			fset := token.NewFileSet()
+			fset.AddFile("a.tgo", -1, 1<<16)
+			data, err := MarshalJSON(fset, node)
+			if err != nil {
+				t.Fatalf("MarshalJSON() = %v", err)
+			}
+
+			got, err := UnmarshalJSON(token.NewFileSet(), data)
+			if err != nil {
+				t.Fatalf("UnmarshalJSON() = %v", err)
+			}
+			if !Equal(node, got, 0) {
+				t.Errorf("round trip changed the node:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestJSONCorpus(t *testing.T) {
+	files, err := filepath.Glob("../parser/testdata/tgo/*.tgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files")
+	}
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fset := token.NewFileSet()
+			// Some of the files contain syntax errors on purpose,
+			// the partial ASTs must round trip too.
+			f, _ := parser.ParseFile(fset, path, src, parser.ParseComments|parser.SkipObjectResolution|parser.AllErrors)
+
+			data, err := MarshalJSON(fset, f)
+			if err != nil {
+				t.Fatalf("MarshalJSON() = %v", err)
+			}
+
+			fset2 := token.NewFileSet()
+			got, err := UnmarshalJSON(fset2, data)
+			if err != nil {
+				t.Fatalf("UnmarshalJSON() = %v", err)
+			}
+			if !Equal(f, got, IgnorePositions) {
+				t.Fatal("round trip changed the AST")
+			}
+
+			// Positions must resolve to the same line and column.
+			var want, have []token.Position
+			Inspect(f, func(n Node) bool {
+				if n != nil {
+					want = append(want, fset.Position(n.Pos()), fset.Position(n.End()))
+				}
+				return true
+			})
+			Inspect(got, func(n Node) bool {
+				if n != nil {
+					have = append(have, fset2.Position(n.Pos()), fset2.Position(n.End()))
+				}
+				return true
+			})
+			if !reflect.DeepEqual(want, have) {
+				t.Errorf("positions differ after round trip")
+			}
+
+			data2, err := MarshalJSON(fset2, got)
+			if err != nil {
+				t.Fatalf("MarshalJSON() = %v", err)
+			}
+			if !bytes.Equal(data, data2) {
+				t.Errorf("MarshalJSON(UnmarshalJSON(data)) != data")
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	const src = `package p
+
+func f(tgo.Ctx) error {
+	<div @class="a">"\{name}"</div>
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := f.Decls[0].(*FuncDecl).Body.List[0]
+	data, err := MarshalJSON(fset, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version int
+		Files   []struct{ Name string }
+		Node    struct {
+			Kind    string
+			OpenTag struct {
+				Kind    string
+				OpenPos struct{ File, Line, Column int }
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != JSONVersion {
+		t.Errorf("version = %v; want = %v", doc.Version, JSONVersion)
+	}
+	if len(doc.Files) != 1 || doc.Files[0].Name != "a.tgo" {
+		t.Errorf("files = %v; want = [{a.tgo}]", doc.Files)
+	}
+	if doc.Node.Kind != "ElementBlockStmt" || doc.Node.OpenTag.Kind != "OpenTag" {
+		t.Errorf("kinds = %q, %q; want = %q, %q", doc.Node.Kind, doc.Node.OpenTag.Kind, "ElementBlockStmt", "OpenTag")
+	}
+	if p := doc.Node.OpenTag.OpenPos; p.File != 0 || p.Line != 4 || p.Column != 2 {
+		t.Errorf("OpenTag.OpenPos = %+v; want = {File:0 Line:4 Column:2}", p)
+	}
+}
+
+var update = flag.Bool("update", false, "update golden (.golden) files")
+
+// TestJSONGolden covers the fields of the tgo nodes added after the
+// first version of the schema: the dynamic tag names of the open and
+// end tags, and the formats of the template literal parts.
+func TestJSONGolden(t *testing.T) {
+	const src = "package p\n\nfunc f() {\n\t<\\{tag}>\"\\{x:%q}\\{t:time.Kitchen}\"</\\{tag}>\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := f.Decls[0].(*FuncDecl).Body.List[0]
+	data, err := MarshalJSON(fset, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := json.Indent(&b, data, "", "\t"); err != nil {
+		t.Fatal(err)
+	}
+	b.WriteByte('\n')
+
+	golden := filepath.Join("testdata", "json_tgo.golden")
+	if *update {
+		if err := os.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("MarshalJSON() =\n%s\nwant:\n%s", b.Bytes(), want)
+	}
+
+	got, err := UnmarshalJSON(token.NewFileSet(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(block, got, IgnorePositions) {
+		t.Error("round trip changed the AST")
+	}
+}
+
+func TestJSONSharedComments(t *testing.T) {
+	const src = `package p
+
+// f is a function.
+func f() {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := MarshalJSON(fset, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := UnmarshalJSON(token.NewFileSet(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := n.(*File)
+	if got.Comments[0] != got.Decls[0].(*FuncDecl).Doc {
+		t.Error("Doc comment is not shared with File.Comments")
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{`{"version":2,"files":[],"node":null}`, "unsupported version"},
+		{`{"version":1,"files":[],"node":{"kind":"Foo"}}`, "unknown node kind"},
+		{`{"version":1,"files":[],"node":{"kind":"Ident","NamePos":null}}`, "missing field"},
+		{`{"version":1,"files":[],"node":{"kind":"Ident","NamePos":{"line":1,"column":1},"Name":"a"}}`, "invalid file index"},
+		{`{"version":1,"files":[{"name":"a","size":1,"lines":[0]}],"node":{"kind":"Ident","NamePos":{"line":3,"column":1},"Name":"a"}}`, "invalid position"},
+		{`{"version":1,"files":[],"node":{"kind":"BasicLit","ValuePos":null,"Kind":"BAD","Value":""}}`, "unknown token"},
+		{`{"version":1,"files":[],"node":{"kind":"ExprStmt","X":{"kind":"EmptyStmt","Semicolon":null,"Implicit":false}}}`, "not assignable"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalJSON(token.NewFileSet(), []byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("UnmarshalJSON(%s) = %v; want error containing %q", tt.data, err, tt.err)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package ast
 
 import (
+	"cmp"
+	"slices"
+
 	"github.com/mateusz834/tgoast/token"
 )
 
@@ -12,11 +15,11 @@ func walkTgo(v Visitor, node Node) bool {
 		Walk(v, n.EndTag)
 		return true
 	case *OpenTag:
-		Walk(v, n.Name)
+		walkTagName(v, n.Name, n.DynamicName)
 		walkList(v, n.Body)
 		return true
 	case *EndTag:
-		Walk(v, n.Name)
+		walkTagName(v, n.Name, n.DynamicName)
 		return true
 	case *AttributeStmt:
 		Walk(v, n.AttrName)
@@ -31,30 +34,61 @@ func walkTgo(v Visitor, node Node) bool {
 		return true
 	case *TemplateLiteralPart:
 		Walk(v, n.X)
+		if n.Format != nil {
+			Walk(v, n.Format)
+		}
+		return true
+	case *FormatVerb:
 		return true
 	default:
 		return false
 	}
 }
 
+func walkTagName(v Visitor, name *Ident, dynamicName *TemplateLiteralPart) {
+	if name != nil {
+		Walk(v, name)
+	}
+	if dynamicName != nil {
+		Walk(v, dynamicName)
+	}
+}
+
 type (
+	// An ElementBlockStmt node represents an element with an end tag.
+	//
+	// A return statement in the Body (at any depth, excluding function
+	// literals) does not skip the end tag: when it is executed, the
+	// result expressions are evaluated first, then the end tags of all
+	// enclosing elements are written, innermost first, and then the
+	// function returns (running deferred calls as usual). The output
+	// is thus always well-formed, callers that receive a non-nil error
+	// are free to discard it. Other jumps out of the Body (break,
+	// continue and goto) are not permitted.
 	ElementBlockStmt struct {
 		OpenTag *OpenTag
 		Body    []Stmt
 		EndTag  *EndTag
 	}
 
+	// An OpenTag node represents an open tag, either with a static
+	// name (<div>) or with a dynamic name (<\{x}>).
 	OpenTag struct {
-		OpenPos  token.Pos // position of the "<" sign.
-		Name     *Ident
-		Body     []Stmt
-		ClosePos token.Pos // position of the ">" sign.
+		OpenPos     token.Pos            // position of the "<" sign.
+		Name        *Ident               // static name; or nil
+		DynamicName *TemplateLiteralPart // dynamic name; or nil
+		Body        []Stmt
+		ClosePos    token.Pos // position of the ">" sign.
 	}
 
+	// An EndTag node represents an end tag, either with a static name
+	// (</div>), with a dynamic name (</\{x}>) or without a name (</>).
+	// An end tag without a name closes the innermost open tag.
 	EndTag struct {
-		OpenPos  token.Pos // position of the "</" sign.
-		Name     *Ident
-		ClosePos token.Pos // position of the ">" sign.
+		OpenPos     token.Pos            // position of the "</" sign.
+		Name        *Ident               // static name; or nil
+		DynamicName *TemplateLiteralPart // dynamic name; or nil
+		ClosePos    token.Pos            // position of the ">" sign.
 	}
 
 	AttributeStmt struct {
@@ -92,11 +126,128 @@ func (s *TemplateLiteralExpr) Pos() token.Pos { return s.OpenPos }
 func (s *TemplateLiteralExpr) End() token.Pos { return s.ClosePos + 1 }
 func (s *TemplateLiteralExpr) exprNode()      {}
 
+// A TemplateLiteralPart node represents an interpolated expression
+// ("\{X}") in a [TemplateLiteralExpr] or in a dynamic tag name.
+//
+// The expression of a part in a [TemplateLiteralExpr] might be followed
+// by a format ("\{X:Format}"), either a printf-style verb ("\{x:%.2f}"),
+// equivalent to "\{fmt.Sprintf("%.2f", x)}", or a layout expression
+// ("\{t:time.RFC3339}"), equivalent to "\{t.Format(time.RFC3339)}".
 type TemplateLiteralPart struct {
-	LBrace token.Pos
+	LBrace token.Pos // position of the "{" sign
 	X      Expr
-	RBrace token.Pos
+	Colon  token.Pos // position of the ":" sign; or token.NoPos
+	Format Expr      // *FormatVerb or a layout expression; or nil
+	RBrace token.Pos // position of the "}" sign
 }
 
 func (s *TemplateLiteralPart) Pos() token.Pos { return s.LBrace }
 func (s *TemplateLiteralPart) End() token.Pos { return s.RBrace + 1 }
+
+// A FormatVerb node represents a printf-style verb (%.2f) in
+// the Format of a [TemplateLiteralPart].
+type FormatVerb struct {
+	VerbPos token.Pos // position of the "%" sign
+	Verb    string    // verb, including the "%" sign
+}
+
+func (s *FormatVerb) Pos() token.Pos { return s.VerbPos }
+func (s *FormatVerb) End() token.Pos { return token.Pos(int(s.VerbPos) + len(s.Verb)) }
+func (s *FormatVerb) exprNode()      {}
+
+// A tgoCommentRange is a source range [from, to) inside of a tgo node,
+// that does not contain any other node. Comment groups that start in
+// that range are associated with node by [NewCommentMap].
+type tgoCommentRange struct {
+	from, to token.Pos
+	node     Node
+}
+
+type tgoCommentRanges []tgoCommentRange
+
+// tagName returns the name node of a tag, or nil.
+func tagName(name *Ident, dynamicName *TemplateLiteralPart) Node {
+	if dynamicName != nil {
+		return dynamicName
+	}
+	if name != nil {
+		return name
+	}
+	return nil
+}
+
+// newTgoCommentRanges collects the comment ranges of all tgo nodes
+// in nodes, nodes must be in source order.
+func newTgoCommentRanges(fset *token.FileSet, nodes []Node) tgoCommentRanges {
+	var ranges tgoCommentRanges
+
+	// add adds the range [from, to) associated with n. If prev is valid,
+	// the range starts at the beginning of the line that follows the
+	// one on which prev ends, so that trailing comments stay with
+	// the node that ends at prev.
+	add := func(n Node, from, to, prev token.Pos) {
+		if prev.IsValid() {
+			f := fset.File(prev)
+			if line := f.Line(prev); line < f.LineCount() {
+				from = max(from, f.LineStart(line+1))
+			} else {
+				return
+			}
+		}
+		if from.IsValid() && from < to {
+			ranges = append(ranges, tgoCommentRange{from, to, n})
+		}
+	}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *OpenTag:
+			name := tagName(n.Name, n.DynamicName)
+			if name == nil || !n.ClosePos.IsValid() {
+				continue
+			}
+			add(n, n.OpenPos, name.Pos(), token.NoPos)
+			if len(n.Body) == 0 {
+				add(n, name.End(), n.ClosePos, token.NoPos)
+			} else {
+				last := n.Body[len(n.Body)-1]
+				add(n, last.End(), n.ClosePos, last.End()-1)
+			}
+		case *EndTag:
+			if !n.ClosePos.IsValid() {
+				continue
+			}
+			if n.DynamicName != nil {
+				add(n, n.OpenPos, n.DynamicName.Pos(), token.NoPos)
+				add(n, n.DynamicName.End(), n.ClosePos, token.NoPos)
+			} else {
+				add(n, n.OpenPos, n.ClosePos, token.NoPos)
+			}
+		case *ElementBlockStmt:
+			var last Node = n.OpenTag
+			if len(n.Body) != 0 {
+				last = n.Body[len(n.Body)-1]
+			}
+			add(n, last.End(), n.EndTag.OpenPos, last.End()-1)
+		}
+	}
+
+	slices.SortFunc(ranges, func(a, b tgoCommentRange) int {
+		return cmp.Compare(a.from, b.from)
+	})
+	return ranges
+}
+
+// lookup returns the node associated with the comment that starts at pos.
+func (r tgoCommentRanges) lookup(pos token.Pos) Node {
+	i, found := slices.BinarySearchFunc(r, pos, func(r tgoCommentRange, pos token.Pos) int {
+		return cmp.Compare(r.from, pos)
+	})
+	if !found {
+		i--
+	}
+	if i >= 0 && pos < r[i].to {
+		return r[i].node
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Tgoextract extracts the translatable text of tgo sources into
+// a message catalog.
+//
+// Usage:
+//
+//	tgoextract [-format pot|icu] [-o file] [path ...]
+//
+// The paths name .tgo and .go files, or directories, whose files are
+// extracted recursively. Without paths the current directory is used.
+// The catalog is a gettext template (pot, the default) or an ICU message
+// catalog in JSON (icu), it is written to the standard output, unless
+// the -o flag is set. See package i18n for the extracted text.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/i18n"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+var (
+	format = flag.String("format", "pot", "catalog `format`: pot or icu")
+	output = flag.String("o", "", "write the catalog to `file`")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: tgoextract [flags] [path ...]\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	var write func(io.Writer, []*i18n.Message) error
+	switch *format {
+	case "pot":
+		write = i18n.WritePOT
+	case "icu":
+		write = i18n.WriteICU
+	default:
+		usage()
+	}
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	var err error
+	for _, path := range paths {
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name := d.Name(); path != "." && len(name) > 1 && (name[0] == '.' || name[0] == '_' || name == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(path); ext != ".tgo" && ext != ".go" {
+				return nil
+			}
+			f, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+			if err != nil {
+				return err
+			}
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	msgs := i18n.Extract(fset, files)
+	if *output == "" {
+		err = write(os.Stdout, msgs)
+	} else {
+		var f *os.File
+		if f, err = os.Create(*output); err == nil {
+			err = write(f, msgs)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -82,6 +82,10 @@ type Func struct {
 	Orig  string // original receiver "T" or "*T"
 	Level int    // embedding level; 0 means not embedded
 
+	// Component describes the markup rendered by a tgo function
+	// or method (see [Component]); it is nil for other functions.
+	Component *Component
+
 	// Examples is a sorted list of examples associated with this
 	// function or method. Examples are extracted from _test.go files
 	// provided to NewFromFiles.
@@ -32,6 +32,10 @@ type Example struct {
 	Unordered   bool
 	EmptyOutput bool // expect empty output
 	Order       int  // original source code order
+
+	// Component is set for examples that are tgo components,
+	// i.e. func(tgo.Ctx) error; their Output is the rendered HTML.
+	Component bool
 }
 
 // Examples returns the examples found in testFiles, sorted by Name field.
@@ -49,12 +53,16 @@ type Example struct {
 //     example function, zero test, fuzz test, or benchmark function, and at
 //     least one top-level function, type, variable, or constant declaration
 //     other than the example function.
+//
+// An example function might also be a tgo component (func(tgo.Ctx) error),
+// its output is the rendered HTML. Component examples are not playable.
 func Examples(testFiles ...*ast.File) []*Example {
 	var list []*Example
 	for _, file := range testFiles {
 		hasTests := false // file contains tests, fuzz test, or benchmarks
 		numDecl := 0      // number of non-import declarations in the file
 		var flist []*Example
+		tgoName := tgoImportName(file)
 		for _, decl := range file.Decls {
 			if g, ok := decl.(*ast.GenDecl); ok && g.Tok != token.IMPORT {
 				numDecl++
@@ -73,7 +81,9 @@ func Examples(testFiles ...*ast.File) []*Example {
 			if !isTest(name, "Example") {
 				continue
 			}
-			if params := f.Type.Params; len(params.List) != 0 {
+			// a component example takes only the tgo.Ctx
+			component := isComponent(f, tgoName) && f.Type.Params.NumFields() == 1
+			if params := f.Type.Params; len(params.List) != 0 && !component {
 				continue // function has params; not a valid example
 			}
 			if f.Body == nil { // ast.File.Body nil dereference (see issue 28044)
@@ -83,17 +93,22 @@ func Examples(testFiles ...*ast.File) []*Example {
 			if f.Doc != nil {
 				doc = f.Doc.Text()
 			}
+			var play *ast.File
+			if !component {
+				play = playExample(file, f)
+			}
 			output, unordered, hasOutput := exampleOutput(f.Body, file.Comments)
 			flist = append(flist, &Example{
 				Name:        name[len("Example"):],
 				Doc:         doc,
 				Code:        f.Body,
-				Play:        playExample(file, f),
+				Play:        play,
 				Comments:    file.Comments,
 				Output:      output,
 				Unordered:   unordered,
 				EmptyOutput: output == "" && hasOutput,
 				Order:       len(flist),
+				Component:   component,
 			})
 		}
 		if !hasTests && numDecl > 1 && len(flist) == 1 {
@@ -101,7 +116,9 @@ func Examples(testFiles ...*ast.File) []*Example {
 			// other top-level declarations, and no tests or
 			// benchmarks, use the whole file as the example.
 			flist[0].Code = file
-			flist[0].Play = playExampleFile(file)
+			if !flist[0].Component {
+				flist[0].Play = playExampleFile(file)
+			}
 		}
 		list = append(list, flist...)
 	}
@@ -67,8 +67,9 @@ func recvParam(p ast.Expr) string {
 // set creates the corresponding Func for f and adds it to mset.
 // If there are multiple f's with the same name, set keeps the first
 // one with documentation; conflicts are ignored. The boolean
-// specifies whether to leave the AST untouched.
-func (mset methodSet) set(f *ast.FuncDecl, preserveAST bool) {
+// specifies whether to leave the AST untouched; comp describes f,
+// if it is a tgo component.
+func (mset methodSet) set(f *ast.FuncDecl, comp *Component, preserveAST bool) {
 	name := f.Name.Name
 	if g := mset[name]; g != nil && g.Doc != "" {
 		// A function with the same name has already been registered;
@@ -94,6 +95,8 @@ func (mset methodSet) set(f *ast.FuncDecl, preserveAST bool) {
 		Decl: f,
 		Recv: recv,
 		Orig: recv,
+
+		Component: comp,
 	}
 	if !preserveAST {
 		f.Doc = nil // doc consumed - remove from AST
@@ -187,6 +190,7 @@ type reader struct {
 	imports      map[string]int
 	hasDotImp    bool // if set, package contains a dot import
 	importByName map[string]string
+	tgoName      string // name of the tgo import of the file being read, see tgoImportName
 
 	// declarations
 	values []*Value // consts and vars
@@ -402,6 +406,9 @@ func (r *reader) isPredeclared(n string) bool {
 
 // readFunc processes a func or method declaration.
 func (r *reader) readFunc(fun *ast.FuncDecl) {
+	// describe tgo components before the body is stripped
+	comp := newComponent(fun, r.tgoName)
+
 	// strip function body if requested.
 	if r.mode&PreserveAST == 0 {
 		fun.Body = nil
@@ -422,7 +429,7 @@ func (r *reader) readFunc(fun *ast.FuncDecl) {
 			return
 		}
 		if typ := r.lookupType(recvTypeName); typ != nil {
-			typ.methods.set(fun, r.mode&PreserveAST != 0)
+			typ.methods.set(fun, comp, r.mode&PreserveAST != 0)
 		}
 		// otherwise ignore the method
 		// TODO(gri): There may be exported methods of non-exported types
@@ -462,13 +469,13 @@ func (r *reader) readFunc(fun *ast.FuncDecl) {
 		// If there is exactly one result type,
 		// associate the function with that type.
 		if numResultTypes == 1 {
-			typ.funcs.set(fun, r.mode&PreserveAST != 0)
+			typ.funcs.set(fun, comp, r.mode&PreserveAST != 0)
 			return
 		}
 	}
 
 	// just an ordinary function
-	r.funcs.set(fun, r.mode&PreserveAST != 0)
+	r.funcs.set(fun, comp, r.mode&PreserveAST != 0)
 }
 
 // lookupTypeParam searches for type parameters named name within the tparams
@@ -684,6 +691,7 @@ func (r *reader) readPackage(pkg *ast.Package, mode Mode) {
 
 	// process functions now that we have better type information
 	for _, f := range pkg.Files {
+		r.tgoName = tgoImportName(f)
 		for _, decl := range f.Decls {
 			if d, ok := decl.(*ast.FuncDecl); ok {
 				r.readFunc(d)
@@ -0,0 +1,221 @@
+package doc
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+)
+
+// tgoPath is the import path of the tgo package.
+const tgoPath = "github.com/mateusz834/tgo"
+
+// A Component describes a tgo component, a function or method
+// that renders markup, i.e. func(tgo.Ctx, ...) error.
+type Component struct {
+	// Roots are the root elements rendered by the component, i.e. the
+	// elements that are not nested inside of other elements of the
+	// function body, in source order. Consecutive identical elements
+	// are listed once. Elements of function literals are ignored.
+	Roots []*Element
+
+	// HTML is the rendered output of a constant component, one that
+	// consists only of elements, static attributes and string text,
+	// followed by "return nil"; otherwise it is empty.
+	HTML string
+}
+
+// An Element summarizes an element rendered by a component.
+type Element struct {
+	Name  string   // static tag name; or "" for dynamic tag names
+	Attrs []string // names of the attributes set in the open tag, sorted
+}
+
+// tgoImportName returns the name under which file imports the tgo
+// package, "." for a dot import, or "" if file does not import it.
+func tgoImportName(file *ast.File) string {
+	for _, s := range file.Imports {
+		if path, err := strconv.Unquote(s.Path.Value); err != nil || path != tgoPath {
+			continue
+		}
+		if s.Name != nil {
+			if s.Name.Name == "_" {
+				continue
+			}
+			return s.Name.Name
+		}
+		return assumedPackageName(tgoPath)
+	}
+	return ""
+}
+
+// isComponent reports whether fun is a tgo function, with tgo imported
+// as tgoName. As in the type checker, the first parameter must be of
+// type tgo.Ctx and the only result of type error; as the documentation
+// is computed without type information, the types are matched by name.
+func isComponent(fun *ast.FuncDecl, tgoName string) bool {
+	if tgoName == "" || fun.Type.Params.NumFields() == 0 || fun.Type.Results.NumFields() != 1 {
+		return false
+	}
+	if res, ok := fun.Type.Results.List[0].Type.(*ast.Ident); !ok || res.Name != "error" {
+		return false
+	}
+	switch x := fun.Type.Params.List[0].Type.(type) {
+	case *ast.Ident:
+		return tgoName == "." && x.Name == "Ctx"
+	case *ast.SelectorExpr:
+		pkg, ok := x.X.(*ast.Ident)
+		return ok && pkg.Name == tgoName && x.Sel.Name == "Ctx"
+	}
+	return false
+}
+
+// newComponent returns the component description of fun, if it is
+// a tgo function (see isComponent); otherwise it returns nil.
+func newComponent(fun *ast.FuncDecl, tgoName string) *Component {
+	if !isComponent(fun, tgoName) {
+		return nil
+	}
+	c := &Component{}
+	if fun.Body == nil {
+		return c
+	}
+	ast.Inspect(fun.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ElementBlockStmt:
+			c.addRoot(newElement(n.OpenTag))
+			return false
+		case *ast.OpenTag:
+			// A void element.
+			c.addRoot(newElement(n))
+			return false
+		}
+		return true
+	})
+	if html, ok := renderConstant(fun.Body.List); ok {
+		c.HTML = html
+	}
+	return c
+}
+
+// addRoot appends el to the root elements of c, unless it is equal
+// to the last one.
+func (c *Component) addRoot(el *Element) {
+	if len(c.Roots) == 0 || !c.Roots[len(c.Roots)-1].equal(el) {
+		c.Roots = append(c.Roots, el)
+	}
+}
+
+func newElement(tag *ast.OpenTag) *Element {
+	el := &Element{}
+	if tag.Name != nil {
+		el.Name = tag.Name.Name
+	}
+	ast.Inspect(&ast.BlockStmt{List: tag.Body}, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AttributeStmt:
+			if id, ok := n.AttrName.(*ast.Ident); ok && !slices.Contains(el.Attrs, id.Name) {
+				el.Attrs = append(el.Attrs, id.Name)
+			}
+		}
+		return true
+	})
+	slices.Sort(el.Attrs)
+	return el
+}
+
+func (el *Element) equal(other *Element) bool {
+	return el.Name == other.Name && slices.Equal(el.Attrs, other.Attrs)
+}
+
+// renderConstant renders the statements of a constant component body,
+// it reports false if the output depends on the execution.
+func renderConstant(list []ast.Stmt) (string, bool) {
+	var b strings.Builder
+	if len(list) == 0 {
+		return "", false
+	}
+	if ret, ok := list[len(list)-1].(*ast.ReturnStmt); !ok || len(ret.Results) != 1 || !isNil(ret.Results[0]) {
+		return "", false
+	}
+	if !renderStmts(&b, list[:len(list)-1]) {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func renderStmts(b *strings.Builder, list []ast.Stmt) bool {
+	for _, s := range list {
+		switch s := s.(type) {
+		case *ast.EmptyStmt:
+		case *ast.ExprStmt:
+			text, ok := stringLit(s.X)
+			if !ok {
+				return false
+			}
+			b.WriteString(html.EscapeString(text))
+		case *ast.ElementBlockStmt:
+			if !renderOpenTag(b, s.OpenTag) || !renderStmts(b, s.Body) {
+				return false
+			}
+			b.WriteString("</" + s.OpenTag.Name.Name + ">")
+		case *ast.OpenTag:
+			// A void element, without an end tag.
+			if !renderOpenTag(b, s) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func renderOpenTag(b *strings.Builder, t *ast.OpenTag) bool {
+	if t.Name == nil {
+		return false
+	}
+	b.WriteString("<" + t.Name.Name)
+	for _, st := range t.Body {
+		attr, ok := st.(*ast.AttributeStmt)
+		if !ok {
+			return false
+		}
+		name, ok := attr.AttrName.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		b.WriteString(" " + name.Name)
+		if attr.Value != nil {
+			val, ok := stringLit(attr.Value)
+			if !ok {
+				return false
+			}
+			b.WriteString(`="` + html.EscapeString(val) + `"`)
+		}
+	}
+	b.WriteString(">")
+	return true
+}
+
+// stringLit returns the value of x, if it is a string literal.
+func stringLit(x ast.Expr) (string, bool) {
+	lit, ok := x.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+func isNil(x ast.Expr) bool {
+	id, ok := ast.Unparen(x).(*ast.Ident)
+	return ok && id.Name == "nil"
+}
@@ -0,0 +1,134 @@
+package doc_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/doc"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+const componentSrc = `package p
+
+import "github.com/mateusz834/tgo"
+
+// Card renders a card.
+func Card(ctx tgo.Ctx, title string) error {
+	<div @class="card">
+		<h2>"\{title}"</h2>
+	</div>
+	return nil
+}
+
+// Logo renders the logo.
+func Logo(tgo.Ctx) error {
+	<a @href="/" @title="Home & away">
+		<img @src="logo.png" @alt="Logo" @hidden>
+	</a>
+	"<3"
+	return nil
+}
+
+func List(ctx tgo.Ctx, items []string) error {
+	for _, item := range items {
+		<li @class="item">"\{item}"</li>
+		<li @class="item">"\{item}"</li>
+	}
+	<\{tag}></\{tag}>
+	return nil
+}
+
+type Button struct{}
+
+func (Button) Render(tgo.Ctx) error {
+	<button @name="ok">"OK"</button>
+	return nil
+}
+
+func F(ctx tgo.Ctx) int { return 0 }
+
+func G() error { return nil }
+`
+
+const componentTestSrc = `package p_test
+
+import "github.com/mateusz834/tgo"
+
+func ExampleLogo(ctx tgo.Ctx) error {
+	<p>"Hello"</p>
+	return nil
+	// Output: <p>Hello</p>
+}
+
+func ExampleCard(ctx tgo.Ctx, title string) error {
+	return nil
+	// Output:
+}
+`
+
+func TestComponents(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, src := range []struct{ name, src string }{
+		{"p.go", componentSrc},
+		{"p_test.go", componentTestSrc},
+	} {
+		f, err := parser.ParseFile(fset, src.name, src.src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	p, err := doc.NewFromFiles(fset, files, "example.com/p")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := make(map[string]*doc.Func)
+	for _, f := range p.Funcs {
+		funcs[f.Name] = f
+	}
+	for _, typ := range p.Types {
+		for _, m := range typ.Methods {
+			funcs[typ.Name+"."+m.Name] = m
+		}
+	}
+
+	want := map[string]*doc.Component{
+		"Card": {Roots: []*doc.Element{{Name: "div", Attrs: []string{"class"}}}},
+		"Logo": {
+			Roots: []*doc.Element{{Name: "a", Attrs: []string{"href", "title"}}},
+			HTML:  `<a href="/" title="Home &amp; away"><img src="logo.png" alt="Logo" hidden></a>&lt;3`,
+		},
+		"List": {Roots: []*doc.Element{{Name: "li", Attrs: []string{"class"}}, {}}},
+		"Button.Render": {
+			Roots: []*doc.Element{{Name: "button", Attrs: []string{"name"}}},
+			HTML:  `<button name="ok">OK</button>`,
+		},
+		"F": nil,
+		"G": nil,
+	}
+	for name, want := range want {
+		f := funcs[name]
+		if f == nil {
+			t.Errorf("missing func %s", name)
+			continue
+		}
+		if !reflect.DeepEqual(f.Component, want) {
+			t.Errorf("%s: Component = %+v; want %+v", name, f.Component, want)
+		}
+	}
+
+	examples := funcs["Logo"].Examples
+	if len(examples) != 1 {
+		t.Fatalf("got %d examples of Logo; want 1", len(examples))
+	}
+	if ex := examples[0]; !ex.Component || ex.Output != "<p>Hello</p>\n" || ex.Play != nil {
+		t.Errorf("example: Component = %v, Output = %q, Play = %v; want true, %q, nil", ex.Component, ex.Output, ex.Play, "<p>Hello</p>\n")
+	}
+	if len(funcs["Card"].Examples) != 0 {
+		t.Errorf("got examples of Card; want none (the example takes parameters other than tgo.Ctx)")
+	}
+}
@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WritePOT writes the messages as a gettext template (.pot file).
+// The msgid of a message is its ID, the placeholders are described by
+// extracted comments and the source positions by references.
+func WritePOT(w io.Writer, msgs []*Message) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	for _, m := range msgs {
+		bw.WriteString("\n")
+		for _, c := range comments(m) {
+			fmt.Fprintf(bw, "#. %s\n", c)
+		}
+		for _, ref := range m.Refs {
+			fmt.Fprintf(bw, "#: %s:%d\n", ref.Filename, ref.Line)
+		}
+		if strings.ContainsAny(m.ID, "%") {
+			bw.WriteString("#, no-c-format\n")
+		}
+		fmt.Fprintf(bw, "msgid %s\nmsgstr \"\"\n", quotePO(m.ID))
+	}
+	return bw.Flush()
+}
+
+// comments returns the descriptions of the attributes
+// and the placeholders of m.
+func comments(m *Message) []string {
+	var list []string
+	if len(m.Attrs) > 0 {
+		list = append(list, "attribute @"+strings.Join(m.Attrs, ", @"))
+	}
+	for _, p := range m.Placeholders {
+		if p.Format != "" {
+			list = append(list, fmt.Sprintf("{%s}: %s (%s)", p.Name, p.Expr, p.Format))
+		} else {
+			list = append(list, fmt.Sprintf("{%s}: %s", p.Name, p.Expr))
+		}
+	}
+	return list
+}
+
+// quotePO quotes s as a PO string, multi-line strings
+// are split after each newline.
+func quotePO(s string) string {
+	quote := func(s string) string {
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+		return `"` + r.Replace(s) + `"`
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) == 1 || len(lines) == 2 && lines[1] == "" {
+		return quote(s)
+	}
+	var b strings.Builder
+	b.WriteString(`""`)
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString("\n" + quote(line))
+		}
+	}
+	return b.String()
+}
+
+// An icuMessage is a message of an ICU message catalog,
+// in the format of FormatJS.
+type icuMessage struct {
+	DefaultMessage string   `json:"defaultMessage"`
+	Description    string   `json:"description,omitempty"`
+	References     []string `json:"references"`
+}
+
+// WriteICU writes the messages as an ICU message catalog, a JSON
+// object mapping the IDs of the messages to their default message
+// (the ID), description (see WritePOT) and source positions.
+func WriteICU(w io.Writer, msgs []*Message) error {
+	catalog := make(map[string]icuMessage, len(msgs))
+	for _, m := range msgs {
+		im := icuMessage{
+			DefaultMessage: m.ID,
+			Description:    strings.Join(comments(m), "; "),
+			References:     make([]string, 0, len(m.Refs)),
+		}
+		for _, ref := range m.Refs {
+			im.References = append(im.References, fmt.Sprintf("%s:%d", ref.Filename, ref.Line))
+		}
+		catalog[m.ID] = im
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(catalog)
+}
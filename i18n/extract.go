@@ -0,0 +1,264 @@
+// Package i18n extracts the user-visible text of tgo sources into
+// message catalogs for translation.
+//
+// The text of tgo functions consists of string literals and template
+// literals in statement position (text nodes), and of the values of
+// the attributes holding text for humans (see TextAttributes). The
+// parts of template literals become named placeholders of the message,
+// e.g. "Hello \{user.Name}!" is extracted as "Hello {Name}!".
+//
+// Text is not extracted when it is marked as non-translatable, either
+// by the translate="no" attribute (as in HTML) of an enclosing element,
+// or by an "i18n:ignore" comment on the same or the preceding line:
+//
+//	<code @translate="no">"go build ./..."</code>
+//	"v1.2.3" // i18n:ignore
+package i18n
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// TextAttributes are the names of the attributes, whose values are
+// extracted.
+var TextAttributes = []string{"alt", "label", "placeholder", "title"}
+
+// A Message is a translatable text.
+type Message struct {
+	// ID is the text of the message in the ICU message format, the
+	// placeholders are enclosed in braces, literal braces and
+	// apostrophes are quoted with apostrophes.
+	ID string
+
+	// Placeholders are the placeholders of the message, in the
+	// order of their first appearance.
+	Placeholders []Placeholder
+
+	// Attrs are the names of the attributes the message was
+	// extracted from, empty for text nodes only.
+	Attrs []string
+
+	// Refs are the source positions of the message.
+	Refs []token.Position
+}
+
+// A Placeholder is a named template literal part.
+type Placeholder struct {
+	Name   string // unique within the message
+	Expr   string // the source of the expression
+	Format string // the format verb or layout; or ""
+}
+
+// Extract returns the messages of the files, sorted by ID. Messages
+// with the same ID are merged.
+func Extract(fset *token.FileSet, files []*ast.File) []*Message {
+	e := extractor{fset: fset, msgs: make(map[string]*Message)}
+	for _, f := range files {
+		e.ignored = ignoredLines(fset, f)
+		ast.Walk(&e, f)
+	}
+	msgs := make([]*Message, 0, len(e.msgs))
+	for _, m := range e.msgs {
+		msgs = append(msgs, m)
+	}
+	slices.SortFunc(msgs, func(a, b *Message) int { return strings.Compare(a.ID, b.ID) })
+	return msgs
+}
+
+type extractor struct {
+	fset    *token.FileSet
+	msgs    map[string]*Message
+	ignored map[int]bool // lines with the ignore directive
+}
+
+// ignoredLines returns the lines of f, that hold or follow
+// an i18n:ignore comment.
+func ignoredLines(fset *token.FileSet, f *ast.File) map[int]bool {
+	lines := make(map[int]bool)
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/"), "//"))
+			if text == "i18n:ignore" {
+				line := fset.Position(c.Pos()).Line
+				lines[line] = true
+				lines[line+1] = true
+			}
+		}
+	}
+	return lines
+}
+
+func (e *extractor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.ElementBlockStmt:
+		if translateNo(n.OpenTag) {
+			return nil // as in HTML, including the attributes
+		}
+	case *ast.ExprStmt:
+		e.text(n.X, "")
+	case *ast.AttributeStmt:
+		name := strings.ToLower(attrName(n))
+		if slices.Contains(TextAttributes, name) {
+			e.text(n.Value, name)
+		}
+	}
+	return e
+}
+
+func attrName(a *ast.AttributeStmt) string {
+	if id, ok := a.AttrName.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// translateNo reports whether the tag has the translate="no" attribute.
+func translateNo(tag *ast.OpenTag) bool {
+	for _, s := range tag.Body {
+		a, ok := s.(*ast.AttributeStmt)
+		if !ok || !strings.EqualFold(attrName(a), "translate") {
+			continue
+		}
+		if lit, ok := a.Value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if v, err := strconv.Unquote(lit.Value); err == nil && strings.EqualFold(v, "no") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// text extracts the message of x, the value of the attribute attr,
+// or a text node when attr is empty.
+func (e *extractor) text(x ast.Expr, attr string) {
+	if x == nil || e.ignored[e.fset.Position(x.Pos()).Line] {
+		return
+	}
+
+	var (
+		id           strings.Builder
+		placeholders []Placeholder
+		hasText      bool
+	)
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return
+		}
+		s, err := strconv.Unquote(x.Value)
+		if err != nil {
+			return
+		}
+		hasText = isText(s)
+		id.WriteString(quoteICU(s))
+	case *ast.TemplateLiteralExpr:
+		for i, raw := range x.Strings {
+			if i == 0 {
+				raw = strings.TrimPrefix(raw, `"`)
+			}
+			if i == len(x.Strings)-1 {
+				raw = strings.TrimSuffix(raw, `"`)
+			}
+			s, err := strconv.Unquote(`"` + raw + `"`)
+			if err != nil {
+				return
+			}
+			hasText = hasText || isText(s)
+			id.WriteString(quoteICU(s))
+			if i < len(x.Parts) {
+				ph := placeholder(x.Parts[i], placeholders)
+				if !slices.Contains(placeholders, ph) {
+					placeholders = append(placeholders, ph)
+				}
+				fmt.Fprintf(&id, "{%s}", ph.Name)
+			}
+		}
+	default:
+		return
+	}
+	if !hasText {
+		return
+	}
+
+	m := e.msgs[id.String()]
+	if m == nil {
+		m = &Message{ID: id.String(), Placeholders: placeholders}
+		e.msgs[m.ID] = m
+	}
+	if attr != "" && !slices.Contains(m.Attrs, attr) {
+		m.Attrs = append(m.Attrs, attr)
+		slices.Sort(m.Attrs)
+	}
+	m.Refs = append(m.Refs, e.fset.Position(x.Pos()))
+	slices.SortFunc(m.Refs, func(a, b token.Position) int {
+		return cmp.Or(strings.Compare(a.Filename, b.Filename), cmp.Compare(a.Offset, b.Offset))
+	})
+}
+
+// isText reports whether s holds text for humans, i.e. a letter.
+func isText(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// quoteICU quotes the ICU syntax characters of s.
+func quoteICU(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\'':
+			b.WriteString("''")
+		case '{', '}':
+			b.WriteString("'" + string(r) + "'")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// placeholder returns the placeholder of part. Parts with identical
+// expressions share the placeholder with prev, other names are unique.
+func placeholder(part *ast.TemplateLiteralPart, prev []Placeholder) Placeholder {
+	ph := Placeholder{Expr: types.ExprString(part.X)}
+	switch f := part.Format.(type) {
+	case nil:
+	case *ast.FormatVerb:
+		ph.Format = f.Verb
+	default:
+		ph.Format = types.ExprString(f)
+	}
+	for _, p := range prev {
+		if p.Expr == ph.Expr && p.Format == ph.Format {
+			return p
+		}
+	}
+
+	name := "arg"
+	switch x := ast.Unparen(part.X).(type) {
+	case *ast.Ident:
+		name = x.Name
+	case *ast.SelectorExpr:
+		name = x.Sel.Name
+	case *ast.CallExpr:
+		switch fun := ast.Unparen(x.Fun).(type) {
+		case *ast.Ident:
+			name = fun.Name
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+		}
+	}
+	ph.Name = name
+	for i := 2; slices.ContainsFunc(prev, func(p Placeholder) bool { return p.Name == ph.Name }); i++ {
+		ph.Name = name + strconv.Itoa(i)
+	}
+	return ph
+}
@@ -0,0 +1,121 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+const src = `package page
+
+import "github.com/mateusz834/tgo"
+
+func page(_ tgo.Ctx, user User, n int) error {
+	<div @title="Greeting" @class="greeting">
+		"Hello \{user.Name}, you have \{n:%d} new messages"
+		<img @alt="Avatar of \{user.Name}" @src="\{user.Avatar}">
+		"Say \"hi\" to {everyone}"
+		"Hello \{user.Name}, you have \{n:%d} new messages"
+		"\{user.Name} and \{user.Name()} by \{name(user)} for \{user.First + user.Last}"
+		"\{user.Name} \{n}"
+	</div>
+	<code @translate="no" @title="Not translated">
+		"go build ./..."
+	</code>
+	"v1.2.3" // i18n:ignore
+	// i18n:ignore
+	"Ignored"
+	"Multi-line\ntext"
+	" | "
+	return nil
+}
+`
+
+func extract(t *testing.T) []*Message {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "page.tgo", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Extract(fset, []*ast.File{f})
+}
+
+func TestWritePOT(t *testing.T) {
+	var b strings.Builder
+	if err := WritePOT(&b, extract(t)); err != nil {
+		t.Fatal(err)
+	}
+	const want = `msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#. attribute @alt
+#. {Name}: user.Name
+#: page.tgo:8
+msgid "Avatar of {Name}"
+msgstr ""
+
+#. attribute @title
+#: page.tgo:6
+msgid "Greeting"
+msgstr ""
+
+#. {Name}: user.Name
+#. {n}: n (%d)
+#: page.tgo:7
+#: page.tgo:10
+msgid "Hello {Name}, you have {n} new messages"
+msgstr ""
+
+#: page.tgo:20
+msgid ""
+"Multi-line\n"
+"text"
+msgstr ""
+
+#: page.tgo:9
+msgid "Say \"hi\" to '{'everyone'}'"
+msgstr ""
+
+#. {Name}: user.Name
+#. {Name2}: user.Name()
+#. {name}: name(user)
+#. {arg}: user.First + user.Last
+#: page.tgo:11
+msgid "{Name} and {Name2} by {name} for {arg}"
+msgstr ""
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteICU(t *testing.T) {
+	var b strings.Builder
+	if err := WriteICU(&b, extract(t)[:2]); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{
+	"Avatar of {Name}": {
+		"defaultMessage": "Avatar of {Name}",
+		"description": "attribute @alt; {Name}: user.Name",
+		"references": [
+			"page.tgo:8"
+		]
+	},
+	"Greeting": {
+		"defaultMessage": "Greeting",
+		"description": "attribute @title",
+		"references": [
+			"page.tgo:6"
+		]
+	}
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
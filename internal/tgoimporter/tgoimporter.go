@@ -33,6 +33,14 @@ var tgoPkg = sync.OnceValues(func() (*types.Package, error) {
 type Ctx struct{}
 type Error = error
 type UnsafeHTML string
+type TagName string
+type SafeURL string
+type SafeAttr string
+type SafeJS string
+type SafeCSS string
+type Renderer interface {
+	Render(Ctx) error
+}
 type DynamicWriteAllowed interface {
 	string|UnsafeHTML|int|uint|rune
 }
@@ -45,7 +53,7 @@ func DynamicWrite[T DynamicWriteAllowed](t T) {
 		return nil, err
 	}
 
-	tgoPkg, err := new(types.Config).Check("github.com/mateusz834/tgoast", fset, []*ast.File{tgoModuleFile}, nil)
+	tgoPkg, err := new(types.Config).Check("github.com/mateusz834/tgo", fset, []*ast.File{tgoModuleFile}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1544,7 +1544,8 @@ const (
 	MisplacedTag
 
 	// JumpOverEndTag occurs when a goto, continue or break would
-	// cause an end tag not to be reached.
+	// cause an end tag not to be reached. Unlike a return, such jumps
+	// do not write the end tags of the elements they leave.
 	//
 	// Example:
 	// import "github.com/mateusz834/tgo"
@@ -1568,20 +1569,26 @@ const (
 	// }
 	JumpOverEndTag
 
-	// MisplacedReturn occurs when a return would cause an end tag
-	// not to be rached.
+	// MisplacedReturn occurs when a return appears inside of an open tag.
+	//
+	// A return inside of an element body is permitted, the end tags
+	// of the enclosing elements are written before returning.
+	//
 	// Example:
 	// import "github.com/mateusz834/tgo"
 	//
 	// func f(tgo.Ctx) error {
-	//		<div>
+	//		<div
 	//			return nil
+	//		>
 	//		</div>
 	// }
 	MisplacedReturn
 
 	// InvalidTemplateLiteralType occurs when a template literal part contains
-	// an unexpected type.
+	// an unexpected type. A value is permitted when its type satisfies
+	// tgo.DynamicWriteAllowed, implements tgo.Renderer (except in attributes)
+	// or implements an interface of Config.Interpolators.
 	///
 	// Example:
 	// import "github.com/mateusz834/tgo"
@@ -1590,4 +1597,96 @@ const (
 	//		"\{1.1}"
 	// }
 	InvalidTemplateLiteralType
+
+	// UnsafeHTMLInAttribute occurs when a value that might be of type
+	// tgo.UnsafeHTML is interpolated in an attribute value. Attribute
+	// values are not HTML, the unescaped value could end the attribute.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(_ tgo.Ctx, html tgo.UnsafeHTML) error {
+	//		<div @title="\{html}"></div>
+	//		return nil
+	// }
+	UnsafeHTMLInAttribute
+
+	// MismatchedSafeAttributeType occurs when a value of type tgo.SafeURL,
+	// tgo.SafeJS, tgo.SafeCSS or tgo.SafeAttr is interpolated in an
+	// attribute of a different kind. SafeURL is permitted in attributes
+	// holding URLs (like href and src), SafeJS in event handlers (on*),
+	// SafeCSS in style and SafeAttr in the remaining attributes.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(_ tgo.Ctx, js tgo.SafeJS) error {
+	//		<a @href="\{js}"></a>
+	//		return nil
+	// }
+	MismatchedSafeAttributeType
+
+	// MarkupInGoStmt occurs when markup (a tag, an attribute or a template
+	// literal) appears inside of a function literal started by a go
+	// statement. Such markup would write to the tgo.Ctx concurrently.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(tgo.Ctx) error {
+	//		go func() {
+	//			<div></div>
+	//		}()
+	//		return nil
+	// }
+	MarkupInGoStmt
+
+	// EscapingMarkup occurs when markup (a tag, an attribute or a template
+	// literal) appears inside of a function literal of a tgo function, that
+	// is not called synchronously, such function literal might be called
+	// after the tgo function returns.
+	//
+	// Function literals that are called immediately (including with a
+	// defer statement) are called synchronously.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(tgo.Ctx) error {
+	//		render := func() {
+	//			<div></div>
+	//		}
+	//		render()
+	//		return nil
+	// }
+	EscapingMarkup
+
+	// InvalidTagName occurs when a dynamic tag name is neither of type
+	// tgo.TagName, nor a constant string that is a valid tag name.
+	// Valid tag names start with an ASCII letter, followed by ASCII
+	// letters, digits and hyphens.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(_ tgo.Ctx, name string) error {
+	//		<\{name}></>
+	//		return nil
+	// }
+	InvalidTagName
+
+	// InvalidTemplateLiteralFormat occurs when the format of a template
+	// literal part does not match the formatted expression, either a
+	// format verb that does not accept the type of the expression, or
+	// a layout for an expression without the Format(string) string
+	// method.
+	//
+	// Example:
+	// import "github.com/mateusz834/tgo"
+	//
+	// func f(tgo.Ctx) error {
+	//		"\{"text":%d}"
+	//		return nil
+	// }
+	InvalidTemplateLiteralFormat
 )
@@ -0,0 +1,36 @@
+package test
+
+import (
+	"github.com/mateusz834/tgo"
+)
+
+func _(_ tgo.Ctx, html tgo.UnsafeHTML, url tgo.SafeURL, attr tgo.SafeAttr, js tgo.SafeJS, css tgo.SafeCSS) error {
+	"\{html}"
+	<a
+		@title="\{html /* ERROR "cannot use html (variable of type tgo.UnsafeHTML) in attribute title (tgo.UnsafeHTML is not permitted in attributes)" */}"
+		@title="\{tgo /* ERROR "tgo.UnsafeHTML is not permitted in attributes" */ .UnsafeHTML("<b>")}"
+		@href="\{url}"
+		@SRC="\{url}"
+		@title="\{attr}"
+		@onclick="\{js}"
+		@style="\{css}"
+		@title="\{url /* ERROR "cannot use url (variable of type tgo.SafeURL) in attribute title (tgo.SafeURL is permitted only in URL attributes)" */}"
+		@href="\{js /* ERROR "tgo.SafeJS is permitted only in event handler attributes" */}"
+		@onclick="\{css /* ERROR "tgo.SafeCSS is permitted only in style attributes" */}"
+		@style="\{attr /* ERROR "tgo.SafeAttr is permitted only in text attributes" */}"
+		@href="\{"/path"} \{1}"
+	>
+	</a>
+	"\{url /* ERROR "tgo.SafeURL does not satisfy tgo.DynamicWriteAllowed" */}"
+	return nil
+}
+
+func _[T string | int](_ tgo.Ctx, t T) error {
+	<a @title="\{t}"></a>
+	return nil
+}
+
+func _[T ~string](_ tgo.Ctx, t T) error {
+	<a @title="\{t /* ERROR "does not satisfy tgo.DynamicWriteAllowed" */}"></a>
+	return nil
+}
@@ -0,0 +1,159 @@
+package test
+
+import "github.com/mateusz834/tgo"
+
+func _(tgo.Ctx) error {
+	func() {
+		<div>"\{"a"}"</div>
+	}()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	<ul>
+		for _, v := range []string{"a", "b"} {
+			func() {
+				<li @class="item">"\{v}"</li>
+			}()
+		}
+	</ul>
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	defer func() {
+		<footer></footer>
+	}()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	<div
+		defer func() {
+			@ /* ERROR "attribute is not allowed outside a tag" */ class="a"
+		}()
+	>
+		defer func() {
+			<p>"\{"a"}"</p>
+		}()
+	</div>
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	(func() {
+		func() {
+			<br>
+		}()
+	})()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	n := func() int {
+		"\{"a"}"
+		return 1
+	}()
+	_ = n
+	return nil
+}
+
+func helper(func(tgo.Ctx) error)
+
+func _(tgo.Ctx) error {
+	helper(func(ctx tgo.Ctx) error {
+		<li></li>
+		return nil
+	})
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	render := func() {
+		< /* ERROR "open tag is not allowed inside a function literal that is not called synchronously" */ div
+			@ /* ERROR "attribute is not allowed inside a function literal that is not called synchronously" */ class="a"
+		>
+			"\{ /* ERROR "template literal is not allowed inside a function literal that is not called synchronously" */ "a"}"
+		</ /* ERROR "end tag is not allowed inside a function literal that is not called synchronously" */ div>
+	}
+	render()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	f := func() {
+		func() {
+			< /* ERROR "open tag is not allowed inside a function literal that is not called synchronously" */ br>
+		}()
+	}
+	f()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	go func() {
+		< /* ERROR "open tag is not allowed inside a go statement" */ div>
+		</ /* ERROR "end tag is not allowed inside a go statement" */ div>
+	}()
+	return nil
+}
+
+func _(ctx tgo.Ctx) error {
+	go func(ctx tgo.Ctx) error {
+		"\{ /* ERROR "template literal is not allowed inside a go statement" */ "a"}"
+		return nil
+	}(ctx /* ERROR "cannot pass ctx (variable of type tgo.Ctx) to a go statement" */)
+	return nil
+}
+
+func Footer(tgo.Ctx) error
+
+func _(ctx tgo.Ctx) error {
+	go Footer(ctx /* ERROR "cannot pass ctx (variable of type tgo.Ctx) to a go statement" */)
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	go func() {
+		func() {
+			< /* ERROR "open tag is not allowed inside a go statement" */ br>
+		}()
+		_ = func() {
+			< /* ERROR "open tag is not allowed inside a go statement" */ br>
+		}
+	}()
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	go helper(func /* ERROR "cannot pass tgo function literal to a go statement" */ (ctx tgo.Ctx) error {
+		<br>
+		return nil
+	})
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	<div
+		func() {
+			@class="a"
+			< /* ERROR "tag is not allowed inside a tag" */ span></ /* ERROR "end tag is not allowed inside a tag" */ span>
+			"\{ /* ERROR "template literal inside of an tag" */ "x"}"
+		}()
+	>
+		func() {
+			<span></span>
+			@ /* ERROR "attribute is not allowed outside a tag" */ class="b"
+		}()
+	</div>
+	return nil
+}
+
+func _() {
+	func() {
+		< /* ERROR "open tag is not allowed inside a non-tgo function" */ br>
+	}()
+	go func() {
+		< /* ERROR "open tag is not allowed inside a non-tgo function" */ br>
+	}()
+}
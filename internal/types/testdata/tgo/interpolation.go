@@ -0,0 +1,44 @@
+package test
+
+import (
+	"github.com/mateusz834/tgo"
+)
+
+type component struct{}
+
+func (component) Render(tgo.Ctx) error { return nil }
+
+type ptrComponent struct{}
+
+func (*ptrComponent) Render(tgo.Ctx) error { return nil }
+
+type userID int
+
+func (userID) String() string { return "" }
+
+func _(tgo.Ctx) error {
+	var c component
+	var p ptrComponent
+	"\{c} \{component{}} \{&p}"
+	"\{p /* ERROR "ptrComponent does not satisfy tgo.DynamicWriteAllowed" */}"
+	var r tgo.Renderer = c
+	"\{r} \{tgo.Renderer(c)}"
+	<div
+		@attr="\{c /* ERROR "c (variable of type component) writes unescaped output and cannot be interpolated in an attribute" */}"
+		@attr="\{r /* ERROR "writes unescaped output" */}"
+	>
+	</div>
+	return nil
+}
+
+func _[T tgo.Renderer](_ tgo.Ctx, t T) error {
+	"\{t}"
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	// Stringers are only permitted with Config.Interpolators.
+	var id userID
+	"\{id /* ERROR "userID does not satisfy tgo.DynamicWriteAllowed" */}"
+	return nil
+}
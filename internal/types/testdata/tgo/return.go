@@ -4,7 +4,7 @@ import "github.com/mateusz834/tgo"
 
 func _(tgo.Ctx) error {
 	<div>
-		return /* ERROR "invalid return in element body" */ nil
+		return nil
 	</div>
 	return nil
 }
@@ -27,10 +27,67 @@ func _(tgo.Ctx) error {
 func _(tgo.Ctx) error {
 	<div>
 		<div
-			return /* ERROR "invalid return in open tag" */ /* ERROR "invalid return in element body" */ nil
+			return /* ERROR "invalid return in open tag" */ nil
 		>
-			return /* ERROR "invalid return in element body" */ nil
+			return nil
 		</div>
 	</div>
 	return nil
 }
+
+func lookup(string) (string, error)
+
+func _(tgo.Ctx) error {
+	<table>
+		for _, key := range []string{"a", "b"} {
+			<tr>
+				v, err := lookup(key)
+				if err != nil {
+					return err
+				}
+				<td>"\{v}"</td>
+			</tr>
+		}
+	</table>
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	<div>
+		return 1 // ERROR "cannot use 1 (constant of type int) as error value in return statement"
+	</div>
+	return nil
+}
+
+func _(tgo.Ctx) (err error) {
+	<div>
+		err = nil
+		return
+	</div>
+}
+
+func _(tgo.Ctx) error {
+	<div>
+		<span>
+			return nil
+		</span>
+	</div>
+}
+
+func _(tgo.Ctx) error {
+	<div>
+		if true {
+			return nil
+		}
+	</div>
+} // ERROR "missing return"
+
+func _(tgo.Ctx) error {
+	<div>
+		if true {
+			return nil
+		} else {
+			return nil
+		}
+	</div>
+}
@@ -0,0 +1,58 @@
+package test
+
+import "github.com/mateusz834/tgo"
+
+var headings = [...]tgo.TagName{"h1", "h2", "h3", "h4", "h5", "h6"}
+
+func _(_ tgo.Ctx, level int) error {
+	<\{headings[level]} @class="heading">
+		"title"
+	</\{headings[level]}>
+	return nil
+}
+
+func _(_ tgo.Ctx, link bool) error {
+	tag := tgo.TagName("button")
+	if link {
+		tag = "a"
+	}
+	<\{tag}>
+		"click"
+	</>
+	return nil
+}
+
+const section = "section"
+
+func _(tgo.Ctx) error {
+	<\{"custom-element"}></\{"custom-element"}>
+	<\{section}></\{section}>
+	<\{"h" + "1"}></>
+	return nil
+}
+
+func _(_ tgo.Ctx, name string, n int) error {
+	<\{name /* ERROR "cannot use name (variable of type string) as tag name (must be tgo.TagName or a constant string)" */ }></>
+	<\{n /* ERROR "cannot use n (variable of type int) as tag name" */ }></>
+	<\{1 /* ERROR "cannot use 1 (untyped int constant) as tag name" */ }></>
+	<\{"" /* ERROR "invalid tag name \"\"" */ }></>
+	<\{"1h" /* ERROR "invalid tag name \"1h\"" */ }></>
+	<\{"a b" /* ERROR "invalid tag name \"a b\"" */ }></>
+	<\{"div>" /* ERROR "invalid tag name \"div>\"" */ }></>
+	<\{undefined /* ERROR "undefined: undefined" */ }></>
+	return nil
+}
+
+func _(tgo.Ctx) error {
+	<div>
+		<\{"span"}>
+			"text"
+		</>
+	</div>
+	return nil
+}
+
+func _() {
+	< /* ERROR "open tag is not allowed inside a non-tgo function" */ \{"div"}>
+	</ /* ERROR "end tag is not allowed inside a non-tgo function" */ >
+}
@@ -38,9 +38,9 @@ func _(tgo.Ctx) error {
 
 func _(tgo.Ctx) error {
 	<div
-		@attr="\{"str"} \{100} \{-100} \{'r'} \{tgo.UnsafeHTML("<div></div>")}"
-		@attr="\{strTyped} \{inteagerTyped} \{uInteagerTyped} \{charTyped} \{unsafeHTMLTyped}"
-		@attr="\{strVar} \{inteagerVar} \{uInteagerVar} \{charVar} \{unsafeHTMLVar}"
+		@attr="\{"str"} \{100} \{-100} \{'r'}"
+		@attr="\{strTyped} \{inteagerTyped} \{uInteagerTyped} \{charTyped}"
+		@attr="\{strVar} \{inteagerVar} \{uInteagerVar} \{charVar}"
 		@attr="\{str} \{inteager} \{uInteager} \{char}"
 	>
 	</div>
@@ -57,7 +57,7 @@ func _[T tgo.DynamicWriteAllowed](_ tgo.Ctx, t T) error {
 	var zero T
 	"\{zero} \{*new(T)} \{t}"
 	<div
-		@attr="\{zero} \{*new(T)} \{t}"
+		@attr="\{zero /* ERROR "tgo.UnsafeHTML is not permitted in attributes" */} \{* /* ERROR "tgo.UnsafeHTML is not permitted in attributes" */ new(T)} \{t /* ERROR "tgo.UnsafeHTML is not permitted in attributes" */}"
 	>
 	</div>
 	return nil
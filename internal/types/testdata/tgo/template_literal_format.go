@@ -0,0 +1,35 @@
+package test
+
+import "github.com/mateusz834/tgo"
+
+type Time struct{}
+
+func (Time) Format(layout string) string
+
+const RFC3339 = "2006-01-02T15:04:05Z07:00"
+
+type stringer struct{}
+
+func (stringer) String() string
+
+type point struct{ x, y int }
+
+// Errors reported at format verbs are tested by TestTgoFormatVerbErrors.
+
+func _[T ~int | ~int64, S any](_ tgo.Ctx, price float64, n int, s string, b []byte, t Time, p *point, pt point, st stringer, err error, tp T, sp S, ok bool) error {
+	"\{price:%.2f} \{price:%8.3e} \{price:%g} \{n:%d} \{n:%05d} \{n:%x} \{n:%-10c}"
+	"\{s:%s} \{s:%q} \{s:%10s} \{b:%s} \{b:%x} \{st:%s} \{err:%v} \{err:%s} \{ok:%t}"
+	"\{p:%p} \{p:%v} \{pt:%d} \{tp:%d} \{sp:%d} \{1.5:%.1f} \{'a':%c} \{n:%T}"
+	"\{t:RFC3339} \{t:"2006"} \{t:%v}"
+	<div @title="\{price:%.2f}"></div>
+
+	"\{price /* ERROR "format %d has arg price of wrong type float64" */ :%d}"
+	"\{s /* ERROR "format %d has arg s of wrong type string" */ :%d}"
+	"\{n /* ERROR "format %s has arg n of wrong type int" */ :%s}"
+	"\{ok /* ERROR "format %d has arg ok of wrong type bool" */ :%d}"
+	"\{pt /* ERROR "format %s has arg pt of wrong type point" */ :%s}"
+	"\{n /* ERROR "cannot format n (variable of type int) with a layout (missing method Format(string) string)" */ :RFC3339}"
+	"\{t:1 /* ERROR "cannot use 1 (untyped int constant) as string value in layout" */ }"
+	"\{undefined /* ERROR "undefined: undefined" */ :%d}"
+	return nil
+}
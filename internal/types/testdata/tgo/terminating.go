@@ -24,7 +24,7 @@ func _(tgo.Ctx) error {
 			continue
 		}
 	</div>
-} // ERROR "missing return"
+}
 
 func _(tgo.Ctx) error {
 	<div>
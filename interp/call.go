@@ -0,0 +1,312 @@
+package interp
+
+import (
+	"reflect"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/types"
+)
+
+func (m *machine) callExpr(e *env, x *ast.CallExpr) []reflect.Value {
+	tv := m.info.Types[x.Fun]
+	switch {
+	case tv.IsType():
+		return []reflect.Value{m.convert(x, m.expr(e, x.Args[0]), m.info.TypeOf(x))}
+	case tv.IsBuiltin():
+		return m.builtin(e, x)
+	}
+	sig, ok := tv.Type.Underlying().(*types.Signature)
+	if !ok {
+		m.unsupported(x, "call")
+	}
+
+	// Determine the callee (and the receiver) before the arguments.
+	var (
+		fn   *types.Func
+		recv reflect.Value
+		fv   reflect.Value
+	)
+	switch fun := ast.Unparen(x.Fun).(type) {
+	case *ast.Ident:
+		fn, _ = m.info.Uses[fun].(*types.Func)
+	case *ast.SelectorExpr:
+		if sel := m.info.Selections[fun]; sel == nil {
+			fn, _ = m.info.Uses[fun.Sel].(*types.Func) // qualified identifier
+		} else if sel.Kind() == types.MethodVal {
+			fn = sel.Obj().(*types.Func)
+			recv = m.expr(e, fun.X)
+			recv = m.field(fun, recv, sel.Index()[:len(sel.Index())-1])
+		}
+	}
+	if fn == nil {
+		fv = m.expr(e, x.Fun)
+		if !fv.IsValid() || fv.IsNil() {
+			m.errorf(x.Pos(), "call of nil function")
+		}
+	}
+
+	args := m.values(e, x.Args)
+	if sig.Variadic() && !x.Ellipsis.IsValid() {
+		n := sig.Params().Len() - 1
+		st := m.reflectType(sig.Params().At(n).Type())
+		rest := reflect.Zero(st)
+		for _, arg := range args[n:] {
+			rest = reflect.Append(rest, m.assignable(arg, st.Elem()))
+		}
+		args = append(args[:n:n], rest)
+	}
+	for i := range args {
+		args[i] = m.assignable(args[i], m.reflectType(sig.Params().At(i).Type()))
+	}
+
+	if fn != nil {
+		return m.callFunc(x, fn, recv, args)
+	}
+	return m.callValue(x, fv, args)
+}
+
+// callFunc calls the function or method fn, recv is the
+// receiver of a method.
+func (m *machine) callFunc(x *ast.CallExpr, fn *types.Func, recv reflect.Value, args []reflect.Value) []reflect.Value {
+	if fn.Origin() != fn {
+		m.unsupported(x, "generic function")
+	}
+	if decl := m.funcs[fn]; decl != nil {
+		if recv.IsValid() {
+			recv = m.receiver(x, recv, decl)
+		}
+		return m.callDecl(decl, recv, args)
+	}
+	if recv.IsValid() {
+		return m.callValue(x, m.hostMethod(x, recv, fn.Name()), args)
+	}
+	return m.callValue(x, m.hostFunc(x, fn), args)
+}
+
+// callMethod calls the method name of x, of the type typ.
+func (m *machine) callMethod(n ast.Node, x reflect.Value, typ types.Type, name string, args []reflect.Value) []reflect.Value {
+	obj, index, _ := types.LookupFieldOrMethod(typ, x.CanAddr(), m.pkg, name)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		m.errorf(n.Pos(), "missing method %s of %s", name, typ)
+	}
+	x = m.field(n, x, index[:len(index)-1])
+	if decl := m.funcs[fn]; decl != nil {
+		return m.callDecl(decl, m.receiver(n, x, decl), args)
+	}
+	return m.callValue(n, m.hostMethod(n, x, name), args)
+}
+
+// receiver adjusts recv to the receiver of the method decl.
+func (m *machine) receiver(n ast.Node, recv reflect.Value, decl *ast.FuncDecl) reflect.Value {
+	_, ptr := m.info.TypeOf(decl.Recv.List[0].Type).(*types.Pointer)
+	switch {
+	case ptr && recv.Kind() != reflect.Pointer:
+		if !recv.CanAddr() {
+			m.errorf(n.Pos(), "cannot take the address of the receiver of %s", decl.Name.Name)
+		}
+		return recv.Addr()
+	case !ptr && recv.Kind() == reflect.Pointer:
+		return m.deref(n, recv)
+	}
+	return recv
+}
+
+// hostMethod returns the method name of the host value recv.
+func (m *machine) hostMethod(n ast.Node, recv reflect.Value, name string) reflect.Value {
+	v := recv
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			m.errorf(n.Pos(), "invalid memory address or nil pointer dereference")
+		}
+		v = v.Elem()
+	}
+	if mv := v.MethodByName(name); mv.IsValid() {
+		return mv
+	}
+	if v.CanAddr() {
+		if mv := v.Addr().MethodByName(name); mv.IsValid() {
+			return mv
+		}
+	}
+	m.errorf(n.Pos(), "cannot call method %s of %s (methods of interpreted types are not available through interfaces)", name, v.Type())
+	return reflect.Value{}
+}
+
+// hostFunc returns the host function fn (see Config.Funcs).
+func (m *machine) hostFunc(n ast.Node, fn *types.Func) reflect.Value {
+	name := fn.Pkg().Path() + "." + fn.Name()
+	f, ok := m.conf.Funcs[name]
+	if !ok {
+		m.errorf(n.Pos(), "missing host function %s", name)
+	}
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func {
+		m.errorf(n.Pos(), "host function %s is a %T, not a function", name, f)
+	}
+	return v
+}
+
+// callValue calls the function value fv.
+func (m *machine) callValue(n ast.Node, fv reflect.Value, args []reflect.Value) []reflect.Value {
+	t := fv.Type()
+	if t.NumIn() != len(args) {
+		m.errorf(n.Pos(), "cannot call %s with %d arguments", t, len(args))
+	}
+	for i := range args {
+		args[i] = m.assignable(args[i], t.In(i))
+		if !args[i].Type().AssignableTo(t.In(i)) {
+			m.errorf(n.Pos(), "cannot use %s as %s in argument %d of %s", args[i].Type(), t.In(i), i+1, t)
+		}
+	}
+	if t.IsVariadic() {
+		return fv.CallSlice(args)
+	}
+	return fv.Call(args)
+}
+
+// funcValue returns the value of the function fn.
+func (m *machine) funcValue(n ast.Node, fn *types.Func) reflect.Value {
+	decl := m.funcs[fn]
+	if decl == nil {
+		return m.hostFunc(n, fn)
+	}
+	return reflect.MakeFunc(m.reflectType(fn.Type()), func(args []reflect.Value) []reflect.Value {
+		return m.callDecl(decl, reflect.Value{}, args)
+	})
+}
+
+// methodValue returns the method value of the selection sel,
+// with the receiver recv.
+func (m *machine) methodValue(x *ast.SelectorExpr, recv reflect.Value, sel *types.Selection) reflect.Value {
+	fn := sel.Obj().(*types.Func)
+	recv = m.field(x, recv, sel.Index()[:len(sel.Index())-1])
+	decl := m.funcs[fn]
+	if decl == nil {
+		return m.hostMethod(x, recv, fn.Name())
+	}
+	recv = m.receiver(x, recv, decl)
+	if recv.Kind() != reflect.Pointer {
+		// the receiver is evaluated and copied
+		c := reflect.New(recv.Type()).Elem()
+		c.Set(recv)
+		recv = c
+	}
+	return reflect.MakeFunc(m.reflectType(sel.Type()), func(args []reflect.Value) []reflect.Value {
+		return m.callDecl(decl, recv, args)
+	})
+}
+
+func (m *machine) funcLit(e *env, x *ast.FuncLit) reflect.Value {
+	return reflect.MakeFunc(m.reflectType(m.info.TypeOf(x)), func(args []reflect.Value) []reflect.Value {
+		return m.callBody(newEnv(e), x.Type, x.Body, args)
+	})
+}
+
+// convert converts v to the type t.
+func (m *machine) convert(n ast.Node, v reflect.Value, t types.Type) reflect.Value {
+	rt := m.reflectType(t)
+	if !v.IsValid() || rt.Kind() == reflect.Interface {
+		return m.assignable(v, rt)
+	}
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.Type().ConvertibleTo(rt) {
+		m.errorf(n.Pos(), "cannot convert %s to %s", v.Type(), rt)
+	}
+	return v.Convert(rt)
+}
+
+func (m *machine) builtin(e *env, x *ast.CallExpr) []reflect.Value {
+	id, _ := ast.Unparen(x.Fun).(*ast.Ident)
+	if id == nil {
+		m.unsupported(x, "call")
+	}
+	arg := func(i int) reflect.Value { return m.expr(e, x.Args[i]) }
+	switch id.Name {
+	case "len", "cap":
+		v := arg(0)
+		if v.Kind() == reflect.Pointer {
+			v = m.deref(x, v)
+		}
+		if !v.IsValid() {
+			return []reflect.Value{reflect.ValueOf(0)}
+		}
+		if id.Name == "len" {
+			return []reflect.Value{reflect.ValueOf(v.Len())}
+		}
+		return []reflect.Value{reflect.ValueOf(v.Cap())}
+
+	case "append":
+		st := m.reflectType(m.info.TypeOf(x))
+		s := m.assignable(arg(0), st)
+		if x.Ellipsis.IsValid() {
+			rest := arg(1)
+			if rest.Kind() == reflect.String {
+				rest = reflect.ValueOf([]byte(rest.String()))
+			}
+			return []reflect.Value{reflect.AppendSlice(s, m.assignable(rest, st))}
+		}
+		for i := 1; i < len(x.Args); i++ {
+			s = reflect.Append(s, m.assignable(arg(i), st.Elem()))
+		}
+		return []reflect.Value{s}
+
+	case "make":
+		t := m.reflectType(m.info.TypeOf(x.Args[0]))
+		size := func(i int) int {
+			if i >= len(x.Args) {
+				return 0
+			}
+			return m.index(x.Args[i], arg(i), int(^uint(0)>>1))
+		}
+		switch t.Kind() {
+		case reflect.Slice:
+			n := size(1)
+			c := n
+			if len(x.Args) > 2 {
+				c = size(2)
+			}
+			return []reflect.Value{reflect.MakeSlice(t, n, c)}
+		case reflect.Map:
+			return []reflect.Value{reflect.MakeMapWithSize(t, size(1))}
+		}
+		m.unsupported(x, "make of "+t.String())
+
+	case "new":
+		return []reflect.Value{reflect.New(m.reflectType(m.info.TypeOf(x.Args[0])))}
+
+	case "delete":
+		mp := arg(0)
+		if !mp.IsNil() {
+			mp.SetMapIndex(m.assignable(arg(1), mp.Type().Key()), reflect.Value{})
+		}
+		return nil
+
+	case "copy":
+		n := reflect.Copy(arg(0), arg(1))
+		return []reflect.Value{reflect.ValueOf(n)}
+
+	case "clear":
+		v := arg(0)
+		v.Clear()
+		return nil
+
+	case "min", "max":
+		t := m.reflectType(m.info.TypeOf(x))
+		r := m.assignable(arg(0), t)
+		for i := 1; i < len(x.Args); i++ {
+			v := m.assignable(arg(i), t)
+			if less(v, r) == (id.Name == "min") {
+				r = v
+			}
+		}
+		return []reflect.Value{r}
+
+	case "panic":
+		m.errorf(x.Pos(), "panic: %v", valueInterface(arg(0)))
+	}
+	m.unsupported(x, "builtin "+id.Name)
+	return nil
+}
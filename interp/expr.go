@@ -0,0 +1,398 @@
+package interp
+
+import (
+	"reflect"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// expr evaluates the single-valued expression x. The untyped nil
+// is represented by the invalid value.
+func (m *machine) expr(e *env, x ast.Expr) reflect.Value {
+	vals := m.exprs(e, x)
+	if len(vals) != 1 {
+		m.errorf(x.Pos(), "%s is not a single value", types.ExprString(x))
+	}
+	return vals[0]
+}
+
+// values evaluates the expressions of list, a single call
+// with multiple results is expanded.
+func (m *machine) values(e *env, list []ast.Expr) []reflect.Value {
+	if len(list) == 1 {
+		return m.exprs(e, list[0])
+	}
+	vals := make([]reflect.Value, len(list))
+	for i, x := range list {
+		vals[i] = m.expr(e, x)
+	}
+	return vals
+}
+
+// valuesN evaluates the n values of list, a single map index,
+// type assertion or call expression is permitted to produce
+// multiple values.
+func (m *machine) valuesN(e *env, list []ast.Expr, n int) []reflect.Value {
+	if len(list) != 1 || n == 1 {
+		return m.values(e, list)
+	}
+	switch x := ast.Unparen(list[0]).(type) {
+	case *ast.IndexExpr:
+		mp := m.expr(e, x.X)
+		k := m.assignable(m.expr(e, x.Index), mp.Type().Key())
+		v := mp.MapIndex(k)
+		if !v.IsValid() {
+			return []reflect.Value{reflect.Zero(mp.Type().Elem()), reflect.ValueOf(false)}
+		}
+		return []reflect.Value{v, reflect.ValueOf(true)}
+	case *ast.TypeAssertExpr:
+		v, ok := m.typeAssert(e, x)
+		return []reflect.Value{v, reflect.ValueOf(ok)}
+	}
+	return m.values(e, list)
+}
+
+// exprs evaluates the expression x, with any number of values.
+func (m *machine) exprs(e *env, x ast.Expr) []reflect.Value {
+	tv := m.info.Types[x]
+	if tv.Value != nil {
+		return []reflect.Value{m.constValue(x, tv.Value, tv.Type)}
+	}
+
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return m.exprs(e, x.X)
+
+	case *ast.Ident:
+		switch obj := m.info.Uses[x].(type) {
+		case *types.Var:
+			return []reflect.Value{m.variable(x, e, obj)}
+		case *types.Nil:
+			return []reflect.Value{{}}
+		case *types.Func:
+			return []reflect.Value{m.funcValue(x, obj)}
+		}
+
+	case *ast.FuncLit:
+		return []reflect.Value{m.funcLit(e, x)}
+
+	case *ast.CompositeLit:
+		return []reflect.Value{m.compositeLit(e, x)}
+
+	case *ast.SelectorExpr:
+		if sel := m.info.Selections[x]; sel != nil {
+			switch sel.Kind() {
+			case types.FieldVal:
+				v := m.expr(e, x.X)
+				return []reflect.Value{m.field(x, v, sel.Index())}
+			case types.MethodVal:
+				recv := m.expr(e, x.X)
+				return []reflect.Value{m.methodValue(x, recv, sel)}
+			}
+			m.unsupported(x, "method expression")
+		}
+		// qualified identifier
+		if fn, ok := m.info.Uses[x.Sel].(*types.Func); ok {
+			return []reflect.Value{m.funcValue(x, fn)}
+		}
+		m.errorf(x.Pos(), "variable %s of another package is not supported", types.ExprString(x))
+
+	case *ast.IndexExpr:
+		if id, ok := ast.Unparen(x.X).(*ast.Ident); ok {
+			if _, ok := m.info.Instances[id]; ok {
+				m.unsupported(x, "generic function")
+			}
+		}
+		v := m.expr(e, x.X)
+		if v.Kind() == reflect.Pointer {
+			v = m.deref(x, v)
+		}
+		if v.Kind() == reflect.Map {
+			r := v.MapIndex(m.assignable(m.expr(e, x.Index), v.Type().Key()))
+			if !r.IsValid() {
+				r = reflect.Zero(v.Type().Elem())
+			}
+			return []reflect.Value{r}
+		}
+		i := m.index(x.Index, m.expr(e, x.Index), v.Len())
+		return []reflect.Value{v.Index(i)}
+
+	case *ast.SliceExpr:
+		v := m.expr(e, x.X)
+		if v.Kind() == reflect.Pointer {
+			v = m.deref(x, v)
+		}
+		lo, hi, cap := 0, v.Len(), v.Len()
+		if v.Kind() != reflect.String {
+			cap = v.Cap()
+		}
+		if x.Low != nil {
+			lo = m.index(x.Low, m.expr(e, x.Low), cap+1)
+		}
+		if x.High != nil {
+			hi = m.index(x.High, m.expr(e, x.High), cap+1)
+		}
+		if x.Max != nil {
+			cap = m.index(x.Max, m.expr(e, x.Max), cap+1)
+			return []reflect.Value{v.Slice3(lo, hi, cap)}
+		}
+		return []reflect.Value{v.Slice(lo, hi)}
+
+	case *ast.StarExpr:
+		return []reflect.Value{m.deref(x, m.expr(e, x.X))}
+
+	case *ast.UnaryExpr:
+		if x.Op == token.AND {
+			if lit, ok := ast.Unparen(x.X).(*ast.CompositeLit); ok {
+				v := m.compositeLit(e, lit)
+				p := reflect.New(v.Type())
+				p.Elem().Set(v)
+				return []reflect.Value{p}
+			}
+			return []reflect.Value{m.lvalue(e, x.X).addr(x)}
+		}
+		if x.Op == token.ARROW {
+			m.unsupported(x, "receive operation")
+		}
+		return []reflect.Value{m.unary(x, m.expr(e, x.X))}
+
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.LAND:
+			return []reflect.Value{reflect.ValueOf(m.expr(e, x.X).Bool() && m.expr(e, x.Y).Bool())}
+		case token.LOR:
+			return []reflect.Value{reflect.ValueOf(m.expr(e, x.X).Bool() || m.expr(e, x.Y).Bool())}
+		}
+		return []reflect.Value{m.binary(x, x.Op, m.expr(e, x.X), m.expr(e, x.Y))}
+
+	case *ast.TypeAssertExpr:
+		v, ok := m.typeAssert(e, x)
+		if !ok {
+			m.errorf(x.Pos(), "interface conversion: %s is not %s", types.ExprString(x.X), m.info.TypeOf(x))
+		}
+		return []reflect.Value{v}
+
+	case *ast.CallExpr:
+		return m.callExpr(e, x)
+
+	case *ast.TemplateLiteralExpr:
+		m.unsupported(x, "template literal in an expression")
+	}
+	m.unsupported(x, "expression")
+	return nil
+}
+
+// variable returns the (settable) value of the variable v.
+func (m *machine) variable(n ast.Node, e *env, v *types.Var) reflect.Value {
+	if val, ok := e.lookup(v); ok {
+		return val
+	}
+	if val, ok := m.globals[v]; ok {
+		return val
+	}
+	m.errorf(n.Pos(), "undefined variable %s", v.Name())
+	return reflect.Value{}
+}
+
+func (m *machine) deref(n ast.Node, v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		m.errorf(n.Pos(), "invalid memory address or nil pointer dereference")
+	}
+	return v.Elem()
+}
+
+// index returns the index v, which must be in the range [0, n).
+func (m *machine) index(x ast.Expr, v reflect.Value, n int) int {
+	var i int
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i = int(v.Int())
+	default:
+		i = int(v.Uint())
+	}
+	if i < 0 || i >= n {
+		m.errorf(x.Pos(), "index out of range [%d] with length %d", i, n)
+	}
+	return i
+}
+
+// field returns the field of v with the index path of a selection.
+func (m *machine) field(n ast.Node, v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Pointer {
+			v = m.deref(n, v)
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+func (m *machine) typeAssert(e *env, x *ast.TypeAssertExpr) (reflect.Value, bool) {
+	v := m.expr(e, x.X)
+	t := m.info.TypeOf(x.Type)
+	if iface, ok := t.Underlying().(*types.Interface); ok && !iface.Empty() {
+		m.unsupported(x, "type assertion to a non-empty interface")
+	}
+	rt := m.reflectType(t)
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return reflect.Zero(rt), false
+	}
+	if rt.Kind() == reflect.Interface {
+		return m.assignable(v, rt), true
+	}
+	if v.Type() != rt {
+		return reflect.Zero(rt), false
+	}
+	return v, true
+}
+
+// An lvalue is an assignable location.
+type lvalue struct {
+	m   *machine
+	v   reflect.Value // settable value; or the map
+	key reflect.Value // key of the map element; or invalid
+}
+
+func (m *machine) lvalue(e *env, x ast.Expr) lvalue {
+	switch x := ast.Unparen(x).(type) {
+	case *ast.Ident:
+		v, ok := m.info.Uses[x].(*types.Var)
+		if !ok {
+			v, _ = m.info.Defs[x].(*types.Var)
+		}
+		return lvalue{m: m, v: m.variable(x, e, v)}
+	case *ast.IndexExpr:
+		v := m.expr(e, x.X)
+		if v.Kind() == reflect.Pointer {
+			v = m.deref(x, v)
+		}
+		if v.Kind() == reflect.Map {
+			if v.IsNil() {
+				m.errorf(x.Pos(), "assignment to entry in nil map")
+			}
+			return lvalue{m: m, v: v, key: m.assignable(m.expr(e, x.Index), v.Type().Key())}
+		}
+		i := m.index(x.Index, m.expr(e, x.Index), v.Len())
+		return lvalue{m: m, v: v.Index(i)}
+	case *ast.SelectorExpr:
+		if sel := m.info.Selections[x]; sel != nil && sel.Kind() == types.FieldVal {
+			return lvalue{m: m, v: m.field(x, m.expr(e, x.X), sel.Index())}
+		}
+	case *ast.StarExpr:
+		return lvalue{m: m, v: m.deref(x, m.expr(e, x.X))}
+	}
+	m.unsupported(x, "assignment")
+	return lvalue{}
+}
+
+func (lv lvalue) get() reflect.Value {
+	if lv.key.IsValid() {
+		if v := lv.v.MapIndex(lv.key); v.IsValid() {
+			return v
+		}
+		return reflect.Zero(lv.v.Type().Elem())
+	}
+	return lv.v
+}
+
+func (lv lvalue) set(x reflect.Value) {
+	if lv.key.IsValid() {
+		lv.v.SetMapIndex(lv.key, lv.m.assignable(x, lv.v.Type().Elem()))
+		return
+	}
+	if !lv.v.CanSet() {
+		lv.m.errorf(lv.m.pos, "cannot assign to unaddressable value")
+	}
+	lv.v.Set(lv.m.assignable(x, lv.v.Type()))
+}
+
+func (lv lvalue) addr(n ast.Node) reflect.Value {
+	if lv.key.IsValid() || !lv.v.CanAddr() {
+		lv.m.errorf(n.Pos(), "cannot take the address of %s", types.ExprString(n.(ast.Expr)))
+	}
+	return lv.v.Addr()
+}
+
+func (m *machine) compositeLit(e *env, x *ast.CompositeLit) reflect.Value {
+	typ := m.info.TypeOf(x)
+	rt := m.reflectType(typ)
+	under := typ.Underlying()
+	if p, ok := under.(*types.Pointer); ok {
+		// &T{} elided in an outer composite literal
+		under = p.Elem().Underlying()
+		v := m.compositeValue(e, x, rt.Elem(), under)
+		p := reflect.New(rt.Elem())
+		p.Elem().Set(v)
+		return p
+	}
+	return m.compositeValue(e, x, rt, under)
+}
+
+func (m *machine) compositeValue(e *env, x *ast.CompositeLit, rt reflect.Type, under types.Type) reflect.Value {
+	switch under := under.(type) {
+	case *types.Struct:
+		v := reflect.New(rt).Elem()
+		for i, elt := range x.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				name := kv.Key.(*ast.Ident).Name
+				for j := range under.NumFields() {
+					if under.Field(j).Name() == name {
+						i = j
+						break
+					}
+				}
+				elt = kv.Value
+			}
+			f := v.Field(i)
+			f.Set(m.assignable(m.expr(e, elt), f.Type()))
+		}
+		return v
+	case *types.Map:
+		v := reflect.MakeMapWithSize(rt, len(x.Elts))
+		for _, elt := range x.Elts {
+			kv := elt.(*ast.KeyValueExpr)
+			k := m.assignable(m.expr(e, kv.Key), rt.Key())
+			v.SetMapIndex(k, m.assignable(m.expr(e, kv.Value), rt.Elem()))
+		}
+		return v
+	case *types.Slice, *types.Array:
+		// determine the length first
+		n, length := 0, 0
+		for _, elt := range x.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				n = m.constIndex(kv.Key)
+			}
+			n++
+			length = max(length, n)
+		}
+		var v reflect.Value
+		if rt.Kind() == reflect.Array {
+			v = reflect.New(rt).Elem()
+		} else {
+			v = reflect.MakeSlice(rt, length, length)
+		}
+		i := 0
+		for _, elt := range x.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				i = m.constIndex(kv.Key)
+				elt = kv.Value
+			}
+			v.Index(i).Set(m.assignable(m.expr(e, elt), rt.Elem()))
+			i++
+		}
+		return v
+	}
+	m.unsupported(x, "composite literal")
+	return reflect.Value{}
+}
+
+// constIndex returns the value of the constant index x.
+func (m *machine) constIndex(x ast.Expr) int {
+	v := m.expr(nil, x)
+	return m.index(x, v, int(^uint(0)>>1))
+}
@@ -0,0 +1,293 @@
+// Package interp implements a tree-walking interpreter of type-checked
+// tgo packages, it renders tgo components without compiling them.
+//
+// The interpreter executes the function bodies of a single package, the
+// calls of functions of other packages are dispatched to host functions
+// (see [Config.Funcs]). Values are represented with the reflect package.
+// The values created by the interpreter of named types are represented
+// by their underlying types, so the methods of the types declared by the
+// interpreted package can be called statically, but not through
+// interfaces, and the values of the types of other packages only have
+// methods when they are created by host functions.
+//
+// The output is written as by the compiled functions: text and attribute
+// values are HTML-escaped, template literal parts are written as described
+// by [types.Info.Interpolations]. Unsupported constructs (e.g. goroutines,
+// channels, defer statements and generic functions) and run-time panics
+// are reported as an [*Error], with the position of the executed statement.
+//
+// This module has no compiler of tgo functions, the expected outputs (the
+// .html and .err files) of the test corpus in testdata are compared with
+// the output of the interpreter and with the static output of the write
+// plans (see package writeplan) that a code generator would follow.
+package interp
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// tgoPath is the import path of the tgo package.
+const tgoPath = "github.com/mateusz834/tgo"
+
+// A Config configures an [Interpreter].
+type Config struct {
+	// Funcs maps the qualified names of the functions of other packages
+	// (e.g. "strings.ToUpper" or "example.com/pkg.Func") to their
+	// implementations, which must be Go functions of the same type.
+	Funcs map[string]any
+}
+
+// An Error describes an unsupported construct or a run-time panic.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// An Interpreter executes the functions of a type-checked package.
+type Interpreter struct {
+	fset  *token.FileSet
+	pkg   *types.Package
+	info  *types.Info
+	conf  Config
+	funcs map[*types.Func]*ast.FuncDecl
+
+	globals  map[*types.Var]reflect.Value
+	types    map[types.Type]reflect.Type
+	argTypes map[types.Type]reflect.Type // see ArgTypes
+}
+
+// New returns an interpreter of the package pkg, with the given files.
+// The info must hold the Types, Defs, Uses, Selections, InitOrder and
+// Interpolations recorded by the type checker. The package-level
+// variables are initialized by New.
+func New(fset *token.FileSet, pkg *types.Package, info *types.Info, files []*ast.File, conf *Config) (*Interpreter, error) {
+	in := &Interpreter{
+		fset:     fset,
+		pkg:      pkg,
+		info:     info,
+		funcs:    make(map[*types.Func]*ast.FuncDecl),
+		globals:  make(map[*types.Var]reflect.Value),
+		types:    make(map[types.Type]reflect.Type),
+		argTypes: make(map[types.Type]reflect.Type),
+	}
+	if conf != nil {
+		in.conf = *conf
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok {
+				if fn, ok := info.Defs[decl.Name].(*types.Func); ok {
+					in.funcs[fn] = decl
+				}
+			}
+		}
+	}
+
+	m := &machine{Interpreter: in, w: io.Discard}
+	err := m.run(func() {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			if v, ok := scope.Lookup(name).(*types.Var); ok {
+				in.globals[v] = reflect.New(m.reflectType(v.Type())).Elem()
+			}
+		}
+		for _, init := range info.InitOrder {
+			m.pos = init.Rhs.Pos()
+			m.assignDef(nil, nil, init.Lhs, []ast.Expr{init.Rhs})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// Render calls the tgo function name of the package with the arguments
+// args (following the tgo.Ctx), the output is written to w. It returns
+// the error returned by the function, the first error of w, or an
+// [*Error].
+func (in *Interpreter) Render(w io.Writer, name string, args ...any) error {
+	decl, sig, err := in.tgoFunc(name)
+	if err != nil {
+		return err
+	}
+	if sig.Params().Len() != len(args)+1 || sig.Variadic() {
+		return fmt.Errorf("interp: %s takes %d arguments, got %d", name, sig.Params().Len()-1, len(args))
+	}
+
+	m := &machine{Interpreter: in, w: w}
+	var res []reflect.Value
+	err = m.run(func() {
+		vals := []reflect.Value{reflect.Zero(ctxType)}
+		for i, arg := range args {
+			t := m.reflectType(sig.Params().At(i + 1).Type())
+			v := reflect.ValueOf(arg)
+			if v.IsValid() && !v.Type().AssignableTo(t) && !v.Type().ConvertibleTo(t) {
+				m.errorf(decl.Pos(), "cannot use argument %d of type %s as %s", i+1, v.Type(), t)
+			}
+			vals = append(vals, m.assignable(v, t))
+		}
+		res = m.callDecl(decl, reflect.Value{}, vals)
+	})
+	switch {
+	case err != nil:
+		return err
+	case m.err != nil:
+		return m.err
+	}
+	if err, _ := res[0].Interface().(error); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ArgTypes returns the representations of the types of the arguments
+// of the tgo function name (following the tgo.Ctx), the values of these
+// types can be passed to [Interpreter.Render]. The fields of the struct
+// types, including the unexported ones, are tagged with their names for
+// encoding/json (unless they have a json tag), e.g. the arguments can be
+// decoded from JSON.
+func (in *Interpreter) ArgTypes(name string) ([]reflect.Type, error) {
+	decl, sig, err := in.tgoFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	m := &machine{Interpreter: in, w: io.Discard, pos: decl.Pos(), args: true}
+	var list []reflect.Type
+	err = m.run(func() {
+		for i := 1; i < sig.Params().Len(); i++ {
+			list = append(list, m.reflectType(sig.Params().At(i).Type()))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// tgoFunc returns the declaration and the signature
+// of the tgo function name of the package.
+func (in *Interpreter) tgoFunc(name string) (*ast.FuncDecl, *types.Signature, error) {
+	fn, _ := in.pkg.Scope().Lookup(name).(*types.Func)
+	decl := in.funcs[fn]
+	if fn == nil || decl == nil {
+		return nil, nil, fmt.Errorf("interp: function %s not found", name)
+	}
+	sig := fn.Type().(*types.Signature)
+	if !isTgoSignature(sig) {
+		return nil, nil, fmt.Errorf("interp: %s is not a tgo function", name)
+	}
+	return decl, sig, nil
+}
+
+// isTgoSignature reports whether sig is a signature of a tgo function,
+// i.e. func(tgo.Ctx, ...) error.
+func isTgoSignature(sig *types.Signature) bool {
+	return sig.Params().Len() > 0 && sig.Results().Len() == 1 &&
+		isTgoType(sig.Params().At(0).Type(), "Ctx") &&
+		types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// isTgoType reports whether t is the type name of the tgo package.
+func isTgoType(t types.Type, name string) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == tgoPath && n.Obj().Name() == name
+}
+
+// A machine holds the state of an execution.
+type machine struct {
+	*Interpreter
+	w    io.Writer
+	err  error     // first error of w
+	pos  token.Pos // position of the statement being executed
+	args bool      // reflectType returns the representations of ArgTypes
+}
+
+// run calls f, the panics of f are returned as an *Error.
+func (m *machine) run(f func()) (err error) {
+	defer func() {
+		switch p := recover().(type) {
+		case nil:
+		case *Error:
+			err = p
+		default:
+			err = &Error{Pos: m.fset.Position(m.pos), Msg: fmt.Sprintf("panic: %v", p)}
+		}
+	}()
+	f()
+	return nil
+}
+
+func (m *machine) errorf(pos token.Pos, format string, args ...any) {
+	panic(&Error{Pos: m.fset.Position(pos), Msg: fmt.Sprintf(format, args...)})
+}
+
+func (m *machine) unsupported(n ast.Node, what string) {
+	m.errorf(n.Pos(), "%s is not supported", what)
+}
+
+func (m *machine) write(s string) {
+	if m.err == nil {
+		_, m.err = io.WriteString(m.w, s)
+	}
+}
+
+// An env holds the variables of a scope.
+type env struct {
+	vars   map[*types.Var]reflect.Value
+	parent *env
+}
+
+func newEnv(parent *env) *env {
+	return &env{vars: make(map[*types.Var]reflect.Value), parent: parent}
+}
+
+func (e *env) lookup(v *types.Var) (reflect.Value, bool) {
+	for ; e != nil; e = e.parent {
+		if val, ok := e.vars[v]; ok {
+			return val, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// declare declares the variable v of type t, set to its zero value.
+func (e *env) declare(v *types.Var, t reflect.Type) reflect.Value {
+	val := reflect.New(t).Elem()
+	e.vars[v] = val
+	return val
+}
+
+// A frame holds the state of a function call.
+type frame struct {
+	results []reflect.Value // named results; or nil
+	ret     []reflect.Value // values of the executed return statement
+	label   string          // label of the executed branch statement; or ""
+}
+
+// A ctrl describes how the execution of a statement completed.
+type ctrl int
+
+const (
+	ctrlNone ctrl = iota
+	ctrlBreak
+	ctrlContinue
+	ctrlReturn
+	ctrlFallthrough
+)
+
+// ctx is the representation of tgo.Ctx, the output
+// is written to the writer of the machine.
+type ctx struct{}
+
+var ctxType = reflect.TypeFor[ctx]()
@@ -0,0 +1,265 @@
+package interp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/importer"
+	"github.com/mateusz834/tgoast/internal/tgoimporter"
+	"github.com/mateusz834/tgoast/internal/txtar"
+	"github.com/mateusz834/tgoast/interp"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+	"github.com/mateusz834/tgoast/writeplan"
+)
+
+var hostFuncs = map[string]any{
+	"errors.New":      errors.New,
+	"strconv.Itoa":    strconv.Itoa,
+	"strings.Join":    strings.Join,
+	"strings.ToUpper": strings.ToUpper,
+}
+
+// TestCorpus renders the functions of the packages of the archives in
+// testdata. For each file <Func>.html of an archive, the function Func
+// is rendered and its output is compared with the file, the error must
+// match the file <Func>.err, if present.
+func TestCorpus(t *testing.T) {
+	forEachArchive(t, func(t *testing.T, fset *token.FileSet, files []*ast.File, want map[string]map[string]string) {
+		in := newInterpreter(t, fset, files)
+		for name, want := range want {
+			var b strings.Builder
+			err := in.Render(&b, name)
+			if got := b.String(); got != want[".html"] {
+				t.Errorf("%s: output:\n%s\nwant:\n%s", name, got, want[".html"])
+			}
+			var gotErr string
+			if err != nil {
+				gotErr = err.Error()
+			}
+			if gotErr != want[".err"] {
+				t.Errorf("%s: error = %q; want %q", name, gotErr, want[".err"])
+			}
+		}
+	})
+}
+
+// TestCorpusPlans compares the write plans of the functions of the
+// archives in testdata, that write only static output, with the
+// reference outputs of TestCorpus. The static output of a plan includes
+// the constant template literal parts folded by the type checker.
+func TestCorpusPlans(t *testing.T) {
+	compared := 0
+	forEachArchive(t, func(t *testing.T, fset *token.FileSet, files []*ast.File, want map[string]map[string]string) {
+		_, info := check(t, fset, files)
+		for _, p := range writeplan.Compute(info, files) {
+			decl, ok := p.Func.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			want, ok := want[decl.Name.Name]
+			if !ok || want[".err"] != "" {
+				continue
+			}
+			got, ok := staticOutput(p)
+			if !ok {
+				continue
+			}
+			compared++
+			if got != want[".html"] {
+				t.Errorf("%s: plan output:\n%s\nwant:\n%s", decl.Name.Name, got, want[".html"])
+			}
+		}
+	})
+	if compared == 0 {
+		t.Error("no static plans compared")
+	}
+}
+
+// staticOutput returns the output of the plan p, if it consists
+// of static chunks followed by a return statement.
+func staticOutput(p *writeplan.Plan) (string, bool) {
+	var b strings.Builder
+	for i, c := range p.Chunks {
+		switch c.Kind {
+		case writeplan.Static:
+			b.WriteString(c.Static)
+		case writeplan.Branch:
+			if _, ok := c.Node.(*ast.ReturnStmt); !ok || i != len(p.Chunks)-1 {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// forEachArchive calls f in a subtest for each archive in testdata, with
+// the parsed .go files of the archive and the contents of its .html and
+// .err files, by the function name and the extension.
+func forEachArchive(t *testing.T, f func(t *testing.T, fset *token.FileSet, files []*ast.File, want map[string]map[string]string)) {
+	archives, err := filepath.Glob(filepath.Join("testdata", "*.txtar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, archive := range archives {
+		t.Run(strings.TrimSuffix(filepath.Base(archive), ".txtar"), func(t *testing.T) {
+			ar, err := txtar.ParseFile(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fset := token.NewFileSet()
+			var files []*ast.File
+			want := make(map[string]map[string]string)
+			for _, f := range ar.Files {
+				ext := filepath.Ext(f.Name)
+				switch ext {
+				case ".go":
+					file, err := parser.ParseFile(fset, f.Name, f.Data, parser.ParseComments)
+					if err != nil {
+						t.Fatal(err)
+					}
+					files = append(files, file)
+				case ".html", ".err":
+					name := strings.TrimSuffix(f.Name, ext)
+					if want[name] == nil {
+						want[name] = make(map[string]string)
+					}
+					want[name][ext] = strings.TrimSuffix(string(f.Data), "\n")
+				}
+			}
+			f(t, fset, files, want)
+		})
+	}
+}
+
+func newInterpreter(t *testing.T, fset *token.FileSet, files []*ast.File) *interp.Interpreter {
+	pkg, info := check(t, fset, files)
+	in, err := interp.New(fset, pkg, info, files, &interp.Config{Funcs: hostFuncs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func check(t *testing.T, fset *token.FileSet, files []*ast.File) (*types.Package, *types.Info) {
+	conf := types.Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(types.ImporterFrom)},
+		Interpolators: types.DefaultInterpolators(),
+	}
+	info := &types.Info{
+		Types:          make(map[ast.Expr]types.TypeAndValue),
+		Defs:           make(map[*ast.Ident]types.Object),
+		Uses:           make(map[*ast.Ident]types.Object),
+		Selections:     make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:      make(map[*ast.Ident]types.Instance),
+		Interpolations: make(map[*ast.TemplateLiteralPart]types.Interpolation),
+		ConstantParts:  make(map[*ast.TemplateLiteralPart]string),
+	}
+	pkg, err := conf.Check("p", fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg, info
+}
+
+func TestUnknownFunction(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", `package p
+
+import "github.com/mateusz834/tgo"
+
+func Card(ctx tgo.Ctx, title string) error {
+	<h2>"\{title}"</h2>
+	return nil
+}
+
+func helper() {}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := newInterpreter(t, fset, []*ast.File{f})
+
+	var b strings.Builder
+	if err := in.Render(&b, "Card", "<Title>"); err != nil || b.String() != "<h2>&lt;Title&gt;</h2>" {
+		t.Errorf("Render(Card) = %q, %v", b.String(), err)
+	}
+	for _, test := range []struct {
+		name string
+		args []any
+		err  string
+	}{
+		{"Missing", nil, "interp: function Missing not found"},
+		{"helper", nil, "interp: helper is not a tgo function"},
+		{"Card", nil, "interp: Card takes 1 arguments, got 0"},
+	} {
+		if err := in.Render(&b, test.name, test.args...); err == nil || err.Error() != test.err {
+			t.Errorf("Render(%s) = %v; want %s", test.name, err, test.err)
+		}
+	}
+}
+
+func TestArgTypes(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", `package p
+
+import (
+	"encoding/json"
+
+	"github.com/mateusz834/tgo"
+)
+
+type User struct {
+	Name  string `+"`json:\"full_name\"`"+`
+	Email string `+"`xml:\"email\"`"+`
+	tags  []string
+}
+
+func Card(ctx tgo.Ctx, u User) error {
+	b, _ := json.Marshal(u)
+	<p>"\{u.Name} \{u.Email} \{len(u.tags)}"</p>
+	"\{string(b)}"
+	return nil
+}
+`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, info := check(t, fset, []*ast.File{f})
+	in, err := interp.New(fset, pkg, info, []*ast.File{f}, &interp.Config{Funcs: map[string]any{"encoding/json.Marshal": json.Marshal}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	argTypes, err := in.ArgTypes("Card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []reflect.StructTag{`json:"full_name"`, `xml:"email" json:"Email"`, `json:"tags"`} {
+		if got := argTypes[0].Field(i).Tag; got != want {
+			t.Errorf("tag of field %d = %q; want %q", i, got, want)
+		}
+	}
+
+	u := reflect.New(argTypes[0])
+	if err := json.Unmarshal([]byte(`{"full_name": "Ada", "Email": "ada@example.com", "tags": ["x"]}`), u.Interface()); err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := in.Render(&b, "Card", u.Elem().Interface()); err != nil {
+		t.Fatal(err)
+	}
+	// The values created by the interpreter leave the unexported fields out of JSON.
+	const want = `<p>Ada ada@example.com 1</p>{&#34;full_name&#34;:&#34;Ada&#34;,&#34;Email&#34;:&#34;ada@example.com&#34;}`
+	if got := b.String(); got != want {
+		t.Errorf("Render(Card) = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+package interp
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// element writes the element s. A return statement in its body writes
+// the end tag before the function returns.
+func (m *machine) element(f *frame, e *env, s *ast.ElementBlockStmt) ctrl {
+	name, c := m.openTag(f, e, s.OpenTag)
+	if c == ctrlNone {
+		c = m.stmtList(f, newEnv(e), s.Body)
+	}
+	m.pos = s.EndTag.Pos()
+	m.write("</" + name + ">")
+	return c
+}
+
+// openTag writes the open tag t, with its attributes.
+// It returns the tag name.
+func (m *machine) openTag(f *frame, e *env, t *ast.OpenTag) (string, ctrl) {
+	name := m.tagName(e, t.Name, t.DynamicName)
+	m.write("<" + name)
+	c := m.stmtList(f, newEnv(e), t.Body)
+	m.write(">")
+	return name, c
+}
+
+// tagName returns the static or the dynamic tag name.
+func (m *machine) tagName(e *env, name *ast.Ident, dynamic *ast.TemplateLiteralPart) string {
+	if name != nil {
+		return name.Name
+	}
+	// The type checker permits only tgo.TagName values
+	// and constant strings, that are valid tag names.
+	return m.expr(e, dynamic.X).String()
+}
+
+// attribute writes the attribute s.
+func (m *machine) attribute(f *frame, e *env, s *ast.AttributeStmt) ctrl {
+	name, ok := s.AttrName.(*ast.Ident)
+	if !ok {
+		m.unsupported(s, "attribute name")
+	}
+	m.write(" " + name.Name)
+	switch v := s.Value.(type) {
+	case nil:
+	case *ast.BasicLit:
+		m.write(`="` + escape(m.stringLit(v)) + `"`)
+	case *ast.TemplateLiteralExpr:
+		m.write(`="`)
+		err := m.templateLiteral(e, v, true)
+		m.write(`"`)
+		if err.IsValid() {
+			f.ret = []reflect.Value{err}
+			return ctrlReturn
+		}
+	default:
+		m.unsupported(s, "attribute value")
+	}
+	return ctrlNone
+}
+
+// templateLiteral writes the template literal x, the value of an
+// attribute when attr is set. It returns the error that is returned
+// from the enclosing tgo function; or an invalid value.
+func (m *machine) templateLiteral(e *env, x *ast.TemplateLiteralExpr, attr bool) reflect.Value {
+	for i, raw := range x.Strings {
+		if i == 0 {
+			raw = strings.TrimPrefix(raw, `"`)
+		}
+		if i == len(x.Strings)-1 {
+			raw = strings.TrimSuffix(raw, `"`)
+		}
+		s, err := strconv.Unquote(`"` + raw + `"`)
+		if err != nil {
+			m.errorf(x.Pos(), "invalid template literal: %v", err)
+		}
+		m.write(escape(s))
+		if i < len(x.Parts) {
+			if err := m.part(e, x.Parts[i], attr); err.IsValid() {
+				return err
+			}
+		}
+	}
+	return reflect.Value{}
+}
+
+// part writes the template literal part p, as described by its
+// interpolation. It returns a non-nil error; or an invalid value.
+func (m *machine) part(e *env, p *ast.TemplateLiteralPart, attr bool) reflect.Value {
+	ip, ok := m.info.Interpolations[p]
+	if !ok {
+		m.errorf(p.Pos(), "missing interpolation of template literal part")
+	}
+	m.pos = p.Pos()
+	x := m.expr(e, p.X)
+	typ := m.info.TypeOf(p.X)
+
+	switch ip.Strategy {
+	case types.InterpolateDynamic:
+		if x.Kind() == reflect.Interface {
+			x = x.Elem()
+		}
+		switch x.Kind() {
+		case reflect.String:
+			if isTgoType(typ, "UnsafeHTML") {
+				m.write(x.String())
+			} else {
+				m.write(escape(x.String()))
+			}
+		case reflect.Int32:
+			m.write(escape(string(rune(x.Int()))))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64:
+			m.write(strconv.FormatInt(x.Int(), 10))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			m.write(strconv.FormatUint(x.Uint(), 10))
+		default:
+			m.errorf(p.X.Pos(), "cannot interpolate %s", typ)
+		}
+
+	case types.InterpolateFormat:
+		var s string
+		if verb, ok := p.Format.(*ast.FormatVerb); ok {
+			s = fmt.Sprintf(verb.Verb, valueInterface(x))
+		} else {
+			layout := m.expr(e, p.Format)
+			s = m.callMethod(p, x, typ, "Format", []reflect.Value{layout})[0].String()
+		}
+		m.write(escape(s))
+
+	case types.InterpolateString:
+		res := m.callMethod(p, x, typ, ip.Interface.Method(0).Name(), nil)
+		m.write(escape(res[0].String()))
+
+	case types.InterpolateText:
+		res := m.callMethod(p, x, typ, ip.Interface.Method(0).Name(), nil)
+		if !res[1].IsNil() {
+			return res[1]
+		}
+		m.write(escape(string(res[0].Bytes())))
+
+	case types.InterpolateRender:
+		if attr {
+			m.errorf(p.Pos(), "cannot render %s in an attribute", typ)
+		}
+		res := m.callMethod(p, x, typ, ip.Interface.Method(0).Name(), []reflect.Value{reflect.Zero(ctxType)})
+		if !res[0].IsNil() {
+			return res[0]
+		}
+
+	case types.InterpolateSafe:
+		if x.Kind() == reflect.Interface {
+			x = x.Elem()
+		}
+		m.write(x.String())
+
+	default:
+		m.errorf(p.Pos(), "unknown interpolation strategy %s", ip.Strategy)
+	}
+	return reflect.Value{}
+}
+
+// escape escapes the text or the attribute value s.
+func escape(s string) string {
+	return html.EscapeString(s)
+}
+
+// stringLit returns the value of the string literal x.
+func (m *machine) stringLit(x *ast.BasicLit) string {
+	s, err := strconv.Unquote(x.Value)
+	if err != nil {
+		m.errorf(x.Pos(), "invalid string literal: %v", err)
+	}
+	return s
+}
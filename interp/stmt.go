@@ -0,0 +1,467 @@
+package interp
+
+import (
+	"reflect"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// callDecl calls the function or method decl.
+func (m *machine) callDecl(decl *ast.FuncDecl, recv reflect.Value, args []reflect.Value) []reflect.Value {
+	if decl.Body == nil {
+		m.errorf(decl.Pos(), "function %s without body", decl.Name.Name)
+	}
+	if decl.Type.TypeParams != nil {
+		m.unsupported(decl, "generic function")
+	}
+	e := newEnv(nil)
+	if decl.Recv != nil {
+		m.bindParams(e, decl.Recv, []reflect.Value{recv})
+	}
+	return m.callBody(e, decl.Type, decl.Body, args)
+}
+
+// callBody calls the function body with the type typ, in the env e.
+func (m *machine) callBody(e *env, typ *ast.FuncType, body *ast.BlockStmt, args []reflect.Value) []reflect.Value {
+	defer func(pos token.Pos) { m.pos = pos }(m.pos)
+
+	m.bindParams(e, typ.Params, args)
+	f := &frame{}
+	var results []reflect.Type
+	if typ.Results != nil {
+		for _, field := range typ.Results.List {
+			t := m.reflectType(m.info.TypeOf(field.Type))
+			if len(field.Names) == 0 {
+				results = append(results, t)
+			}
+			for _, name := range field.Names {
+				results = append(results, t)
+				f.results = append(f.results, e.declare(m.info.Defs[name].(*types.Var), t))
+			}
+		}
+	}
+	if m.stmtList(f, e, body.List) != ctrlReturn {
+		return nil
+	}
+	// copy the values, so that they are not changed through
+	// the variables of the function
+	res := make([]reflect.Value, len(results))
+	for i, t := range results {
+		res[i] = reflect.New(t).Elem()
+		res[i].Set(m.assignable(f.ret[i], t))
+	}
+	return res
+}
+
+// bindParams declares the parameters of list in e, set to args.
+func (m *machine) bindParams(e *env, list *ast.FieldList, args []reflect.Value) {
+	i := 0
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			i++
+			continue
+		}
+		for _, name := range field.Names {
+			if v, ok := m.info.Defs[name].(*types.Var); ok {
+				e.declare(v, m.reflectType(v.Type())).Set(m.assignable(args[i], m.reflectType(v.Type())))
+			}
+			i++
+		}
+	}
+}
+
+func (m *machine) stmtList(f *frame, e *env, list []ast.Stmt) ctrl {
+	for _, s := range list {
+		if c := m.stmt(f, e, s); c != ctrlNone {
+			return c
+		}
+	}
+	return ctrlNone
+}
+
+func (m *machine) stmt(f *frame, e *env, s ast.Stmt) ctrl {
+	m.pos = s.Pos()
+	switch s := s.(type) {
+	case *ast.EmptyStmt:
+
+	case *ast.ExprStmt:
+		switch x := s.X.(type) {
+		case *ast.BasicLit:
+			m.write(escape(m.stringLit(x)))
+		case *ast.TemplateLiteralExpr:
+			if err := m.templateLiteral(e, x, false); err.IsValid() {
+				f.ret = []reflect.Value{err}
+				return ctrlReturn
+			}
+		default:
+			m.exprs(e, s.X)
+		}
+
+	case *ast.DeclStmt:
+		d, ok := s.Decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			break // constants and types are resolved statically
+		}
+		for _, spec := range d.Specs {
+			spec := spec.(*ast.ValueSpec)
+			vars := make([]*types.Var, len(spec.Names))
+			for i, name := range spec.Names {
+				vars[i], _ = m.info.Defs[name].(*types.Var)
+			}
+			m.assignDef(f, e, vars, spec.Values)
+		}
+
+	case *ast.AssignStmt:
+		m.assignStmt(f, e, s)
+
+	case *ast.IncDecStmt:
+		op := token.ADD
+		if s.Tok == token.DEC {
+			op = token.SUB
+		}
+		lv := m.lvalue(e, s.X)
+		x := lv.get()
+		lv.set(m.binary(s, op, x, reflect.ValueOf(1).Convert(x.Type())))
+
+	case *ast.BlockStmt:
+		return m.stmtList(f, newEnv(e), s.List)
+
+	case *ast.LabeledStmt:
+		return m.labeled(f, e, s.Stmt, s.Label.Name)
+
+	case *ast.IfStmt:
+		e = newEnv(e)
+		if s.Init != nil {
+			if c := m.stmt(f, e, s.Init); c != ctrlNone {
+				return c
+			}
+		}
+		if m.expr(e, s.Cond).Bool() {
+			return m.stmtList(f, newEnv(e), s.Body.List)
+		} else if s.Else != nil {
+			return m.stmt(f, e, s.Else)
+		}
+
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt:
+		return m.labeled(f, e, s, "")
+
+	case *ast.BranchStmt:
+		if s.Label != nil {
+			f.label = s.Label.Name
+		}
+		switch s.Tok {
+		case token.BREAK:
+			return ctrlBreak
+		case token.CONTINUE:
+			return ctrlContinue
+		case token.FALLTHROUGH:
+			return ctrlFallthrough
+		}
+		m.unsupported(s, "goto statement")
+
+	case *ast.ReturnStmt:
+		if len(s.Results) == 0 {
+			f.ret = f.results
+		} else {
+			f.ret = m.values(e, s.Results)
+		}
+		return ctrlReturn
+
+	case *ast.ElementBlockStmt:
+		return m.element(f, e, s)
+
+	case *ast.OpenTag:
+		// A void element (e.g. <br>), without an end tag.
+		_, c := m.openTag(f, e, s)
+		return c
+
+	case *ast.AttributeStmt:
+		return m.attribute(f, e, s)
+
+	case *ast.GoStmt:
+		m.unsupported(s, "go statement")
+	case *ast.DeferStmt:
+		m.unsupported(s, "defer statement")
+	case *ast.SelectStmt:
+		m.unsupported(s, "select statement")
+	case *ast.SendStmt:
+		m.unsupported(s, "send statement")
+	case *ast.TypeSwitchStmt:
+		m.unsupported(s, "type switch statement")
+	default:
+		m.unsupported(s, "statement")
+	}
+	return ctrlNone
+}
+
+// labeled executes the statement s with the label (or "").
+func (m *machine) labeled(f *frame, e *env, s ast.Stmt, label string) ctrl {
+	var c ctrl
+	switch s := s.(type) {
+	case *ast.ForStmt:
+		c = m.forStmt(f, e, s, label)
+	case *ast.RangeStmt:
+		c = m.rangeStmt(f, e, s, label)
+	case *ast.SwitchStmt:
+		c = m.switchStmt(f, e, s)
+		if c == ctrlBreak && (f.label == "" || f.label == label) {
+			f.label = ""
+			c = ctrlNone
+		}
+	default:
+		c = m.stmt(f, e, s)
+	}
+	return c
+}
+
+// loopCtrl handles the completion c of a loop body, it reports whether
+// the loop continues and the completion of the loop statement.
+func loopCtrl(f *frame, c ctrl, label string) (bool, ctrl) {
+	switch c {
+	case ctrlBreak, ctrlContinue:
+		if f.label != "" && f.label != label {
+			return false, c
+		}
+		f.label = ""
+		return c == ctrlContinue, ctrlNone
+	case ctrlReturn:
+		return false, c
+	}
+	return true, ctrlNone
+}
+
+func (m *machine) forStmt(f *frame, e *env, s *ast.ForStmt, label string) ctrl {
+	e = newEnv(e)
+	if s.Init != nil {
+		m.stmt(f, e, s.Init)
+	}
+	for {
+		if s.Cond != nil && !m.expr(e, s.Cond).Bool() {
+			return ctrlNone
+		}
+		if ok, c := loopCtrl(f, m.stmtList(f, newEnv(e), s.Body.List), label); !ok {
+			return c
+		}
+		// each iteration has its own copy of the loop variables,
+		// the post statement updates the copy of the next iteration
+		next := newEnv(e.parent)
+		for v, val := range e.vars {
+			next.declare(v, val.Type()).Set(val)
+		}
+		e = next
+		if s.Post != nil {
+			m.stmt(f, e, s.Post)
+		}
+	}
+}
+
+func (m *machine) rangeStmt(f *frame, e *env, s *ast.RangeStmt, label string) ctrl {
+	x := m.expr(e, s.X)
+	if x.Kind() == reflect.Pointer {
+		x = x.Elem()
+	}
+	body := func(key, val func() reflect.Value) (bool, ctrl) {
+		m.pos = s.Pos()
+		iter := newEnv(e)
+		for _, lhs := range []struct {
+			x ast.Expr
+			v func() reflect.Value
+		}{{s.Key, key}, {s.Value, val}} {
+			if lhs.x == nil || isBlank(lhs.x) {
+				continue
+			}
+			if s.Tok == token.DEFINE {
+				v := m.info.Defs[lhs.x.(*ast.Ident)].(*types.Var)
+				iter.declare(v, m.reflectType(v.Type())).Set(m.assignable(lhs.v(), m.reflectType(v.Type())))
+			} else {
+				lv := m.lvalue(e, lhs.x)
+				lv.set(lhs.v())
+			}
+		}
+		return loopCtrl(f, m.stmtList(f, iter, s.Body.List), label)
+	}
+
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := x
+		for i := reflect.New(n.Type()).Elem(); less(i, n); i = m.binary(s, token.ADD, i, reflect.ValueOf(1).Convert(n.Type())) {
+			if ok, c := body(func() reflect.Value { return i }, nil); !ok {
+				return c
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < x.Len(); i++ {
+			if ok, c := body(func() reflect.Value { return reflect.ValueOf(i) }, func() reflect.Value { return x.Index(i) }); !ok {
+				return c
+			}
+		}
+	case reflect.String:
+		for i, r := range x.String() {
+			if ok, c := body(func() reflect.Value { return reflect.ValueOf(i) }, func() reflect.Value { return reflect.ValueOf(r) }); !ok {
+				return c
+			}
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(x) {
+			v := x.MapIndex(k)
+			if !v.IsValid() {
+				continue // deleted during the iteration
+			}
+			if ok, c := body(func() reflect.Value { return k }, func() reflect.Value { return v }); !ok {
+				return c
+			}
+		}
+	default:
+		m.errorf(s.X.Pos(), "range over %s is not supported", m.info.TypeOf(s.X))
+	}
+	return ctrlNone
+}
+
+func (m *machine) switchStmt(f *frame, e *env, s *ast.SwitchStmt) ctrl {
+	e = newEnv(e)
+	if s.Init != nil {
+		if c := m.stmt(f, e, s.Init); c != ctrlNone {
+			return c
+		}
+	}
+	tag := reflect.ValueOf(true)
+	if s.Tag != nil {
+		tag = m.expr(e, s.Tag)
+	}
+	match := -1
+	for i, c := range s.Body.List {
+		c := c.(*ast.CaseClause)
+		if c.List == nil {
+			if match < 0 {
+				match = i // default, unless a later case matches
+			}
+			continue
+		}
+		for _, x := range c.List {
+			m.pos = x.Pos()
+			if equal(tag, m.expr(e, x)) {
+				match = i
+				goto found
+			}
+		}
+	}
+	if match < 0 {
+		return ctrlNone
+	}
+found:
+	for i := match; i < len(s.Body.List); i++ {
+		c := m.stmtList(f, newEnv(e), s.Body.List[i].(*ast.CaseClause).Body)
+		if c != ctrlFallthrough {
+			return c
+		}
+	}
+	return ctrlNone
+}
+
+func (m *machine) assignStmt(f *frame, e *env, s *ast.AssignStmt) {
+	switch s.Tok {
+	case token.DEFINE:
+		vars := make([]*types.Var, len(s.Lhs))
+		for i, x := range s.Lhs {
+			id := x.(*ast.Ident)
+			if v, ok := m.info.Defs[id].(*types.Var); ok {
+				vars[i] = v
+			} else if v, ok := m.info.Uses[id].(*types.Var); ok {
+				vars[i] = v // redeclared
+			}
+		}
+		vals := m.valuesN(e, s.Rhs, len(vars))
+		for i, v := range vars {
+			if v == nil || v.Name() == "_" {
+				continue
+			}
+			t := m.reflectType(v.Type())
+			val := m.assignable(vals[i], t)
+			if m.info.Defs[s.Lhs[i].(*ast.Ident)] != nil {
+				e.declare(v, t).Set(val)
+			} else {
+				m.lvalue(e, s.Lhs[i]).set(val)
+			}
+		}
+	case token.ASSIGN:
+		lvs := make([]lvalue, len(s.Lhs))
+		for i, x := range s.Lhs {
+			if !isBlank(x) {
+				lvs[i] = m.lvalue(e, x)
+			}
+		}
+		vals := m.valuesN(e, s.Rhs, len(s.Lhs))
+		for i, lv := range lvs {
+			if lv.m != nil {
+				lv.set(vals[i])
+			}
+		}
+	default:
+		lv := m.lvalue(e, s.Lhs[0])
+		y := m.expr(e, s.Rhs[0])
+		lv.set(m.binary(s, assignOp(s.Tok), lv.get(), y))
+	}
+}
+
+// assignDef assigns the values of rhs to the newly declared variables
+// vars, nil elements of vars are skipped. Package-level variables are
+// assigned in the globals.
+func (m *machine) assignDef(f *frame, e *env, vars []*types.Var, rhs []ast.Expr) {
+	var vals []reflect.Value
+	if len(rhs) > 0 {
+		vals = m.valuesN(e, rhs, len(vars))
+	}
+	for i, v := range vars {
+		if v == nil {
+			continue
+		}
+		t := m.reflectType(v.Type())
+		var val reflect.Value
+		if vals != nil {
+			val = m.assignable(vals[i], t)
+		} else {
+			val = reflect.Zero(t)
+		}
+		if g, ok := m.globals[v]; ok {
+			g.Set(val)
+		} else if v.Name() != "_" && e != nil {
+			e.declare(v, t).Set(val)
+		}
+	}
+}
+
+func assignOp(tok token.Token) token.Token {
+	switch tok {
+	case token.ADD_ASSIGN:
+		return token.ADD
+	case token.SUB_ASSIGN:
+		return token.SUB
+	case token.MUL_ASSIGN:
+		return token.MUL
+	case token.QUO_ASSIGN:
+		return token.QUO
+	case token.REM_ASSIGN:
+		return token.REM
+	case token.AND_ASSIGN:
+		return token.AND
+	case token.OR_ASSIGN:
+		return token.OR
+	case token.XOR_ASSIGN:
+		return token.XOR
+	case token.SHL_ASSIGN:
+		return token.SHL
+	case token.SHR_ASSIGN:
+		return token.SHR
+	case token.AND_NOT_ASSIGN:
+		return token.AND_NOT
+	}
+	return token.ILLEGAL
+}
+
+func isBlank(x ast.Expr) bool {
+	id, ok := x.(*ast.Ident)
+	return ok && id.Name == "_"
+}
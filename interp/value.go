@@ -0,0 +1,445 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/constant"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+var (
+	anyType   = reflect.TypeFor[any]()
+	errorType = reflect.TypeFor[error]()
+)
+
+// reflectType returns the representation of the type t.
+func (m *machine) reflectType(t types.Type) reflect.Type {
+	cache := m.types
+	if m.args {
+		cache = m.argTypes
+	}
+	if rt, ok := cache[t]; ok {
+		if rt == nil {
+			m.errorf(m.pos, "recursive type %s is not supported", t)
+		}
+		return rt
+	}
+	cache[t] = nil // in progress
+	rt := m.newReflectType(t)
+	cache[t] = rt
+	return rt
+}
+
+func (m *machine) newReflectType(t types.Type) reflect.Type {
+	switch t := t.(type) {
+	case *types.Basic:
+		if rt := basicTypes[t.Kind()]; rt != nil {
+			return rt
+		}
+	case *types.Alias:
+		return m.reflectType(types.Unalias(t))
+	case *types.Named:
+		switch {
+		case t.Obj().Pkg() == nil && t.Obj().Name() == "error":
+			return errorType
+		case isTgoType(t, "Ctx"):
+			return ctxType
+		}
+		// The methods of the named types are resolved statically.
+		return m.reflectType(t.Underlying())
+	case *types.Pointer:
+		return reflect.PointerTo(m.reflectType(t.Elem()))
+	case *types.Slice:
+		return reflect.SliceOf(m.reflectType(t.Elem()))
+	case *types.Array:
+		return reflect.ArrayOf(int(t.Len()), m.reflectType(t.Elem()))
+	case *types.Map:
+		return reflect.MapOf(m.reflectType(t.Key()), m.reflectType(t.Elem()))
+	case *types.Struct:
+		// Field names are not significant (fields are selected by
+		// index), they are exported for reflect.StructOf.
+		fields := make([]reflect.StructField, t.NumFields())
+		for i := range fields {
+			fields[i] = reflect.StructField{
+				Name: fmt.Sprintf("F%d", i),
+				Type: m.reflectType(t.Field(i).Type()),
+				Tag:  m.fieldTag(t, i),
+			}
+		}
+		return reflect.StructOf(fields)
+	case *types.Signature:
+		in := make([]reflect.Type, t.Params().Len())
+		for i := range in {
+			in[i] = m.reflectType(t.Params().At(i).Type())
+		}
+		out := make([]reflect.Type, t.Results().Len())
+		for i := range out {
+			out[i] = m.reflectType(t.Results().At(i).Type())
+		}
+		return reflect.FuncOf(in, out, t.Variadic())
+	case *types.Interface:
+		// All interfaces are represented by any, the values keep their
+		// dynamic (host) types.
+		return anyType
+	}
+	m.errorf(m.pos, "type %s is not supported", t)
+	return nil
+}
+
+// fieldTag returns the tag of the representation of the i'th field of t.
+// The tag holds the name of the field for encoding/json, unless it has
+// a json tag. The unexported fields are left out of JSON, except in the
+// representations of ArgTypes, which are converted to the representations
+// of the interpreter by Render.
+func (m *machine) fieldTag(t *types.Struct, i int) reflect.StructTag {
+	f := t.Field(i)
+	if !f.Exported() && !m.args {
+		return `json:"-"`
+	}
+	tag := reflect.StructTag(t.Tag(i))
+	if _, ok := tag.Lookup("json"); ok {
+		return tag
+	}
+	if tag != "" {
+		tag += " "
+	}
+	return tag + reflect.StructTag(fmt.Sprintf("json:%q", f.Name()))
+}
+
+var basicTypes = [...]reflect.Type{
+	types.Bool:          reflect.TypeFor[bool](),
+	types.Int:           reflect.TypeFor[int](),
+	types.Int8:          reflect.TypeFor[int8](),
+	types.Int16:         reflect.TypeFor[int16](),
+	types.Int32:         reflect.TypeFor[int32](),
+	types.Int64:         reflect.TypeFor[int64](),
+	types.Uint:          reflect.TypeFor[uint](),
+	types.Uint8:         reflect.TypeFor[uint8](),
+	types.Uint16:        reflect.TypeFor[uint16](),
+	types.Uint32:        reflect.TypeFor[uint32](),
+	types.Uint64:        reflect.TypeFor[uint64](),
+	types.Uintptr:       reflect.TypeFor[uintptr](),
+	types.Float32:       reflect.TypeFor[float32](),
+	types.Float64:       reflect.TypeFor[float64](),
+	types.Complex64:     reflect.TypeFor[complex64](),
+	types.Complex128:    reflect.TypeFor[complex128](),
+	types.String:        reflect.TypeFor[string](),
+	types.UntypedBool:   reflect.TypeFor[bool](),
+	types.UntypedInt:    reflect.TypeFor[int](),
+	types.UntypedRune:   reflect.TypeFor[rune](),
+	types.UntypedFloat:  reflect.TypeFor[float64](),
+	types.UntypedString: reflect.TypeFor[string](),
+	types.UnsafePointer: nil,
+	types.UntypedNil:    nil,
+}
+
+// constValue returns the value of the constant c of the type t.
+func (m *machine) constValue(n ast.Node, c constant.Value, t types.Type) reflect.Value {
+	rt := m.reflectType(t)
+	if rt.Kind() == reflect.Interface {
+		rt = m.reflectType(types.Default(types.Typ[constantKinds[c.Kind()]]))
+	}
+	v := reflect.New(rt).Elem()
+	switch rt.Kind() {
+	case reflect.Bool:
+		v.SetBool(constant.BoolVal(c))
+	case reflect.String:
+		v.SetString(constant.StringVal(c))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, _ := constant.Int64Val(constant.ToInt(c))
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, _ := constant.Uint64Val(constant.ToInt(c))
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, _ := constant.Float64Val(constant.ToFloat(c))
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		re, _ := constant.Float64Val(constant.Real(c))
+		im, _ := constant.Float64Val(constant.Imag(c))
+		v.SetComplex(complex(re, im))
+	default:
+		m.errorf(n.Pos(), "constant of type %s is not supported", t)
+	}
+	return v
+}
+
+var constantKinds = [...]types.BasicKind{
+	constant.Bool:    types.UntypedBool,
+	constant.String:  types.UntypedString,
+	constant.Int:     types.UntypedInt,
+	constant.Float:   types.UntypedFloat,
+	constant.Complex: types.UntypedComplex,
+}
+
+// assignable returns v as a value of the type t. The invalid value
+// (untyped nil) becomes the zero value of t, values of the named types
+// of the package and of their underlying types are converted.
+func (m *machine) assignable(v reflect.Value, t reflect.Type) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Zero(t)
+	}
+	if v.Type() == t {
+		return v
+	}
+	if t.Kind() == reflect.Interface {
+		if v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Zero(t)
+			}
+			v = v.Elem()
+		}
+		if !v.Type().Implements(t) {
+			m.errorf(m.pos, "%s does not implement %s", v.Type(), t)
+		}
+		r := reflect.New(t).Elem()
+		r.Set(v)
+		return r
+	}
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t)
+	}
+	return v
+}
+
+// binary returns x op y, for an operator other than && and ||.
+func (m *machine) binary(n ast.Node, op token.Token, x, y reflect.Value) reflect.Value {
+	if x.IsValid() && y.IsValid() && x.Kind() == y.Kind() && x.Kind() != reflect.Interface {
+		y = m.assignable(y, x.Type())
+	}
+	switch op {
+	case token.EQL:
+		return reflect.ValueOf(equal(x, y))
+	case token.NEQ:
+		return reflect.ValueOf(!equal(x, y))
+	case token.LSS:
+		return reflect.ValueOf(less(x, y))
+	case token.GTR:
+		return reflect.ValueOf(less(y, x))
+	case token.LEQ:
+		return reflect.ValueOf(!less(y, x))
+	case token.GEQ:
+		return reflect.ValueOf(!less(x, y))
+	case token.SHL, token.SHR:
+		var s uint64
+		switch y.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if y.Int() < 0 {
+				m.errorf(n.Pos(), "negative shift amount")
+			}
+			s = uint64(y.Int())
+		default:
+			s = y.Uint()
+		}
+		r := reflect.New(x.Type()).Elem()
+		switch {
+		case isInt(x) && op == token.SHL:
+			r.SetInt(x.Int() << s)
+		case isInt(x):
+			r.SetInt(x.Int() >> s)
+		case op == token.SHL:
+			r.SetUint(x.Uint() << s)
+		default:
+			r.SetUint(x.Uint() >> s)
+		}
+		return r
+	}
+
+	y = m.assignable(y, x.Type())
+	r := reflect.New(x.Type()).Elem()
+	switch {
+	case isInt(x):
+		a, b := x.Int(), y.Int()
+		if (op == token.QUO || op == token.REM) && b == 0 {
+			m.errorf(n.Pos(), "integer divide by zero")
+		}
+		switch op {
+		case token.ADD:
+			r.SetInt(a + b)
+		case token.SUB:
+			r.SetInt(a - b)
+		case token.MUL:
+			r.SetInt(a * b)
+		case token.QUO:
+			r.SetInt(a / b)
+		case token.REM:
+			r.SetInt(a % b)
+		case token.AND:
+			r.SetInt(a & b)
+		case token.OR:
+			r.SetInt(a | b)
+		case token.XOR:
+			r.SetInt(a ^ b)
+		case token.AND_NOT:
+			r.SetInt(a &^ b)
+		default:
+			goto invalid
+		}
+	case isUint(x):
+		a, b := x.Uint(), y.Uint()
+		if (op == token.QUO || op == token.REM) && b == 0 {
+			m.errorf(n.Pos(), "integer divide by zero")
+		}
+		switch op {
+		case token.ADD:
+			r.SetUint(a + b)
+		case token.SUB:
+			r.SetUint(a - b)
+		case token.MUL:
+			r.SetUint(a * b)
+		case token.QUO:
+			r.SetUint(a / b)
+		case token.REM:
+			r.SetUint(a % b)
+		case token.AND:
+			r.SetUint(a & b)
+		case token.OR:
+			r.SetUint(a | b)
+		case token.XOR:
+			r.SetUint(a ^ b)
+		case token.AND_NOT:
+			r.SetUint(a &^ b)
+		default:
+			goto invalid
+		}
+	case x.Kind() == reflect.Float32 || x.Kind() == reflect.Float64:
+		a, b := x.Float(), y.Float()
+		switch op {
+		case token.ADD:
+			r.SetFloat(a + b)
+		case token.SUB:
+			r.SetFloat(a - b)
+		case token.MUL:
+			r.SetFloat(a * b)
+		case token.QUO:
+			r.SetFloat(a / b)
+		default:
+			goto invalid
+		}
+	case x.Kind() == reflect.Complex64 || x.Kind() == reflect.Complex128:
+		a, b := x.Complex(), y.Complex()
+		switch op {
+		case token.ADD:
+			r.SetComplex(a + b)
+		case token.SUB:
+			r.SetComplex(a - b)
+		case token.MUL:
+			r.SetComplex(a * b)
+		case token.QUO:
+			r.SetComplex(a / b)
+		default:
+			goto invalid
+		}
+	case x.Kind() == reflect.String && op == token.ADD:
+		r.SetString(x.String() + y.String())
+	default:
+		goto invalid
+	}
+	return r
+invalid:
+	m.errorf(n.Pos(), "operator %s on %s is not supported", op, x.Type())
+	return reflect.Value{}
+}
+
+func (m *machine) unary(x *ast.UnaryExpr, v reflect.Value) reflect.Value {
+	r := reflect.New(v.Type()).Elem()
+	switch {
+	case x.Op == token.ADD:
+		return v
+	case x.Op == token.NOT && v.Kind() == reflect.Bool:
+		r.SetBool(!v.Bool())
+	case x.Op == token.SUB && isInt(v):
+		r.SetInt(-v.Int())
+	case x.Op == token.SUB && isUint(v):
+		r.SetUint(-v.Uint())
+	case x.Op == token.SUB && (v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64):
+		r.SetFloat(-v.Float())
+	case x.Op == token.SUB && (v.Kind() == reflect.Complex64 || v.Kind() == reflect.Complex128):
+		r.SetComplex(-v.Complex())
+	case x.Op == token.XOR && isInt(v):
+		r.SetInt(^v.Int())
+	case x.Op == token.XOR && isUint(v):
+		r.SetUint(^v.Uint())
+	default:
+		m.errorf(x.Pos(), "operator %s on %s is not supported", x.Op, v.Type())
+	}
+	return r
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// equal reports whether x == y, the invalid value is nil.
+func equal(x, y reflect.Value) bool {
+	switch {
+	case !x.IsValid() && !y.IsValid():
+		return true
+	case !x.IsValid():
+		return y.IsNil()
+	case !y.IsValid():
+		return x.IsNil()
+	}
+	return valueInterface(x) == valueInterface(y)
+}
+
+// less reports whether x < y, for ordered values of the same type.
+func less(x, y reflect.Value) bool {
+	switch {
+	case isInt(x):
+		return x.Int() < y.Int()
+	case isUint(x):
+		return x.Uint() < y.Uint()
+	case x.Kind() == reflect.Float32 || x.Kind() == reflect.Float64:
+		return x.Float() < y.Float()
+	case x.Kind() == reflect.String:
+		return x.String() < y.String()
+	}
+	panic(fmt.Sprintf("%s is not ordered", x.Type()))
+}
+
+// sortedKeys returns the keys of the map v, ordered keys are sorted.
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	if len(keys) > 0 && (isInt(keys[0]) || isUint(keys[0]) || keys[0].Kind() == reflect.String ||
+		keys[0].Kind() == reflect.Float32 || keys[0].Kind() == reflect.Float64) {
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			switch {
+			case less(a, b):
+				return -1
+			case less(b, a):
+				return 1
+			}
+			return 0
+		})
+	}
+	return keys
+}
+
+// valueInterface returns the value of v as an interface,
+// nil for the invalid value.
+func valueInterface(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
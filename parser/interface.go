@@ -9,6 +9,7 @@ package parser
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -55,6 +56,8 @@ const (
 	DeclarationErrors                                 // report declaration errors
 	SpuriousErrors                                    // same as AllErrors, for backward-compatibility
 	SkipObjectResolution                              // skip deprecated identifier resolution; see ParseFile
+	GoOnly                                            // report tgo syntax as an error; see ParseDir
+	TgoSyntax                                         // accept tgo syntax; see ParseDir
 	AllErrors            = SpuriousErrors             // report all errors (not just the first 10 on different lines)
 )
 
@@ -74,6 +77,10 @@ const (
 // all Ident.Obj fields to be nil. Those fields are deprecated; see
 // [ast.Object] for details.
 //
+// Tgo syntax (tags, attributes and template literals) is accepted unless
+// the [GoOnly] mode bit is set, in which case every tgo construct is
+// reported as a syntax error. [GoOnly] takes precedence over [TgoSyntax].
+//
 // Position information is recorded in the file set fset, which must not be
 // nil.
 //
@@ -127,14 +134,15 @@ func ParseFile(fset *token.FileSet, filename string, src any, mode Mode) (f *ast
 	return
 }
 
-// ParseDir calls [ParseFile] for all files with names ending in ".go" in the
-// directory specified by path and returns a map of package name -> package
+// ParseDir calls [ParseFile] for all files with names ending in ".go" or ".tgo"
+// in the directory specified by path and returns a map of package name -> package
 // AST with all the packages found.
 //
 // If filter != nil, only the files with [fs.FileInfo] entries passing through
-// the filter (and ending in ".go") are considered. The mode bits are passed
-// to [ParseFile] unchanged. Position information is recorded in fset, which
-// must not be nil.
+// the filter (and ending in ".go" or ".tgo") are considered. The mode bits are
+// passed to [ParseFile] unchanged, except when neither [GoOnly] nor [TgoSyntax]
+// is set: then [GoOnly] is used for ".go" files and [TgoSyntax] for ".tgo" files.
+// Position information is recorded in fset, which must not be nil.
 //
 // If the directory couldn't be read, a nil map and the respective error are
 // returned. If a parse error occurred, a non-nil but incomplete map and the
@@ -147,7 +155,7 @@ func ParseDir(fset *token.FileSet, path string, filter func(fs.FileInfo) bool, m
 
 	pkgs = make(map[string]*ast.Package)
 	for _, d := range list {
-		if d.IsDir() || !strings.HasSuffix(d.Name(), ".go") {
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".go") && !strings.HasSuffix(d.Name(), ".tgo") {
 			continue
 		}
 		if filter != nil {
@@ -160,7 +168,7 @@ func ParseDir(fset *token.FileSet, path string, filter func(fs.FileInfo) bool, m
 			}
 		}
 		filename := filepath.Join(path, d.Name())
-		if src, err := ParseFile(fset, filename, nil, mode); err == nil {
+		if src, err := ParseFile(fset, filename, nil, fileMode(filename, mode)); err == nil {
 			name := src.Name.Name
 			pkg, found := pkgs[name]
 			if !found {
@@ -179,6 +187,17 @@ func ParseDir(fset *token.FileSet, path string, filter func(fs.FileInfo) bool, m
 	return
 }
 
+// fileMode returns the mode used by [ParseDir] to parse filename.
+func fileMode(filename string, mode Mode) Mode {
+	if mode&(GoOnly|TgoSyntax) != 0 {
+		return mode
+	}
+	if strings.HasSuffix(filename, ".tgo") {
+		return mode | TgoSyntax
+	}
+	return mode | GoOnly
+}
+
 // ParseExprFrom is a convenience function for parsing an expression.
 // The arguments have the same meaning as for [ParseFile], but the source must
 // be a valid Go (type or value) expression. Specifically, fset must not
@@ -229,6 +248,77 @@ func ParseExprFrom(fset *token.FileSet, filename string, src any, mode Mode) (ex
 	return
 }
 
+// ParseStmtListFrom parses a statement list, as found in a function
+// body, e.g. a snippet of tgo code. Element blocks are parsed as in
+// [ParseFile]. The src argument has the same meaning as for [ParseFile].
+//
+// Position information is recorded in file, e.g. a file added to a
+// [token.FileSet] by the caller; the size of file must match the length
+// of the source. The positions of the errors are relative to the start
+// of file, i.e. of the fragment.
+//
+// If the source couldn't be read, the returned AST is nil and the error
+// indicates the specific failure. If the source was read but syntax
+// errors were found, the result is a partial AST (with [ast.Bad]* nodes
+// representing the fragments of erroneous source code). Multiple errors
+// are returned via a scanner.ErrorList which is sorted by source position.
+func ParseStmtListFrom(file *token.File, src any, mode Mode) (list []ast.Stmt, err error) {
+	if file == nil {
+		panic("parser.ParseStmtListFrom: no token.File provided (file == nil)")
+	}
+	return parseFragment(file, src, mode, false)
+}
+
+// ParseMarkupFrom is like [ParseStmtListFrom], but the source must be a
+// tgo markup fragment: tags, attributes, text and element blocks, e.g.
+//
+//	<a @href="/users/\{id}">"\{name}"</a>
+//
+// Any other statement is reported as a syntax error. Tgo syntax is
+// accepted even if the [GoOnly] mode bit is set.
+func ParseMarkupFrom(file *token.File, src any, mode Mode) (list []ast.Stmt, err error) {
+	if file == nil {
+		panic("parser.ParseMarkupFrom: no token.File provided (file == nil)")
+	}
+	return parseFragment(file, src, mode&^GoOnly, true)
+}
+
+// parseFragment parses the statement list of a fragment for
+// ParseStmtListFrom and ParseMarkupFrom.
+func parseFragment(file *token.File, src any, mode Mode, markup bool) (list []ast.Stmt, err error) {
+	text, err := readSource(file.Name(), src)
+	if err != nil {
+		return nil, err
+	}
+	if file.Size() != len(text) {
+		panic(fmt.Sprintf("parser: file size (%d) does not match src len (%d)", file.Size(), len(text)))
+	}
+
+	var p parser
+	defer func() {
+		if e := recover(); e != nil {
+			// resume same panic if it's not a bailout
+			bail, ok := e.(bailout)
+			if !ok {
+				panic(e)
+			} else if bail.msg != "" {
+				p.errors.Add(p.file.Position(bail.pos), bail.msg)
+			}
+		}
+		p.errors.Sort()
+		err = p.errors.Err()
+	}()
+
+	p.initFile(file, text, mode)
+	list = p.parseStmtList()
+	if markup {
+		p.checkMarkup(list)
+	}
+	p.expect(token.EOF)
+
+	return
+}
+
 // ParseExpr is a convenience function for obtaining the AST of an expression x.
 // The position information recorded in the AST is undefined. The filename used
 // in error messages is the empty string.
@@ -68,12 +68,20 @@ type parser struct {
 
 	templateLit []*ast.TemplateLiteralExpr
 
-	openTags      []ast.OpenTag
-	openTagsIndex int
+	// Open tags of the elements being parsed, the statement list being
+	// parsed starts at openTagsBase (see parseMarkupStmtList).
+	openTags     []*ast.OpenTag
+	openTagsBase int
 }
 
 func (p *parser) init(fset *token.FileSet, filename string, src []byte, mode Mode) {
-	p.file = fset.AddFile(filename, -1, len(src))
+	p.initFile(fset.AddFile(filename, -1, len(src)), src, mode)
+}
+
+// initFile is like init, but records the position information in file,
+// the size of file must match the length of src.
+func (p *parser) initFile(file *token.File, src []byte, mode Mode) {
+	p.file = file
 	eh := func(pos token.Position, msg string) { p.errors.Add(pos, msg) }
 	p.scanner.Init(p.file, src, eh, scanner.ScanComments)
 
@@ -1407,11 +1415,12 @@ func (p *parser) parseStmtList() (list []ast.Stmt) {
 		defer un(trace(p, "StatementList"))
 	}
 
-	for p.tok != token.CASE && p.tok != token.DEFAULT && p.tok != token.RBRACE && p.tok != token.EOF {
-		list = append(list, p.parseStmt())
-	}
-
-	list = p.combineElemmentBlocks(list)
+	// The open tags of enclosing statement lists
+	// cannot be closed in this statement list.
+	base := p.openTagsBase
+	p.openTagsBase = len(p.openTags)
+	list, _, _ = p.parseMarkupStmtList()
+	p.openTagsBase = base
 
 	return
 }
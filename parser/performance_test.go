@@ -5,7 +5,9 @@
 package parser
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/mateusz834/tgoast/token"
@@ -30,6 +32,46 @@ func BenchmarkParse(b *testing.B) {
 	}
 }
 
+// tgoSrc is a tgo-heavy file, with deeply nested elements.
+var tgoSrc = func() []byte {
+	var b strings.Builder
+	b.WriteString("package p\n\nimport \"github.com/mateusz834/tgo\"\n")
+	for i := range 100 {
+		fmt.Fprintf(&b, `
+func Card%d(ctx tgo.Ctx, title string, items []string) error {
+	<article @class="card" @id="card-%d">
+		<header @class="card-header">
+			<h2 @class="card-title"><a @href="/cards/%d">"\{title}"</a></h2>
+		</header>
+		<div @class="card-body">
+			<ul @class="items">
+				for _, item := range items {
+					<li @class="item"><span>"\{item}"</span></li>
+				}
+			</ul>
+			if len(items) == 0 {
+				<p @class="empty">"No items"</p>
+			}
+		</div>
+		<footer @class="card-footer"><a @href="/cards">"All cards"</a></footer>
+	</article>
+	return nil
+}
+`, i, i, i)
+	}
+	return []byte(b.String())
+}()
+
+func BenchmarkParseTgo(b *testing.B) {
+	b.SetBytes(int64(len(tgoSrc)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseFile(token.NewFileSet(), "", tgoSrc, ParseComments|SkipObjectResolution); err != nil {
+			b.Fatalf("benchmark failed due to parse error: %s", err)
+		}
+	}
+}
+
 func BenchmarkParseOnly(b *testing.B) {
 	b.SetBytes(int64(len(src)))
 	for i := 0; i < b.N; i++ {
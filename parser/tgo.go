@@ -3,125 +3,192 @@ package parser
 import (
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/mateusz834/tgoast/ast"
 	"github.com/mateusz834/tgoast/token"
 )
 
-func unlabelAs[T ast.Stmt](labeled ast.Stmt) (lastLabeledStmt *ast.LabeledStmt, unlabeled T) {
+// unlabel returns the statement s without its labels
+// and the innermost label of s; or nil.
+func unlabel(s ast.Stmt) (*ast.LabeledStmt, ast.Stmt) {
+	var label *ast.LabeledStmt
 	for {
-		if l, ok := labeled.(*ast.LabeledStmt); ok {
-			labeled = l.Stmt
-			lastLabeledStmt = l
-			continue
+		l, ok := s.(*ast.LabeledStmt)
+		if !ok {
+			return label, s
 		}
-		return lastLabeledStmt, labeled.(T)
+		label, s = l, l.Stmt
 	}
 }
 
-func (p *parser) combineElemmentBlocks(list []ast.Stmt) (out []ast.Stmt) {
-	type openTagData struct {
-		openTagIndex int
-		body         []ast.Stmt
-	}
-
-	openTagDepth := make([]openTagData, 0, 16)
+// parseMarkupStmtList parses the statements up to the end of the
+// statement list, or up to an end tag matching one of the open tags
+// p.openTags[p.openTagsBase:], the statements between an open tag and
+// its end tag are parsed as the body of an ElementBlockStmt. It returns
+// the end tag that ended the list (end) and its statement (endStmt),
+// which is a *ast.LabeledStmt when the end tag is labeled; or nil.
+//
+// The open tags without a matching end tag are reported (unless they
+// are void elements) and kept in the list, followed by the statements
+// of their bodies, end tags without a matching open tag are reported
+// and kept in the list.
+func (p *parser) parseMarkupStmtList() (list []ast.Stmt, endStmt ast.Stmt, end *ast.EndTag) {
+	for p.tok != token.CASE && p.tok != token.DEFAULT && p.tok != token.RBRACE && p.tok != token.EOF {
+		s := p.parseStmt()
+		label, u := unlabel(s)
+		switch u := u.(type) {
+		case *ast.OpenTag:
+			if !u.ClosePos.IsValid() {
+				break
+			}
+			p.openTags = append(p.openTags, u)
+			incNestLev(p)
+			body, endStmt, end := p.parseMarkupStmtList()
+			decNestLev(p)
+			p.openTags = p.openTags[:len(p.openTags)-1]
+
+			if end == nil || !tagsMatch(u, end) {
+				p.checkUnclosedTag(u)
+				list = append(list, s)
+				list = append(list, body...)
+				if end != nil {
+					// end matches the open tag of an enclosing element.
+					return list, endStmt, end
+				}
+				continue
+			}
 
-	appendStmts := func(stmts []ast.Stmt) {
-		if len(openTagDepth) != 0 {
-			last := &openTagDepth[len(openTagDepth)-1]
-			last.body = append(last.body, stmts...)
-		} else {
-			out = append(out, stmts...)
+			if endLabel, _ := unlabel(endStmt); endLabel != nil {
+				endLabel.Stmt = &ast.EmptyStmt{Semicolon: end.OpenPos, Implicit: true}
+				body = append(body, endStmt)
+			}
+			if isVoidElement(u) {
+				p.error(end.OpenPos, fmt.Sprintf("end tag of void element: %v", u.Name.Name))
+			}
+			s = &ast.ElementBlockStmt{OpenTag: u, Body: body, EndTag: end}
+			if label != nil {
+				label.Stmt = s
+				s = label
+			}
+		case *ast.EndTag:
+			if !u.ClosePos.IsValid() {
+				break
+			}
+			for _, open := range slices.Backward(p.openTags[p.openTagsBase:]) {
+				if tagsMatch(open, u) {
+					return list, s, u
+				}
+			}
+			p.error(u.OpenPos, fmt.Sprintf("unopenend tag: %v", endTagName(u)))
 		}
+		list = append(list, s)
 	}
+	return list, nil, nil
+}
 
-	last := 0
-	for i, stmt := range list {
-		lastLabeledStmt, unlabeledStmt := unlabelAs[ast.Stmt](stmt)
-	outer:
-		switch unlabeledStmt := unlabeledStmt.(type) {
-		case *ast.OpenTag:
-			if !unlabeledStmt.ClosePos.IsValid() {
+// checkMarkup reports the statements of list (and of the bodies of its
+// element blocks) that are not markup: tags, attributes and text.
+func (p *parser) checkMarkup(list []ast.Stmt) {
+	for _, s := range list {
+		switch s := s.(type) {
+		case *ast.ElementBlockStmt:
+			p.checkMarkup(s.Body)
+			continue
+		case *ast.OpenTag, *ast.EndTag, *ast.AttributeStmt, *ast.BadStmt:
+			continue
+		case *ast.EmptyStmt:
+			if s.Implicit {
 				continue
 			}
-			appendStmts(list[last:i])
-			last = i + 1
-			openTagDepth = append(openTagDepth, openTagData{openTagIndex: i})
-		case *ast.EndTag:
-			if !unlabeledStmt.ClosePos.IsValid() {
+		case *ast.ExprStmt:
+			switch x := s.X.(type) {
+			case *ast.TemplateLiteralExpr:
 				continue
-			}
-
-			for j, lastOpenTagData := range slices.Backward(openTagDepth) {
-				openTag := list[lastOpenTagData.openTagIndex]
-				lastLabeledOpen, unlabeledOpenTag := unlabelAs[*ast.OpenTag](openTag)
-
-				if unlabeledOpenTag.Name.Name == unlabeledStmt.Name.Name {
-					body := lastOpenTagData.body
-
-					for _, v := range openTagDepth[j+1:] {
-						openTagStmt := list[v.openTagIndex]
-						_, openTag := unlabelAs[*ast.OpenTag](openTagStmt)
-						if openTag.Name != nil {
-							if openTag.Name.Name != "br" {
-								p.error(openTag.OpenPos, "unclosed tag")
-							}
-						}
-						body = append(body, openTagStmt)
-						body = append(body, v.body...)
-					}
-
-					body = append(body, list[last:i]...)
-					last = i + 1
-
-					openTagDepth = openTagDepth[:j]
-
-					if lastLabeledStmt != nil {
-						lastLabeledStmt.Stmt = &ast.EmptyStmt{Semicolon: unlabeledStmt.OpenPos, Implicit: true}
-						body = append(body, stmt)
-					}
-
-					// TODO: if void element, then error.
-
-					var s ast.Stmt = &ast.ElementBlockStmt{
-						OpenTag: unlabeledOpenTag,
-						Body:    body,
-						EndTag:  unlabeledStmt,
-					}
-
-					if lastLabeledOpen != nil {
-						lastLabeledOpen.Stmt = s
-						s = openTag
-					}
-
-					appendStmts([]ast.Stmt{s})
-					break outer
+			case *ast.BasicLit:
+				if x.Kind == token.STRING {
+					continue
 				}
 			}
-
-			p.error(unlabeledStmt.OpenPos, fmt.Sprintf("unopenend tag: %v", unlabeledStmt.Name.Name))
 		}
+		p.error(s.Pos(), "expected markup, found statement")
+	}
+}
+
+// tagsMatch reports whether end is the end tag of open. A static
+// name matches the same static name, a dynamic name matches a dynamic
+// name with the same (syntactically) expression and an end tag without
+// a name matches any open tag, except for the open tags of void elements,
+// so that it closes the element enclosing a void element.
+func tagsMatch(open *ast.OpenTag, end *ast.EndTag) bool {
+	switch {
+	case end.Name != nil:
+		return open.Name != nil && open.Name.Name == end.Name.Name
+	case end.DynamicName != nil:
+		return open.DynamicName != nil &&
+			ast.Equal(open.DynamicName.X, end.DynamicName.X, ast.IgnorePositions|ast.IgnoreComments)
+	default:
+		return !isVoidElement(open)
 	}
+}
 
-	if last == 0 {
-		out = list
+// isVoidElement reports whether openTag is the open tag of a void
+// element (e.g. <br> or <img>), which has no end tag.
+func isVoidElement(openTag *ast.OpenTag) bool {
+	if openTag.Name == nil {
+		return false
+	}
+	switch strings.ToLower(openTag.Name.Name) {
+	case "area", "base", "br", "col", "embed", "hr", "img", "input", "link", "meta", "source", "track", "wbr":
+		return true
+	}
+	return false
+}
+
+// endTagName returns the name of end for use in error messages.
+func endTagName(end *ast.EndTag) string {
+	switch {
+	case end.Name != nil:
+		return end.Name.Name
+	case end.DynamicName != nil:
+		return `\{...}`
+	default:
+		return "</>"
+	}
+}
+
+// checkUnclosedTag reports an error for an open tag without
+// a matching end tag, unless it is a void element.
+func (p *parser) checkUnclosedTag(openTag *ast.OpenTag) {
+	if isVoidElement(openTag) {
 		return
 	}
+	if openTag.Name != nil || openTag.DynamicName != nil {
+		p.error(openTag.OpenPos, "unclosed tag")
+	}
+}
 
-	for _, v := range openTagDepth {
-		openTagStmt := list[v.openTagIndex]
-		_, openTag := unlabelAs[*ast.OpenTag](openTagStmt)
-		if openTag.Name != nil {
-			if openTag.Name.Name != "br" {
-				p.error(openTag.OpenPos, "unclosed tag")
-			}
-		}
-		out = append(out, openTagStmt)
-		out = append(out, v.body...)
+// parseTagName parses a static (div) or a dynamic (\{x}) tag name.
+func (p *parser) parseTagName() (*ast.Ident, *ast.TemplateLiteralPart) {
+	if p.tok != token.TEMPLATE_LBRACE {
+		return p.parseIdent(), nil
+	}
+	lBracePos := p.pos + 1
+	p.next()
+	x := p.parseExpr()
+	rBracePos := p.expect(token.RBRACE)
+	return nil, &ast.TemplateLiteralPart{
+		LBrace: lBracePos,
+		X:      x,
+		RBrace: rBracePos,
+	}
+}
+
+// checkTgoSyntax reports an error at pos when tgo syntax is not allowed.
+func (p *parser) checkTgoSyntax(pos token.Pos) {
+	if p.mode&GoOnly != 0 {
+		p.error(pos, "tgo syntax in .go file")
 	}
-	out = append(out, list[last:]...)
-	return
 }
 
 func (p *parser) nextTgoTemplate() {
@@ -141,6 +208,7 @@ func (p *parser) parseTgoOpenTag() *ast.OpenTag {
 		panic("unreachable")
 	}
 	openPos := p.pos
+	p.checkTgoSyntax(openPos)
 	p.next()
 
 	if p.tok == token.RBRACE || p.tok == token.CASE || p.tok == token.DEFAULT ||
@@ -149,7 +217,7 @@ func (p *parser) parseTgoOpenTag() *ast.OpenTag {
 		return &ast.OpenTag{OpenPos: openPos}
 	}
 
-	ident := p.parseIdent()
+	ident, dynamicName := p.parseTagName()
 
 	if p.tok != token.AT && p.tok != token.GTR {
 		p.expectSemi()
@@ -157,7 +225,7 @@ func (p *parser) parseTgoOpenTag() *ast.OpenTag {
 
 	if p.tok == token.RBRACE || p.tok == token.END_TAG || p.tok == token.LSS {
 		p.errorExpected(p.pos, "'"+token.GTR.String()+"'")
-		return &ast.OpenTag{OpenPos: openPos, Name: ident}
+		return &ast.OpenTag{OpenPos: openPos, Name: ident, DynamicName: dynamicName}
 	}
 
 	body := p.parseTagStmtList()
@@ -181,10 +249,11 @@ func (p *parser) parseTgoOpenTag() *ast.OpenTag {
 	}
 
 	return &ast.OpenTag{
-		OpenPos:  openPos,
-		Name:     ident,
-		Body:     body,
-		ClosePos: closePos,
+		OpenPos:     openPos,
+		Name:        ident,
+		DynamicName: dynamicName,
+		Body:        body,
+		ClosePos:    closePos,
 	}
 }
 
@@ -193,6 +262,7 @@ func (p *parser) parseTgoCloseTag() *ast.EndTag {
 		panic("unreachable")
 	}
 	openPos := p.pos
+	p.checkTgoSyntax(openPos)
 	p.next()
 
 	if p.tok == token.RBRACE || p.tok == token.CASE || p.tok == token.DEFAULT ||
@@ -201,15 +271,21 @@ func (p *parser) parseTgoCloseTag() *ast.EndTag {
 		return &ast.EndTag{OpenPos: openPos}
 	}
 
-	ident := p.parseIdent()
+	var (
+		ident       *ast.Ident
+		dynamicName *ast.TemplateLiteralPart
+	)
+	if p.tok != token.GTR {
+		ident, dynamicName = p.parseTagName()
 
-	if p.tok != token.AT && p.tok != token.GTR {
-		p.expectSemi()
-	}
+		if p.tok != token.AT && p.tok != token.GTR {
+			p.expectSemi()
+		}
 
-	if p.tok == token.RBRACE || p.tok == token.END_TAG || p.tok == token.LSS {
-		p.errorExpected(p.pos, "'"+token.GTR.String()+"'")
-		return &ast.EndTag{OpenPos: openPos, Name: ident}
+		if p.tok == token.RBRACE || p.tok == token.END_TAG || p.tok == token.LSS {
+			p.errorExpected(p.pos, "'"+token.GTR.String()+"'")
+			return &ast.EndTag{OpenPos: openPos, Name: ident, DynamicName: dynamicName}
+		}
 	}
 
 	p.scanner.AllowInsertSemiAfterGTR()
@@ -218,9 +294,10 @@ func (p *parser) parseTgoCloseTag() *ast.EndTag {
 		p.expectSemi()
 	}
 	return &ast.EndTag{
-		OpenPos:  openPos,
-		Name:     ident,
-		ClosePos: closePos,
+		OpenPos:     openPos,
+		Name:        ident,
+		DynamicName: dynamicName,
+		ClosePos:    closePos,
 	}
 }
 
@@ -242,6 +319,7 @@ func (p *parser) parseTgoStmt() (s ast.Stmt) {
 		return &ast.ExprStmt{X: lit}
 	case token.AT:
 		startPos := p.pos
+		p.checkTgoSyntax(startPos)
 
 		p.next()
 		ident := p.parseIdent()
@@ -315,18 +393,6 @@ func (p *parser) parseTagStmtList() (list []ast.Stmt) {
 	return
 }
 
-func (p *parser) parseElementBlockStmtList() (list []ast.Stmt) {
-	if p.trace {
-		defer un(trace(p, "TagStatementList"))
-	}
-
-	for p.tok != token.CASE && p.tok != token.DEFAULT && p.tok != token.END_TAG && p.tok != token.RBRACE && p.tok != token.EOF {
-		list = append(list, p.parseStmt())
-	}
-
-	return
-}
-
 func (p *parser) parseTemplateLiteral() *ast.TemplateLiteralExpr {
 	var (
 		startPos = p.pos
@@ -336,14 +402,21 @@ func (p *parser) parseTemplateLiteral() *ast.TemplateLiteralExpr {
 		closePos token.Pos
 	)
 
+	p.checkTgoSyntax(startPos)
+
 	for {
 		lBracePos := token.Pos(int(p.pos) + len(p.lit) + 1)
 		p.next()
-		parts = append(parts, &ast.TemplateLiteralPart{
+		part := &ast.TemplateLiteralPart{
 			LBrace: lBracePos,
 			X:      p.parseExpr(),
-			RBrace: p.pos,
-		})
+		}
+		if p.tok == token.COLON {
+			part.Colon = p.pos
+			part.Format = p.parseTemplateLiteralFormat()
+		}
+		part.RBrace = p.pos
+		parts = append(parts, part)
 		if p.tok != token.RBRACE {
 			p.errorExpected(p.pos, "'"+token.RBRACE.String()+"'")
 		}
@@ -363,6 +436,17 @@ func (p *parser) parseTemplateLiteral() *ast.TemplateLiteralExpr {
 	}
 }
 
+// parseTemplateLiteralFormat parses the format that follows
+// the ":" in a template literal part, p.tok must be token.COLON.
+func (p *parser) parseTemplateLiteralFormat() ast.Expr {
+	if pos, verb := p.scanner.TemplateLiteralFormatVerb(); verb != "" {
+		p.next()
+		return &ast.FormatVerb{VerbPos: pos, Verb: verb}
+	}
+	p.next()
+	return p.parseExpr()
+}
+
 func (p *parser) expectSemiAllowEndTag() (comment *ast.CommentGroup) {
 	if p.tok != token.END_TAG {
 		return p.expectSemi()
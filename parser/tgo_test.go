@@ -2,10 +2,12 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
@@ -326,6 +328,29 @@ func TestTgoBasicSyntax(t *testing.T) {
 				},
 			},
 		},
+		{
+			in: `<\{tag}></>`,
+			out: []ast.Stmt{
+				&ast.ElementBlockStmt{
+					OpenTag: &ast.OpenTag{
+						OpenPos: off,
+						DynamicName: &ast.TemplateLiteralPart{
+							LBrace: off + 2,
+							X: &ast.Ident{
+								NamePos: off + 3,
+								Name:    "tag",
+							},
+							RBrace: off + 6,
+						},
+						ClosePos: off + 7,
+					},
+					EndTag: &ast.EndTag{
+						OpenPos:  off + 8,
+						ClosePos: off + 10,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range cases {
@@ -421,6 +446,233 @@ func TestTgoSyntax(t *testing.T) {
 
 }
 
+func TestTgoGoOnly(t *testing.T) {
+	const src = `package main
+
+func test() {
+	<div
+		@attr="value"
+	>
+		"\{sth}"
+	</div>
+}
+`
+	if _, err := ParseFile(token.NewFileSet(), "test.go", src, TgoSyntax); err != nil {
+		t.Fatalf("ParseFile(TgoSyntax) = %v; want = <nil>", err)
+	}
+
+	_, err := ParseFile(token.NewFileSet(), "test.go", src, GoOnly|AllErrors)
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		t.Fatalf("ParseFile(GoOnly) = %v; want = scanner.ErrorList", err)
+	}
+
+	var got []string
+	for _, err := range list {
+		if err.Msg != "tgo syntax in .go file" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		got = append(got, err.Pos.String())
+	}
+	want := []string{"test.go:4:2", "test.go:5:3", "test.go:7:3", "test.go:8:2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("error positions = %q; want = %q", got, want)
+	}
+}
+
+func TestTgoDynamicTagNameErrors(t *testing.T) {
+	cases := []struct {
+		in   string
+		errs []string
+	}{
+		{in: `<\{a}></\{a}>`},
+		{in: `<\{a[i]}></\{a[ i ]}>`},
+		{in: `<\{a}></>`},
+		{in: `<div><\{a}></></>`},
+		{in: `<\{a}></\{b}>`, errs: []string{"test.go:2:14: unclosed tag", "test.go:2:20: unopenend tag: \\{...}"}},
+		{in: `<\{a}></div>`, errs: []string{"test.go:2:14: unclosed tag", "test.go:2:20: unopenend tag: div"}},
+		{in: `<div></\{a}>`, errs: []string{"test.go:2:14: unclosed tag", "test.go:2:19: unopenend tag: \\{...}"}},
+		{in: `</>`, errs: []string{"test.go:2:14: unopenend tag: </>"}},
+		{in: `<\{a}>`, errs: []string{"test.go:2:14: unclosed tag"}},
+		{in: `<br></>`, errs: []string{"test.go:2:18: unopenend tag: </>"}},
+		{in: `<div><br></br></div>`, errs: []string{"test.go:2:23: end tag of void element: br"}},
+	}
+
+	for _, tt := range cases {
+		src := "package main\nfunc test() {" + tt.in + "}"
+		_, err := ParseFile(token.NewFileSet(), "test.go", src, AllErrors)
+		var got []string
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, err := range list {
+				got = append(got, err.Error())
+			}
+		} else if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.in, err)
+		}
+		if !slices.Equal(got, tt.errs) {
+			t.Errorf("%v: errors = %q; want = %q", tt.in, got, tt.errs)
+		}
+	}
+}
+
+func TestTgoElementRecovery(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string // the statements of the body, elements as <name>[body]
+		errs []string
+	}{
+		{in: `<div><span></div>`, want: `<div>[*ast.OpenTag]`, errs: []string{"test.go:2:19: unclosed tag"}},
+		{in: `<div><br><p></p></div>`, want: `<div>[*ast.OpenTag <p>[]]`},
+		{in: `<div><br>"x"</>`, want: `<div>[*ast.OpenTag *ast.ExprStmt]`},
+		{in: `<div><br>"x"</></div>`, want: `<div>[*ast.OpenTag *ast.ExprStmt] *ast.EndTag`, errs: []string{"test.go:2:29: unopenend tag: div"}},
+		{in: "<div>\n{\n</div>\n}\n</div>", want: `<div>[*ast.BlockStmt]`, errs: []string{"test.go:4:1: unopenend tag: div"}},
+		{in: `<div><a><b></a></div>`, want: `<div>[<a>[*ast.OpenTag]]`, errs: []string{"test.go:2:22: unclosed tag"}},
+		{in: "L: <div>\nM: </div>", want: `*ast.LabeledStmt`},
+		{in: "<div>\nM: </div>", want: `<div>[*ast.LabeledStmt]`},
+		{in: `<div><p>`, want: `*ast.OpenTag *ast.OpenTag`, errs: []string{"test.go:2:14: unclosed tag", "test.go:2:19: unclosed tag"}},
+	}
+
+	var dump func(list []ast.Stmt) string
+	dump = func(list []ast.Stmt) string {
+		var out []string
+		for _, s := range list {
+			if e, ok := s.(*ast.ElementBlockStmt); ok {
+				out = append(out, "<"+e.OpenTag.Name.Name+">["+dump(e.Body)+"]")
+				continue
+			}
+			out = append(out, fmt.Sprintf("%T", s))
+		}
+		return strings.Join(out, " ")
+	}
+
+	for _, tt := range cases {
+		src := "package main\nfunc test() {" + tt.in + "}"
+		f, err := ParseFile(token.NewFileSet(), "test.go", src, AllErrors)
+		var got []string
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, err := range list {
+				got = append(got, err.Error())
+			}
+		} else if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.in, err)
+		}
+		if !slices.Equal(got, tt.errs) {
+			t.Errorf("%v: errors = %q; want = %q", tt.in, got, tt.errs)
+		}
+		body := f.Decls[0].(*ast.FuncDecl).Body.List
+		if got := dump(body); got != tt.want {
+			t.Errorf("%v: body = %v; want = %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTgoParseStmtListFrom(t *testing.T) {
+	const src = "x := 1\n<div @id=\"\\{x}\">\n\t\"a\"\n</div>"
+	fset := token.NewFileSet()
+	fset.AddFile("other.tgo", -1, 100)
+	file := fset.AddFile("frag.tgo", -1, len(src))
+
+	list, err := ParseStmtListFrom(file, src, 0)
+	if err != nil {
+		t.Fatalf("ParseStmtListFrom() = %v; want = <nil>", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("len(list) = %v; want = 2", len(list))
+	}
+	e, ok := list[1].(*ast.ElementBlockStmt)
+	if !ok {
+		t.Fatalf("list[1] = %T; want = *ast.ElementBlockStmt", list[1])
+	}
+	if got, want := fset.Position(e.Pos()).String(), "frag.tgo:2:1"; got != want {
+		t.Errorf("element position = %v; want = %v", got, want)
+	}
+	if got, want := fset.Position(e.End()).String(), "frag.tgo:4:7"; got != want {
+		t.Errorf("element end position = %v; want = %v", got, want)
+	}
+	if got, want := fset.Position(e.Body[0].Pos()).String(), "frag.tgo:3:2"; got != want {
+		t.Errorf("text position = %v; want = %v", got, want)
+	}
+
+	const bad = "a := 1\n<div>\n}"
+	file = fset.AddFile("bad.tgo", -1, len(bad))
+	_, err = ParseStmtListFrom(file, bad, AllErrors)
+	list2, ok := err.(scanner.ErrorList)
+	if !ok || len(list2) != 2 {
+		t.Fatalf("ParseStmtListFrom() = %v; want = 2 errors", err)
+	}
+	if got, want := list2[0].Error(), "bad.tgo:2:1: unclosed tag"; got != want {
+		t.Errorf("errors[0] = %v; want = %v", got, want)
+	}
+	if got, want := list2[1].Pos.Offset, len(bad)-1; got != want {
+		t.Errorf("errors[1].Pos.Offset = %v; want = %v", got, want)
+	}
+}
+
+func TestTgoParseMarkupFrom(t *testing.T) {
+	cases := []struct {
+		in   string
+		errs []string
+	}{
+		{in: `<a @href="/users/\{id}">"\{name}"</a>`},
+		{in: "<ul>\n<li>\"a\"</li>\n<li @class=\"b\">`b`</li>\n</ul>"},
+		{in: "<p>\n\"a\"\n<br>\n\"b\"\n</p>"},
+		{in: "<p>\n\"a\" + \"b\"\n</p>", errs: []string{"m.tgo:2:1: expected markup, found statement"}},
+		{in: "<div>\nif x {\n}\n</div>", errs: []string{"m.tgo:2:1: expected markup, found statement"}},
+		{in: "x := 1", errs: []string{"m.tgo:1:1: expected markup, found statement"}},
+		{in: "L: <div>\n</div>", errs: []string{"m.tgo:1:1: expected markup, found statement"}},
+	}
+	for _, tt := range cases {
+		fset := token.NewFileSet()
+		file := fset.AddFile("m.tgo", -1, len(tt.in))
+		_, err := ParseMarkupFrom(file, tt.in, AllErrors|GoOnly)
+		var got []string
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, err := range list {
+				got = append(got, err.Error())
+			}
+		} else if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.in, err)
+		}
+		if !slices.Equal(got, tt.errs) {
+			t.Errorf("%v: errors = %q; want = %q", tt.in, got, tt.errs)
+		}
+	}
+}
+
+func TestTgoParseDirAutoMode(t *testing.T) {
+	const tgoSrc = `package main
+
+func test() {
+	<div></div>
+}
+`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.tgo"), []byte(tgoSrc), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	pkgs, err := ParseDir(token.NewFileSet(), dir, nil, 0)
+	if err != nil {
+		t.Fatalf("ParseDir() = %v; want = <nil>", err)
+	}
+	if n := len(pkgs["main"].Files); n != 1 {
+		t.Fatalf("got %v package files; want = 1", n)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte(tgoSrc), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseDir(token.NewFileSet(), dir, nil, 0)
+	if err == nil || !strings.Contains(err.Error(), "b.go:4:2: tgo syntax in .go file") {
+		t.Errorf("ParseDir() = %v; want = tgo syntax error in b.go", err)
+	}
+
+	if _, err := ParseDir(token.NewFileSet(), dir, nil, TgoSyntax); err != nil {
+		t.Errorf("ParseDir(TgoSyntax) = %v; want = <nil>", err)
+	}
+}
+
 func fuzzAddDir(f *testing.F, testdata string) {
 	files, err := os.ReadDir(testdata)
 	if err != nil {
@@ -1084,6 +1084,8 @@ func (p *printer) expr1(expr ast.Expr, prec1, depth int) {
 		p.expr(x.Value)
 	case *ast.TemplateLiteralExpr:
 		p.templateLiteralExpr(x)
+	case *ast.FormatVerb:
+		p.print(x.Verb)
 	default:
 		panic("unreachable")
 	}
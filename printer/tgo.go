@@ -68,16 +68,16 @@ func (p *printer) opentag(b *ast.OpenTag) {
 	p.setPos(b.OpenPos)
 	p.print(token.LSS)
 
-	forceNewline := p.tagForceNewline(b.OpenPos, b.Name.NamePos, b.Name.End(), b.ClosePos, b.Body)
+	namePos, nameEnd := tagNameRange(b.Name, b.DynamicName, b.OpenPos+token.Pos(len("<")))
+	forceNewline := p.tagForceNewline(b.OpenPos, namePos, nameEnd, b.ClosePos, b.Body)
 
 	if forceNewline {
 		p.print(indent)
-		p.linebreak(p.lineFor(b.Name.NamePos), 1, ignore, false)
+		p.linebreak(p.lineFor(namePos), 1, ignore, false)
 	}
-	p.setPos(b.Name.NamePos)
-	p.print(b.Name)
+	p.tagName(b.Name, b.DynamicName)
 	if forceNewline && isEmptyBody(b.Body) {
-		p.linebreak(p.lineFor(b.Name.NamePos), 1, ignore, false)
+		p.linebreak(p.lineFor(namePos), 1, ignore, false)
 	}
 
 	if !forceNewline {
@@ -105,15 +105,15 @@ func (p *printer) endtag(b *ast.EndTag) {
 	p.setPos(b.OpenPos)
 	p.print(token.END_TAG)
 
-	forceNewline := p.tagForceNewline(b.OpenPos, b.Name.NamePos, b.Name.End(), b.ClosePos, nil)
+	namePos, nameEnd := tagNameRange(b.Name, b.DynamicName, b.OpenPos+token.Pos(len("</")))
+	forceNewline := p.tagForceNewline(b.OpenPos, namePos, nameEnd, b.ClosePos, nil)
 
 	if forceNewline {
 		p.print(indent)
-		p.linebreak(p.lineFor(b.Name.NamePos), 1, ignore, false)
+		p.linebreak(p.lineFor(namePos), 1, ignore, false)
 	}
 
-	p.setPos(b.Name.NamePos)
-	p.print(b.Name)
+	p.tagName(b.Name, b.DynamicName)
 	if forceNewline {
 		p.print(unindent)
 		p.linebreak(p.lineFor(b.ClosePos), 1, ignore, false)
@@ -129,6 +129,34 @@ func (p *printer) endtag(b *ast.EndTag) {
 	p.inEndTag = false
 }
 
+// tagNameRange returns the start and end positions of a tag name,
+// for tags without a name (</>) both are noName.
+func tagNameRange(name *ast.Ident, dynamicName *ast.TemplateLiteralPart, noName token.Pos) (token.Pos, token.Pos) {
+	switch {
+	case name != nil:
+		return name.Pos(), name.End()
+	case dynamicName != nil:
+		return dynamicName.LBrace - 1, dynamicName.End()
+	default:
+		return noName, noName
+	}
+}
+
+func (p *printer) tagName(name *ast.Ident, dynamicName *ast.TemplateLiteralPart) {
+	switch {
+	case name != nil:
+		p.setPos(name.NamePos)
+		p.print(name)
+	case dynamicName != nil:
+		p.setPos(dynamicName.LBrace - 1)
+		p.print("\\", token.LBRACE)
+		p.setPos(dynamicName.LBrace)
+		p.expr(stripParensAlways(dynamicName.X))
+		p.setPos(dynamicName.RBrace)
+		p.print(noExtraLinebreak|noExtraBlank, token.RBRACE, noExtraLinebreak|noExtraBlank)
+	}
+}
+
 func (p *printer) attr(a *ast.AttributeStmt) {
 	p.setPos(a.StartPos)
 	p.print(token.AT)
@@ -151,6 +179,12 @@ func (p *printer) templateLiteralExpr(x *ast.TemplateLiteralExpr) {
 		p.print("\\", token.LBRACE)
 		p.setPos(x.Parts[i].LBrace)
 		p.expr(stripParensAlways(x.Parts[i].X))
+		if f := x.Parts[i].Format; f != nil {
+			p.setPos(x.Parts[i].Colon)
+			p.print(token.COLON)
+			p.setPos(f.Pos())
+			p.expr(f)
+		}
 		p.setPos(x.Parts[i].RBrace)
 		if p.mode&noExtraLinebreak != 0 || p.mode&noExtraBlank != 0 {
 			panic("unreachable")
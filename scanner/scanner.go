@@ -109,6 +109,7 @@ type Mode uint
 const (
 	ScanComments    Mode = 1 << iota // return comments as COMMENT tokens
 	dontInsertSemis                  // do not automatically insert semicolons - for testing only
+	GoOnly                           // do not recognize tgo tokens (END_TAG, AT, STRING_TEMPLATE, TEMPLATE_LBRACE)
 )
 
 // Init prepares the scanner s to tokenize the text src by setting the
@@ -121,7 +122,7 @@ const (
 // Calls to [Scanner.Scan] will invoke the error handler err if they encounter a
 // syntax error and err is not nil. Also, for each error encountered,
 // the [Scanner] field ErrorCount is incremented by one. The mode parameter
-// determines how comments are handled.
+// determines how comments are handled and whether tgo tokens are recognized.
 //
 // Note that Init may call err if there is an error in the first character
 // of the file.
@@ -665,7 +666,7 @@ func (s *Scanner) scanString() (token.Token, string) {
 			break
 		}
 		if ch == '\\' {
-			if s.ch == '{' {
+			if s.ch == '{' && s.mode&GoOnly == 0 {
 				s.next()
 				return token.STRING_TEMPLATE, string(s.src[offs : s.offset-2])
 			}
@@ -938,7 +939,7 @@ scanAgain:
 			if s.ch == '-' {
 				s.next()
 				tok = token.ARROW
-			} else if s.ch == '/' && s.peek() != '/' && s.peek() != '*' {
+			} else if s.ch == '/' && s.peek() != '/' && s.peek() != '*' && s.mode&GoOnly == 0 {
 				s.next()
 				tok = token.END_TAG
 			} else {
@@ -961,8 +962,19 @@ scanAgain:
 			tok = s.switch3(token.OR, token.OR_ASSIGN, '|', token.LOR)
 		case '~':
 			tok = token.TILDE
-		case '@':
-			tok = token.AT
+		case '@', '\\':
+			if s.mode&GoOnly == 0 {
+				if ch == '@' {
+					tok = token.AT
+					break
+				}
+				if s.ch == '{' {
+					s.next()
+					tok = token.TEMPLATE_LBRACE
+					break
+				}
+			}
+			fallthrough
 		default:
 			// next reports unexpected BOMs - don't repeat
 			if ch != bom {
@@ -1000,6 +1012,37 @@ func (s *Scanner) TemplateLiteralContinue() (pos token.Pos, tok token.Token, lit
 	return
 }
 
+// TemplateLiteralFormatVerb scans a printf-style format verb (%.2f)
+// that follows the ":" in a template literal part (\{x:%.2f}). When the
+// next character (after white space) is not "%", the verb is empty and
+// the following token should be scanned with [Scanner.Scan].
+func (s *Scanner) TemplateLiteralFormatVerb() (pos token.Pos, verb string) {
+	s.skipWhitespace()
+	if s.ch != '%' {
+		return token.NoPos, ""
+	}
+	offs := s.offset
+	s.next()
+	for s.ch == '+' || s.ch == '-' || s.ch == '#' || s.ch == ' ' || s.ch == '0' {
+		s.next()
+	}
+	for isDecimal(s.ch) {
+		s.next()
+	}
+	if s.ch == '.' {
+		s.next()
+		for isDecimal(s.ch) {
+			s.next()
+		}
+	}
+	if 'a' <= lower(s.ch) && lower(s.ch) <= 'z' {
+		s.next()
+	} else {
+		s.error(offs, "invalid format verb")
+	}
+	return s.file.Pos(offs), string(s.src[offs:s.offset])
+}
+
 func (s *Scanner) AllowInsertSemiAfterGTR() {
 	s.allowInsertSemiAfterGTR = true
 }
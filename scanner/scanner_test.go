@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
@@ -1200,3 +1201,84 @@ func TestTempalteLitearalSemiInsertion(t *testing.T) {
 	wantNextToken(true, token.STRING, `"`)
 	wantNextToken(false, token.SEMICOLON, "\n")
 }
+
+func TestGoOnly(t *testing.T) {
+	const src = `</div> @attr "\{a}"`
+	var s Scanner
+	fs := token.NewFileSet()
+	var errs []string
+	eh := func(_ token.Position, msg string) { errs = append(errs, msg) }
+	s.Init(fs.AddFile("test", fs.Base(), len(src)), []byte(src), eh, GoOnly)
+
+	want := []token.Token{
+		token.LSS, token.QUO, token.IDENT, token.GTR,
+		token.ILLEGAL, token.IDENT, token.STRING, token.SEMICOLON, token.EOF,
+	}
+	for _, wantTok := range want {
+		if _, tok, lit := s.Scan(); tok != wantTok {
+			t.Errorf("s.Scan() = (_, %v, %q); want = (_, %v, _)", tok, lit, wantTok)
+		}
+	}
+
+	wantErrs := []string{"illegal character U+0040 '@'", "unknown escape sequence"}
+	if !slices.Equal(errs, wantErrs) {
+		t.Errorf("errors = %q; want = %q", errs, wantErrs)
+	}
+}
+
+func TestTemplateLBrace(t *testing.T) {
+	const src = `<\{a}></> \{`
+	for _, tt := range []struct {
+		mode Mode
+		want []token.Token
+	}{
+		{0, []token.Token{
+			token.LSS, token.TEMPLATE_LBRACE, token.IDENT, token.RBRACE, token.GTR,
+			token.END_TAG, token.GTR, token.TEMPLATE_LBRACE, token.EOF,
+		}},
+		{GoOnly, []token.Token{
+			token.LSS, token.ILLEGAL, token.LBRACE, token.IDENT, token.RBRACE, token.GTR,
+			token.LSS, token.QUO, token.GTR, token.ILLEGAL, token.LBRACE, token.EOF,
+		}},
+	} {
+		var s Scanner
+		fs := token.NewFileSet()
+		s.Init(fs.AddFile("test", fs.Base(), len(src)), []byte(src), func(token.Position, string) {}, tt.mode|dontInsertSemis)
+		for _, wantTok := range tt.want {
+			if _, tok, lit := s.Scan(); tok != wantTok {
+				t.Errorf("mode %v: s.Scan() = (_, %v, %q); want = (_, %v, _)", tt.mode, tok, lit, wantTok)
+			}
+		}
+	}
+}
+
+func TestTemplateLiteralFormatVerb(t *testing.T) {
+	for _, tt := range []struct {
+		src, verb, err string
+	}{
+		{src: "%d}", verb: "%d"},
+		{src: " %-08.3f}", verb: "%-08.3f"},
+		{src: "%#x}", verb: "%#x"},
+		{src: "layout}"},
+		{src: "%}", verb: "%", err: "invalid format verb"},
+		{src: "%5}", verb: "%5", err: "invalid format verb"},
+	} {
+		var s Scanner
+		var errs []string
+		fs := token.NewFileSet()
+		eh := func(_ token.Position, msg string) { errs = append(errs, msg) }
+		s.Init(fs.AddFile("test", fs.Base(), len(tt.src)), []byte(tt.src), eh, 0)
+		_, verb := s.TemplateLiteralFormatVerb()
+		if verb != tt.verb {
+			t.Errorf("%q: TemplateLiteralFormatVerb() = %q; want = %q", tt.src, verb, tt.verb)
+		}
+		if tt.err == "" && len(errs) != 0 || tt.err != "" && !slices.Equal(errs, []string{tt.err}) {
+			t.Errorf("%q: errors = %q; want = %q", tt.src, errs, tt.err)
+		}
+		if tt.verb != "" {
+			if _, tok, _ := s.Scan(); tok != token.RBRACE {
+				t.Errorf("%q: s.Scan() = %v; want = %v", tt.src, tok, token.RBRACE)
+			}
+		}
+	}
+}
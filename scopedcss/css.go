@@ -0,0 +1,336 @@
+package scopedcss
+
+import (
+	"slices"
+	"strings"
+)
+
+// A selector is a complex selector of a style sheet.
+type selector struct {
+	text   string // e.g. "ul > li.active:hover"
+	offset int    // byte offset in the style sheet
+}
+
+// groupingRules are the at-rules holding rules, whose selectors
+// are scoped. The blocks of the other at-rules (e.g. @keyframes
+// and @font-face) are kept unchanged.
+var groupingRules = []string{"container", "document", "layer", "media", "scope", "supports"}
+
+// A rewriter scopes the selectors of a style sheet with an attribute.
+type rewriter struct {
+	src  string
+	attr string
+	out  strings.Builder
+	sels []selector
+}
+
+// scopeCSS returns the style sheet css with the attribute selector
+// [attr] added to the last compound selector of each selector, and
+// the (original) selectors of the style sheet.
+func scopeCSS(css, attr string) (string, []selector) {
+	r := rewriter{src: css, attr: attr}
+	i := r.rules(0)
+	r.out.WriteString(css[i:])
+	return r.out.String(), r.sels
+}
+
+// rules rewrites the rules starting at offset i, up to the end of
+// the enclosing block or of the style sheet. It returns the offset
+// of the "}" of the enclosing block; or len(r.src).
+func (r *rewriter) rules(i int) int {
+	for i < len(r.src) {
+		j := r.scan(i, "{;}")
+		if j == len(r.src) || r.src[j] == '}' {
+			r.out.WriteString(r.src[i:j])
+			return j
+		}
+		if r.src[j] == ';' {
+			// A statement at-rule, e.g. @import.
+			r.out.WriteString(r.src[i : j+1])
+			i = j + 1
+			continue
+		}
+
+		prelude := r.src[i:j]
+		if name, ok := atRule(prelude); ok {
+			r.out.WriteString(r.src[i : j+1])
+			if !slices.Contains(groupingRules, name) {
+				end := r.blockEnd(j + 1)
+				r.out.WriteString(r.src[j+1 : end])
+				i = end
+				continue
+			}
+			i = r.rules(j + 1)
+			if i < len(r.src) {
+				r.out.WriteByte('}')
+				i++
+			}
+			continue
+		}
+
+		r.selectorList(i, prelude)
+		r.out.WriteByte('{')
+		end := r.blockEnd(j + 1)
+		r.out.WriteString(r.src[j+1 : end])
+		i = end
+	}
+	return i
+}
+
+// selectorList writes the selector list list at offset i,
+// with the selectors scoped.
+func (r *rewriter) selectorList(i int, list string) {
+	start := 0
+	for {
+		end := scanString(list, start, ",")
+		item := list[start:end]
+		lead := leadingSpace(item)
+		trimmed := strings.TrimSpace(item[lead:])
+		if trimmed == "" {
+			r.out.WriteString(item)
+		} else {
+			r.sels = append(r.sels, selector{text: trimmed, offset: i + start + lead})
+			r.out.WriteString(item[:lead])
+			r.out.WriteString(scopeSelector(trimmed, r.attr))
+			r.out.WriteString(item[lead+len(trimmed):])
+		}
+		if end == len(list) {
+			return
+		}
+		r.out.WriteByte(',')
+		start = end + 1
+	}
+}
+
+// leadingSpace returns the length of the white space
+// and comments at the start of s.
+func leadingSpace(s string) int {
+	i := 0
+	for i < len(s) {
+		switch {
+		case strings.IndexByte(" \t\r\n\f", s[i]) >= 0:
+			i++
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += 2 + end + 2
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scan returns the offset of the first of the characters chars at
+// the top level (outside of comments, strings, parentheses and
+// brackets) starting at offset i; or len(r.src).
+func (r *rewriter) scan(i int, chars string) int {
+	return scanString(r.src, i, chars)
+}
+
+// blockEnd returns the offset following the "}" that closes the block
+// starting at offset i; or len(r.src).
+func (r *rewriter) blockEnd(i int) int {
+	depth := 0
+	for i < len(r.src) {
+		i = r.scan(i, "{}")
+		if i == len(r.src) {
+			break
+		}
+		if r.src[i] == '}' {
+			if depth == 0 {
+				return i + 1
+			}
+			depth--
+		} else {
+			depth++
+		}
+		i++
+	}
+	return i
+}
+
+// scanString is like rewriter.scan for the string s.
+func scanString(s string, i int, chars string) int {
+	depth := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s)
+			}
+			i += 2 + end + 2
+			continue
+		case c == '"' || c == '\'':
+			i = stringEnd(s, i)
+			continue
+		case c == '\\':
+			i += 2
+			continue
+		case depth == 0 && strings.IndexByte(chars, c) >= 0:
+			return i
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		i++
+	}
+	return len(s)
+}
+
+// stringEnd returns the offset following the CSS string starting at
+// offset i; or len(s).
+func stringEnd(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote, '\n':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// atRule returns the name of the at-rule with the prelude p.
+func atRule(p string) (string, bool) {
+	p = strings.TrimSpace(stripComments(p))
+	if !strings.HasPrefix(p, "@") {
+		return "", false
+	}
+	name := p[1:]
+	if i := strings.IndexFunc(name, func(r rune) bool { return !isNameChar(r) }); i >= 0 {
+		name = name[:i]
+	}
+	// Vendor prefixes, e.g. @-webkit-keyframes.
+	if strings.HasPrefix(name, "-") {
+		if i := strings.Index(name[1:], "-"); i >= 0 {
+			name = name[i+2:]
+		}
+	}
+	return strings.ToLower(name), true
+}
+
+// stripComments returns s without its comments.
+func stripComments(s string) string {
+	for {
+		i := strings.Index(s, "/*")
+		if i < 0 {
+			return s
+		}
+		end := strings.Index(s[i+2:], "*/")
+		if end < 0 {
+			return s[:i]
+		}
+		s = s[:i] + " " + s[i+2+end+2:]
+	}
+}
+
+// scopeSelector adds the attribute selector [attr] to the last compound
+// selector of sel, before its pseudo-classes and pseudo-elements.
+func scopeSelector(sel, attr string) string {
+	compounds := compoundSelectors(sel)
+	last := compounds[len(compounds)-1]
+	at := last.offset + scanString(last.text, 0, ":")
+	return sel[:at] + "[" + attr + "]" + sel[at:]
+}
+
+// compoundSelectors returns the compound selectors of the complex
+// selector sel, i.e. the parts of sel between the combinators.
+func compoundSelectors(sel string) []selector {
+	var list []selector
+	for i := 0; i < len(sel); {
+		j := scanString(sel, i, " \t\r\n\f>+~")
+		if j > i {
+			list = append(list, selector{text: sel[i:j], offset: i})
+		}
+		i = j + 1
+	}
+	if len(list) == 0 {
+		list = append(list, selector{text: sel})
+	}
+	return list
+}
+
+// A compound describes the simple selectors of a compound selector,
+// the pseudo-classes and pseudo-elements are ignored.
+type compound struct {
+	tag     string // type selector; or "" (any element)
+	id      string
+	classes []string
+	attrs   []string
+}
+
+// parseCompound returns the simple selectors of the compound selector s.
+func parseCompound(s string) compound {
+	var c compound
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '#', '.':
+			name := nameAt(s, i+1)
+			if s[i] == '#' {
+				c.id = name
+			} else {
+				c.classes = append(c.classes, name)
+			}
+			i += 1 + len(name)
+		case '[':
+			end := scanString(s, i+1, "]")
+			name := strings.TrimSpace(s[i+1 : end])
+			if j := strings.IndexAny(name, "=~|^$* \t"); j >= 0 {
+				name = name[:j]
+			}
+			c.attrs = append(c.attrs, strings.ToLower(name))
+			i = end + 1
+		case ':':
+			// Pseudo-classes and pseudo-elements, with their arguments.
+			i++
+			if i < len(s) && s[i] == ':' {
+				i++
+			}
+			i += len(nameAt(s, i))
+			if i < len(s) && s[i] == '(' {
+				i = scanString(s, i+1, ")") + 1
+			}
+		case '*', '&':
+			i++
+		default:
+			name := nameAt(s, i)
+			if name == "" {
+				i++
+				continue
+			}
+			c.tag = strings.ToLower(name)
+			i += len(name)
+		}
+	}
+	return c
+}
+
+// nameAt returns the CSS name (identifier) at offset i of s.
+func nameAt(s string, i int) string {
+	j := i
+	for j < len(s) {
+		if s[j] == '\\' && j+1 < len(s) {
+			j += 2
+			continue
+		}
+		if !isNameChar(rune(s[j])) && s[j] < 0x80 {
+			break
+		}
+		j++
+	}
+	return s[i:j]
+}
+
+func isNameChar(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_' || r >= 0x80
+}
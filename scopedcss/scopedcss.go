@@ -0,0 +1,310 @@
+// Package scopedcss scopes the styles of tgo components to their markup.
+//
+// A component is a function with a <style> element, e.g.
+//
+//	func Card(ctx tgo.Ctx, title string) error {
+//		<style>
+//			`.card h2 { color: red; }`
+//		</style>
+//		<div @class="card">
+//			<h2>"\{title}"</h2>
+//		</div>
+//		return nil
+//	}
+//
+// [Rewrite] adds an attribute with a name derived from a hash of the
+// import path of the package and of the function name (e.g. @tgo1a2b3c4d),
+// so that it is unique across the packages of a program, to every other open tag
+// of the function, including the open tags of its function literals, and
+// adds the matching attribute selector to the selectors of the style:
+//
+//	.card h2[tgo1a2b3c4d] { color: red; }
+//
+// so that the style only applies to the elements of the component (and
+// not to the elements of the components it calls). The style is the text
+// of the string and raw string literals in the body of the <style>
+// element, each holding complete rules; template literals cannot be
+// scoped.
+package scopedcss
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+)
+
+// A Component is a function with a scoped style.
+type Component struct {
+	Func   *ast.FuncDecl
+	Attr   string                  // name of the scoping attribute, e.g. tgo1a2b3c4d
+	Styles []*ast.ElementBlockStmt // the <style> elements of Func
+}
+
+// A Diagnostic is a problem found in a style.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Rewrite scopes the styles of the components declared in the files of
+// the package with the import path importPath, the files are modified in
+// place. It returns the components and the diagnostics: the selectors that
+// match no element of their components and the styles that cannot be
+// scoped.
+func Rewrite(files []*ast.File, importPath string) ([]*Component, []Diagnostic) {
+	var (
+		comps []*Component
+		diags []Diagnostic
+	)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			styles := styleElements(fn.Body)
+			if len(styles) == 0 {
+				continue
+			}
+			c := &Component{
+				Func:   fn,
+				Attr:   Attr(importPath, funcName(fn)),
+				Styles: styles,
+			}
+			comps = append(comps, c)
+			diags = append(diags, c.rewrite()...)
+		}
+	}
+	return comps, diags
+}
+
+// Attr returns the name of the scoping attribute of the function
+// funcName (e.g. "Card" or "T.Render") of the package with the import
+// path importPath.
+func Attr(importPath, funcName string) string {
+	h := fnv.New32a()
+	h.Write([]byte(importPath + "." + funcName))
+	return fmt.Sprintf("tgo%08x", h.Sum32())
+}
+
+// funcName returns the name of the function or
+// method fn, e.g. "Card" or "T.Render".
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	t := fn.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+			continue
+		case *ast.IndexExpr:
+			t = x.X
+			continue
+		case *ast.IndexListExpr:
+			t = x.X
+			continue
+		case *ast.ParenExpr:
+			t = x.X
+			continue
+		case *ast.Ident:
+			return x.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
+
+// styleElements returns the <style> elements of body.
+func styleElements(body *ast.BlockStmt) []*ast.ElementBlockStmt {
+	var list []*ast.ElementBlockStmt
+	ast.Inspect(body, func(n ast.Node) bool {
+		if e, ok := n.(*ast.ElementBlockStmt); ok && isStyle(e.OpenTag) {
+			list = append(list, e)
+			return false
+		}
+		return true
+	})
+	return list
+}
+
+func isStyle(tag *ast.OpenTag) bool {
+	return tag.Name != nil && strings.EqualFold(tag.Name.Name, "style")
+}
+
+// An element describes the open tag of an element of a component.
+type element struct {
+	tag      string // lower case; or "" for dynamic tag names
+	id       string
+	anyID    bool // dynamic id
+	classes  []string
+	anyClass bool // dynamic class
+	attrs    map[string]bool
+}
+
+// rewrite scopes the styles of c and adds the scoping attribute to
+// the open tags of c.
+func (c *Component) rewrite() []Diagnostic {
+	var elems []element
+	ast.Inspect(c.Func.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ElementBlockStmt:
+			if isStyle(n.OpenTag) {
+				return false
+			}
+		case *ast.OpenTag:
+			if !isStyle(n) {
+				elems = append(elems, c.addAttr(n))
+			}
+		}
+		return true
+	})
+
+	var diags []Diagnostic
+	for _, style := range c.Styles {
+		for _, s := range style.Body {
+			s, ok := s.(*ast.ExprStmt)
+			if !ok {
+				continue
+			}
+			switch x := s.X.(type) {
+			case *ast.BasicLit:
+				if x.Kind == token.STRING {
+					diags = append(diags, c.rewriteStyle(x, elems)...)
+				}
+			case *ast.TemplateLiteralExpr:
+				diags = append(diags, Diagnostic{x.Pos(), "style with template literal parts cannot be scoped"})
+			}
+		}
+	}
+	return diags
+}
+
+// addAttr adds the scoping attribute to the open tag,
+// it returns the element of the open tag.
+func (c *Component) addAttr(tag *ast.OpenTag) element {
+	e := element{attrs: make(map[string]bool)}
+	if tag.Name != nil {
+		e.tag = strings.ToLower(tag.Name.Name)
+	}
+	// The attributes might be nested in statements of the open tag,
+	// the open tags of its function literals are other elements.
+	ast.Inspect(tag, func(n ast.Node) bool {
+		if n, ok := n.(*ast.OpenTag); ok && n != tag {
+			return false
+		}
+		a, ok := n.(*ast.AttributeStmt)
+		if !ok {
+			return true
+		}
+		id, ok := a.AttrName.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		name := strings.ToLower(id.Name)
+		e.attrs[name] = true
+		if name != "class" && name != "id" {
+			return false
+		}
+		value, dynamic := "", false
+		switch v := a.Value.(type) {
+		case *ast.BasicLit:
+			value, _ = strconv.Unquote(v.Value)
+		case *ast.TemplateLiteralExpr:
+			dynamic = true
+		}
+		if name == "class" {
+			e.classes = append(e.classes, strings.Fields(value)...)
+			e.anyClass = e.anyClass || dynamic
+		} else {
+			e.id = value
+			e.anyID = dynamic
+		}
+		return false
+	})
+	if !e.attrs[strings.ToLower(c.Attr)] {
+		// The attribute is added at the end of the open tag, without
+		// positions, so that the printer keeps it on the line of the
+		// preceding attribute.
+		tag.Body = append(tag.Body, &ast.AttributeStmt{
+			AttrName: &ast.Ident{Name: c.Attr},
+		})
+	}
+	return e
+}
+
+// rewriteStyle scopes the style sheet of the string literal lit, it
+// reports the selectors that match none of the elements elems.
+func (c *Component) rewriteStyle(lit *ast.BasicLit, elems []element) []Diagnostic {
+	css, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil
+	}
+	out, sels := scopeCSS(css, c.Attr)
+
+	var diags []Diagnostic
+	for _, sel := range sels {
+		if matchesAny(sel.text, elems) {
+			continue
+		}
+		// The offsets in the style sheet are positions
+		// in the literal, unless it has escapes.
+		pos := lit.Pos()
+		if lit.Value[1:len(lit.Value)-1] == css {
+			pos += token.Pos(1 + sel.offset)
+		}
+		diags = append(diags, Diagnostic{pos, fmt.Sprintf("selector %s matches no element of %s", sel.text, funcName(c.Func))})
+	}
+
+	if lit.Value[0] == '`' && !strings.Contains(out, "`") && !strings.Contains(out, "\r") {
+		lit.Value = "`" + out + "`"
+	} else {
+		lit.Value = strconv.Quote(out)
+	}
+	return diags
+}
+
+// matchesAny reports whether each compound selector of the
+// selector sel matches at least one of the elements elems.
+func matchesAny(sel string, elems []element) bool {
+	for _, part := range compoundSelectors(sel) {
+		c := parseCompound(part.text)
+		matched := false
+		for _, e := range elems {
+			if e.matches(c) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether e might match the compound selector c.
+func (e *element) matches(c compound) bool {
+	if c.tag != "" && e.tag != "" && c.tag != e.tag {
+		return false
+	}
+	if c.id != "" && !e.anyID && c.id != e.id {
+		return false
+	}
+	for _, class := range c.classes {
+		if !e.anyClass && !slices.Contains(e.classes, class) {
+			return false
+		}
+	}
+	for _, attr := range c.attrs {
+		if !e.attrs[attr] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,208 @@
+package scopedcss
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/format"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+)
+
+func TestScopeCSS(t *testing.T) {
+	cases := []struct {
+		in, want string
+		sels     []string
+	}{
+		{
+			in:   ".card { color: red; }",
+			want: ".card[s] { color: red; }",
+			sels: []string{".card"},
+		},
+		{
+			in:   "ul > li.active:hover, a::before,\n  p + p ~ span{margin:0}",
+			want: "ul > li.active[s]:hover, a[s]::before,\n  p + p ~ span[s]{margin:0}",
+			sels: []string{"ul > li.active:hover", "a::before", "p + p ~ span"},
+		},
+		{
+			in:   `a[href^="a,b{"]:not(.x, .y) { content: "}" }`,
+			want: `a[href^="a,b{"][s]:not(.x, .y) { content: "}" }`,
+			sels: []string{`a[href^="a,b{"]:not(.x, .y)`},
+		},
+		{
+			in:   "@import url(x.css);\n@media (max-width: 600px) { .a, .b { x: y } }\n/* .c { } */ :hover {}",
+			want: "@import url(x.css);\n@media (max-width: 600px) { .a[s], .b[s] { x: y } }\n/* .c { } */ [s]:hover {}",
+			sels: []string{".a", ".b", ":hover"},
+		},
+		{
+			in:   "@keyframes spin { from { x: 0 } to { x: 1 } }\n@font-face { font-family: f }\nli:nth-child(2n+1) {}",
+			want: "@keyframes spin { from { x: 0 } to { x: 1 } }\n@font-face { font-family: f }\nli[s]:nth-child(2n+1) {}",
+			sels: []string{"li:nth-child(2n+1)"},
+		},
+	}
+	for _, tt := range cases {
+		got, sels := scopeCSS(tt.in, "s")
+		if got != tt.want {
+			t.Errorf("scopeCSS(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+		var gotSels []string
+		for _, sel := range sels {
+			if !strings.HasPrefix(tt.in[sel.offset:], sel.text) {
+				t.Errorf("scopeCSS(%q): selector %q at offset %d", tt.in, sel.text, sel.offset)
+			}
+			gotSels = append(gotSels, sel.text)
+		}
+		if fmt.Sprint(gotSels) != fmt.Sprint(tt.sels) {
+			t.Errorf("scopeCSS(%q) selectors = %q; want %q", tt.in, gotSels, tt.sels)
+		}
+	}
+}
+
+const src = `package p
+
+import "github.com/mateusz834/tgo"
+
+func Card(ctx tgo.Ctx, title string, active bool) error {
+	<style>
+		` + "`" + `
+.card h2 { color: red; }
+.card.active, #main:hover { color: blue; }
+.missing, table td { x: y }
+` + "`" + `
+		".\{title} {}"
+	</style>
+	<div @id="main" @class="card"
+		if active {
+			@class="active"
+		}
+	>
+		<h2>"\{title}"</h2>
+		Footer(ctx)
+	</div>
+	return nil
+}
+
+func (t T) Render(ctx tgo.Ctx) error {
+	<style>
+		".x[data-y], p[data], p[data=y].z:hover, p[id] { }"
+	</style>
+	<p @class="\{t.class}" @data="y"></p>
+	return nil
+}
+
+func Footer(ctx tgo.Ctx) error {
+	<footer></footer>
+	return nil
+}
+`
+
+func TestRewrite(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.tgo", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps, diags := Rewrite([]*ast.File{f}, "example.com/p")
+
+	if len(comps) != 2 {
+		t.Fatalf("len(comps) = %d; want 2", len(comps))
+	}
+	card, render := Attr("example.com/p", "Card"), Attr("example.com/p", "T.Render")
+	if comps[0].Attr != card || comps[1].Attr != render {
+		t.Errorf("attributes = %s, %s; want %s, %s", comps[0].Attr, comps[1].Attr, card, render)
+	}
+	if Attr("example.com/a/p", "Card") == Attr("example.com/b/p", "Card") {
+		t.Error("packages with the same name have the same scoping attribute")
+	}
+
+	var got []string
+	for _, d := range diags {
+		got = append(got, fmt.Sprintf("%v: %s", fset.Position(d.Pos), d.Message))
+	}
+	want := []string{
+		"p.tgo:10:1: selector .missing matches no element of Card",
+		"p.tgo:10:11: selector table td matches no element of Card",
+		"p.tgo:12:3: style with template literal parts cannot be scoped",
+		"p.tgo:27:4: selector .x[data-y] matches no element of T.Render",
+		"p.tgo:27:44: selector p[id] matches no element of T.Render",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var b strings.Builder
+	if err := format.Node(&b, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	// The printer puts the attributes of an open tag on separate lines.
+	wantOut := `package p
+
+import "github.com/mateusz834/tgo"
+
+func Card(ctx tgo.Ctx, title string, active bool) error {
+	<style>
+		` + "`" + `
+.card h2[` + card + `] { color: red; }
+.card.active[` + card + `], #main[` + card + `]:hover { color: blue; }
+.missing[` + card + `], table td[` + card + `] { x: y }
+` + "`" + `
+		".\{title} {}"
+	</style>
+	<div
+		@id="main"
+		@class="card"
+		if active {
+			@class="active"
+		}
+		@` + card + `
+	>
+		<h2
+			@` + card + `
+		>
+			"\{title}"
+		</h2>
+		Footer(ctx)
+	</div>
+	return nil
+}
+
+func (t T) Render(ctx tgo.Ctx) error {
+	<style>
+		".x[data-y][` + render + `], p[data][` + render + `], p[data=y].z[` + render + `]:hover, p[id][` + render + `] { }"
+	</style>
+	<p
+		@class="\{t.class}"
+		@data="y"
+		@` + render + `
+	>
+	</p>
+	return nil
+}
+
+func Footer(ctx tgo.Ctx) error {
+	<footer></footer>
+	return nil
+}
+`
+	if got := b.String(); got != wantOut {
+		t.Errorf("output:\n%s\nwant:\n%s", got, wantOut)
+	}
+}
+
+func TestParseCompound(t *testing.T) {
+	cases := []struct {
+		in   string
+		want compound
+	}{
+		{"a[href]", compound{tag: "a", attrs: []string{"href"}}},
+		{`input[type="a]b"].x#y:not([z])`, compound{tag: "input", id: "y", classes: []string{"x"}, attrs: []string{"type"}}},
+		{"li:nth-child(2n+1).a", compound{tag: "li", classes: []string{"a"}}},
+	}
+	for _, tt := range cases {
+		if got := parseCompound(tt.in); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("parseCompound(%q) = %+v; want %+v", tt.in, got, tt.want)
+		}
+	}
+}
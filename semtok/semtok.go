@@ -0,0 +1,483 @@
+// Package semtok classifies the tokens of tgo files for syntax
+// highlighting, as the semantic tokens of the Language Server Protocol.
+//
+// The markup (tag names, attribute names and values, text and the
+// delimiters of tags and of template literal parts) is classified with
+// the tgo token types (see [TagName] and the following types), the Go
+// code (including the expressions of template literal parts) with the
+// standard LSP token types. When the file is type-checked, identifiers
+// are classified using the objects recorded in [types.Info.Defs] and
+// [types.Info.Uses], otherwise by their syntactic context only.
+//
+// Keywords are classified when their positions are recorded in the AST,
+// i.e. all keywords except "else".
+package semtok
+
+import (
+	"slices"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// A Type is the type of a semantic token, an index into [TokenTypes].
+type Type uint32
+
+const (
+	Namespace Type = iota
+	TypeName
+	TypeParameter
+	Parameter
+	Variable
+	Property
+	Function
+	Method
+	Label
+	Keyword
+	Comment
+	String
+	Number
+	Operator
+
+	// TagName: a static tag name, e.g. div in <div>.
+	TagName
+	// TagDelimiter: "<", "</" and ">" of tags.
+	TagDelimiter
+	// AttrName: an attribute name, with its "@".
+	AttrName
+	// AttrValue: the (static part of an) attribute value.
+	AttrValue
+	// Text: the (static part of a) text node.
+	Text
+	// Interpolation: "\{", ":" and "}" of template literal parts
+	// and of dynamic tag names.
+	Interpolation
+	// FormatVerb: the format verb of a template literal part.
+	FormatVerb
+)
+
+// TokenTypes are the names of the token types, as in the
+// legend of the semantic tokens provider of an LSP server.
+var TokenTypes = []string{
+	Namespace:     "namespace",
+	TypeName:      "type",
+	TypeParameter: "typeParameter",
+	Parameter:     "parameter",
+	Variable:      "variable",
+	Property:      "property",
+	Function:      "function",
+	Method:        "method",
+	Label:         "label",
+	Keyword:       "keyword",
+	Comment:       "comment",
+	String:        "string",
+	Number:        "number",
+	Operator:      "operator",
+	TagName:       "tag",
+	TagDelimiter:  "tagDelimiter",
+	AttrName:      "attribute",
+	AttrValue:     "attributeValue",
+	Text:          "text",
+	Interpolation: "interpolation",
+	FormatVerb:    "formatVerb",
+}
+
+func (t Type) String() string {
+	if int(t) < len(TokenTypes) {
+		return TokenTypes[t]
+	}
+	return "invalid"
+}
+
+// A Modifier is a set of token modifiers, bit i is the modifier
+// TokenModifiers[i].
+type Modifier uint32
+
+const (
+	Declaration    Modifier = 1 << iota // the declaration of an identifier
+	Readonly                            // a constant
+	DefaultLibrary                      // a predeclared identifier
+)
+
+// TokenModifiers are the names of the modifiers, as in the
+// legend of the semantic tokens provider of an LSP server.
+var TokenModifiers = []string{"declaration", "readonly", "defaultLibrary"}
+
+// A Token is a classified token.
+type Token struct {
+	Pos       token.Pos
+	Len       int // in bytes
+	Type      Type
+	Modifiers Modifier
+}
+
+// Tokens returns the classified tokens of the file f, sorted by their
+// positions. The info might be nil, otherwise it must hold the Defs and
+// Uses recorded by the type checker.
+func Tokens(f *ast.File, info *types.Info) []Token {
+	t := tokenizer{info: info, idents: make(map[*ast.Ident]Token), params: make(map[types.Object]bool)}
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			t.add(c.Pos(), len(c.Text), Comment, 0)
+		}
+	}
+	ast.Inspect(f, t.node)
+	slices.SortStableFunc(t.toks, func(a, b Token) int { return int(a.Pos - b.Pos) })
+	return t.toks
+}
+
+type tokenizer struct {
+	info *types.Info
+	toks []Token
+
+	// idents are the classifications of identifiers
+	// by their syntactic context.
+	idents map[*ast.Ident]Token
+	params map[types.Object]bool // parameters and results
+}
+
+func (t *tokenizer) add(pos token.Pos, n int, typ Type, mods Modifier) {
+	if pos.IsValid() && n > 0 {
+		t.toks = append(t.toks, Token{pos, n, typ, mods})
+	}
+}
+
+func (t *tokenizer) keyword(pos token.Pos, kw string) {
+	t.add(pos, len(kw), Keyword, 0)
+}
+
+// declare classifies the identifiers names by their syntactic context.
+func (t *tokenizer) declare(typ Type, names ...*ast.Ident) {
+	for _, id := range names {
+		if id != nil {
+			t.idents[id] = Token{Type: typ, Modifiers: Declaration}
+		}
+	}
+}
+
+func (t *tokenizer) inspect(n ast.Node) {
+	if n != nil {
+		ast.Inspect(n, t.node)
+	}
+}
+
+func (t *tokenizer) node(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.CommentGroup:
+		return false // see Tokens
+
+	case *ast.Ident:
+		t.ident(n)
+
+	case *ast.BasicLit:
+		if n.Kind == token.STRING || n.Kind == token.CHAR {
+			t.add(n.Pos(), len(n.Value), String, 0)
+		} else {
+			t.add(n.Pos(), len(n.Value), Number, 0)
+		}
+
+	case *ast.File:
+		t.keyword(n.Package, "package")
+		t.add(n.Name.Pos(), len(n.Name.Name), Namespace, 0)
+		for _, d := range n.Decls {
+			t.inspect(d)
+		}
+		return false
+
+	case *ast.GenDecl:
+		t.keyword(n.TokPos, n.Tok.String())
+	case *ast.ImportSpec:
+		t.declare(Namespace, n.Name)
+	case *ast.TypeSpec:
+		t.declare(TypeName, n.Name)
+		if n.TypeParams != nil {
+			for _, f := range n.TypeParams.List {
+				t.declare(TypeParameter, f.Names...)
+			}
+		}
+	case *ast.ValueSpec:
+		t.declare(Variable, n.Names...)
+	case *ast.StructType:
+		t.keyword(n.Struct, "struct")
+		for _, f := range n.Fields.List {
+			t.declare(Property, f.Names...)
+		}
+	case *ast.InterfaceType:
+		t.keyword(n.Interface, "interface")
+		for _, f := range n.Methods.List {
+			t.declare(Method, f.Names...)
+		}
+	case *ast.MapType:
+		t.keyword(n.Map, "map")
+	case *ast.ChanType:
+		if n.Begin != n.Arrow {
+			t.keyword(n.Begin, "chan")
+		}
+
+	case *ast.FuncDecl:
+		if n.Recv != nil {
+			t.declare(Method, n.Name)
+			t.declareParams(n.Recv)
+		} else {
+			t.declare(Function, n.Name)
+		}
+	case *ast.FuncType:
+		t.keyword(n.Func, "func")
+		if n.TypeParams != nil {
+			for _, f := range n.TypeParams.List {
+				t.declare(TypeParameter, f.Names...)
+			}
+		}
+		t.declareParams(n.Params)
+		t.declareParams(n.Results)
+
+	case *ast.LabeledStmt:
+		t.declare(Label, n.Label)
+	case *ast.BranchStmt:
+		t.keyword(n.TokPos, n.Tok.String())
+		if n.Label != nil {
+			t.add(n.Label.Pos(), len(n.Label.Name), Label, 0)
+		}
+		return false
+	case *ast.ReturnStmt:
+		t.keyword(n.Return, "return")
+	case *ast.GoStmt:
+		t.keyword(n.Go, "go")
+	case *ast.DeferStmt:
+		t.keyword(n.Defer, "defer")
+	case *ast.IfStmt:
+		t.keyword(n.If, "if")
+	case *ast.ForStmt:
+		t.keyword(n.For, "for")
+	case *ast.RangeStmt:
+		t.keyword(n.For, "for")
+		t.keyword(n.Range, "range")
+	case *ast.SwitchStmt:
+		t.keyword(n.Switch, "switch")
+	case *ast.TypeSwitchStmt:
+		t.keyword(n.Switch, "switch")
+	case *ast.SelectStmt:
+		t.keyword(n.Select, "select")
+	case *ast.CaseClause:
+		t.caseKeyword(n.Case, n.List == nil)
+	case *ast.CommClause:
+		t.caseKeyword(n.Case, n.Comm == nil)
+
+	case *ast.OpenTag:
+		t.add(n.OpenPos, 1, TagDelimiter, 0)
+		t.tagName(n.Name, n.DynamicName)
+		for _, s := range n.Body {
+			t.inspect(s)
+		}
+		t.add(n.ClosePos, 1, TagDelimiter, 0)
+		return false
+	case *ast.EndTag:
+		t.add(n.OpenPos, 2, TagDelimiter, 0)
+		t.tagName(n.Name, n.DynamicName)
+		t.add(n.ClosePos, 1, TagDelimiter, 0)
+		return false
+	case *ast.AttributeStmt:
+		t.add(n.StartPos, int(n.AttrName.End()-n.StartPos), AttrName, 0)
+		t.add(n.AssignPos, 1, Operator, 0)
+		switch v := n.Value.(type) {
+		case *ast.BasicLit:
+			t.add(v.Pos(), len(v.Value), AttrValue, 0)
+		case *ast.TemplateLiteralExpr:
+			t.templateLiteral(v, AttrValue)
+		}
+		return false
+	case *ast.ExprStmt:
+		if x, ok := n.X.(*ast.BasicLit); ok && x.Kind == token.STRING {
+			// Only text is a string literal in statement position.
+			t.add(x.Pos(), len(x.Value), Text, 0)
+			return false
+		}
+	case *ast.TemplateLiteralExpr:
+		t.templateLiteral(n, Text)
+		return false
+	}
+	return true
+}
+
+func (t *tokenizer) caseKeyword(pos token.Pos, isDefault bool) {
+	if isDefault {
+		t.keyword(pos, "default")
+	} else {
+		t.keyword(pos, "case")
+	}
+}
+
+// declareParams classifies the names of the parameters (or results) list.
+func (t *tokenizer) declareParams(list *ast.FieldList) {
+	if list == nil {
+		return
+	}
+	for _, f := range list.List {
+		t.declare(Parameter, f.Names...)
+		if t.info != nil {
+			for _, name := range f.Names {
+				if obj := t.info.Defs[name]; obj != nil {
+					t.params[obj] = true
+				}
+			}
+		}
+	}
+}
+
+func (t *tokenizer) tagName(name *ast.Ident, dynamic *ast.TemplateLiteralPart) {
+	if name != nil {
+		t.add(name.Pos(), len(name.Name), TagName, 0)
+	}
+	if dynamic != nil {
+		t.part(dynamic)
+	}
+}
+
+// templateLiteral adds the template literal x, its strings are of the
+// type typ (Text or AttrValue).
+func (t *tokenizer) templateLiteral(x *ast.TemplateLiteralExpr, typ Type) {
+	pos := x.OpenPos
+	for i, s := range x.Strings {
+		t.add(pos, len(s), typ, 0)
+		if i < len(x.Parts) {
+			p := x.Parts[i]
+			t.part(p)
+			pos = p.RBrace + 1
+		}
+	}
+}
+
+// part adds the template literal part p, "\{" precedes its LBrace.
+func (t *tokenizer) part(p *ast.TemplateLiteralPart) {
+	t.add(p.LBrace-1, 2, Interpolation, 0)
+	t.inspect(p.X)
+	t.add(p.Colon, 1, Interpolation, 0)
+	if v, ok := p.Format.(*ast.FormatVerb); ok {
+		t.add(v.VerbPos, len(v.Verb), FormatVerb, 0)
+	} else {
+		t.inspect(p.Format)
+	}
+	t.add(p.RBrace, 1, Interpolation, 0)
+}
+
+func (t *tokenizer) ident(id *ast.Ident) {
+	tok, ok := t.idents[id]
+	if t.info != nil {
+		if typ, mods, ok2 := t.object(id); ok2 {
+			tok.Type, tok.Modifiers, ok = typ, mods, true
+		}
+	}
+	if !ok {
+		tok.Type = Variable
+	}
+	t.add(id.Pos(), len(id.Name), tok.Type, tok.Modifiers)
+}
+
+// object classifies the identifier id by its object.
+func (t *tokenizer) object(id *ast.Ident) (typ Type, mods Modifier, ok bool) {
+	obj := t.info.Defs[id]
+	if obj != nil {
+		mods |= Declaration
+	} else {
+		obj = t.info.Uses[id]
+	}
+	if obj == nil {
+		return 0, 0, false
+	}
+	if obj.Pkg() == nil && obj.Parent() == types.Universe {
+		mods |= DefaultLibrary
+	}
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		typ = Namespace
+	case *types.TypeName:
+		typ = TypeName
+		if _, ok := obj.Type().(*types.TypeParam); ok {
+			typ = TypeParameter
+		}
+	case *types.Var:
+		switch {
+		case obj.IsField():
+			typ = Property
+		case t.params[obj]:
+			typ = Parameter
+		default:
+			typ = Variable
+		}
+	case *types.Const:
+		typ, mods = Variable, mods|Readonly
+	case *types.Nil:
+		typ, mods = Variable, mods|Readonly
+	case *types.Func:
+		typ = Function
+		if obj.Type().(*types.Signature).Recv() != nil {
+			typ = Method
+		}
+	case *types.Builtin:
+		typ = Function
+	case *types.Label:
+		typ = Label
+	default:
+		return 0, 0, false
+	}
+	return typ, mods, true
+}
+
+// Encode encodes the tokens of the file f, with the content src, as the
+// data of the LSP semantic tokens: five integers per token, the line and
+// the start character (relative to the previous token), the length, the
+// type and the modifiers. The characters are counted in UTF-16 code
+// units, tokens spanning multiple lines are split.
+func Encode(f *token.File, src []byte, toks []Token) []uint32 {
+	var (
+		data           []uint32
+		prevLine, prev int // line and start character of the previous token
+	)
+	emit := func(line, start, end int, tok Token) {
+		lineStart := f.Offset(f.LineStart(line))
+		char := utf16Len(src[lineStart:start])
+		deltaChar := char
+		if line == prevLine {
+			deltaChar = char - prev
+		}
+		data = append(data, uint32(line-prevLine), uint32(deltaChar), uint32(utf16Len(src[start:end])), uint32(tok.Type), uint32(tok.Modifiers))
+		prevLine, prev = line, char
+	}
+	prevLine = 1
+	for _, tok := range toks {
+		start := f.Offset(tok.Pos)
+		end := min(start+tok.Len, len(src))
+		line := f.Line(tok.Pos)
+		// split the token at line ends
+		for start < end {
+			lineEnd := end
+			if line < f.LineCount() {
+				lineEnd = min(end, f.Offset(f.LineStart(line+1)))
+			}
+			segEnd := lineEnd
+			for segEnd > start && (src[segEnd-1] == '\n' || src[segEnd-1] == '\r') {
+				segEnd--
+			}
+			if segEnd > start {
+				emit(line, start, segEnd, tok)
+			}
+			start = lineEnd
+			line++
+		}
+	}
+	return data
+}
+
+// utf16Len returns the length of the UTF-8 encoded b in UTF-16 code units.
+func utf16Len(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		n += utf16.RuneLen(r)
+		b = b[size:]
+	}
+	return n
+}
@@ -0,0 +1,192 @@
+package semtok_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/importer"
+	"github.com/mateusz834/tgoast/internal/tgoimporter"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/semtok"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+var update = flag.Bool("update", false, "update golden (.golden) files")
+
+// dump returns the tokens, one per line.
+func dump(fset *token.FileSet, src []byte, toks []semtok.Token) string {
+	var b strings.Builder
+	for _, tok := range toks {
+		pos := fset.Position(tok.Pos)
+		fmt.Fprintf(&b, "%d:%d %v", pos.Line, pos.Column, tok.Type)
+		for i, m := range semtok.TokenModifiers {
+			if tok.Modifiers&(1<<i) != 0 {
+				fmt.Fprintf(&b, " +%s", m)
+			}
+		}
+		fmt.Fprintf(&b, " %q\n", src[pos.Offset:pos.Offset+tok.Len])
+	}
+	return b.String()
+}
+
+// TestGolden classifies the tokens of the tgo files of the parser
+// testdata, the output is compared with the files in testdata.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "parser", "testdata", "tgo", "*.tgo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no tgo files")
+	}
+	for _, filename := range files {
+		name := strings.TrimSuffix(filepath.Base(filename), ".tgo")
+		if name == "element_blocks" {
+			continue // syntax errors
+		}
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, filename, src, parser.ParseComments|parser.SkipObjectResolution)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := dump(fset, src, semtok.Tokens(f, nil))
+
+			golden := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("tokens of %s:\n%s\nwant:\n%s", filename, got, want)
+			}
+		})
+	}
+}
+
+func TestTypes(t *testing.T) {
+	const src = `package p
+
+import "github.com/mateusz834/tgo"
+
+const greeting = "Hello"
+
+type user struct{ Name string }
+
+func (u user) Render(ctx tgo.Ctx) error {
+	<p @title="\{greeting}">"\{greeting}, \{u.Name:%q}!"</p>
+	return nil
+}
+
+func list[T any](ctx tgo.Ctx, items []T) error {
+	for i := range len(items) {
+		<li>"\{i}"</li>
+	}
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.tgo", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: &tgoimporter.TgoDefaultImporter{I: importer.Default().(types.ImporterFrom)}}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	if _, err := conf.Check("p", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Split(dump(fset, []byte(src), semtok.Tokens(f, info)), "\n")
+	for _, want := range []string{
+		`3:8 string "\"github.com/mateusz834/tgo\""`,
+		`5:7 variable +declaration +readonly "greeting"`,
+		`7:6 type +declaration "user"`,
+		`7:19 property +declaration "Name"`,
+		`7:24 type +defaultLibrary "string"`,
+		`9:7 parameter +declaration "u"`,
+		`9:15 method +declaration "Render"`,
+		`9:26 namespace "tgo"`,
+		`9:30 type "Ctx"`,
+		`10:2 tagDelimiter "<"`,
+		`10:3 tag "p"`,
+		`10:5 attribute "@title"`,
+		`10:11 operator "="`,
+		`10:12 attributeValue "\""`,
+		`10:13 interpolation "\\{"`,
+		`10:15 variable +readonly "greeting"`,
+		`10:24 attributeValue "\""`,
+		`10:25 tagDelimiter ">"`,
+		`10:26 text "\""`,
+		`10:38 text ", "`,
+		`10:42 parameter "u"`,
+		`10:44 property "Name"`,
+		`10:48 interpolation ":"`,
+		`10:49 formatVerb "%q"`,
+		`10:51 interpolation "}"`,
+		`10:52 text "!\""`,
+		`10:54 tagDelimiter "</"`,
+		`11:9 variable +readonly +defaultLibrary "nil"`,
+		`14:11 typeParameter +declaration "T"`,
+		`14:13 type +defaultLibrary "any"`,
+		`14:39 typeParameter "T"`,
+		`15:2 keyword "for"`,
+		`15:6 variable +declaration "i"`,
+		`15:11 keyword "range"`,
+		`15:17 function +defaultLibrary "len"`,
+		`15:21 parameter "items"`,
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("missing token %s", want)
+		}
+	}
+	if t.Failed() {
+		t.Logf("tokens:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestEncode(t *testing.T) {
+	const src = "package p\n\nfunc f() {\n\t\"é\\{x}\"\n\t/* a\n   b */\n}\n"
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.tgo", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toks := semtok.Tokens(f, nil)
+	got := semtok.Encode(fset.File(f.Pos()), []byte(src), toks)
+	want := []uint32{
+		0, 0, 7, uint32(semtok.Keyword), 0, // package
+		0, 8, 1, uint32(semtok.Namespace), 0, // p
+		2, 0, 4, uint32(semtok.Keyword), 0, // func
+		0, 5, 1, uint32(semtok.Function), uint32(semtok.Declaration), // f
+		1, 1, 2, uint32(semtok.Text), 0, // "é
+		0, 2, 2, uint32(semtok.Interpolation), 0, // \{
+		0, 2, 1, uint32(semtok.Variable), 0, // x
+		0, 1, 1, uint32(semtok.Interpolation), 0, // }
+		0, 1, 1, uint32(semtok.Text), 0, // "
+		1, 1, 4, uint32(semtok.Comment), 0, // /* a
+		1, 0, 7, uint32(semtok.Comment), 0, //    b */
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Encode() =\n%v\nwant:\n%v", got, want)
+	}
+}
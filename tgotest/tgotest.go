@@ -0,0 +1,251 @@
+// Package tgotest implements snapshot tests of tgo components, described
+// by txtar archives.
+//
+// An archive holds the source of a package (the .tgo and .go files) and,
+// for each tested component Func, its expected output Func.html. The
+// arguments of the component (following the tgo.Ctx) are read from the
+// JSON array Func.json, if present, the error returned by the component
+// must match the text of Func.err, if present. For example:
+//
+//	A card with a title.
+//
+//	-- card.tgo --
+//	package p
+//
+//	import "github.com/mateusz834/tgo"
+//
+//	func Card(ctx tgo.Ctx, title string) error {
+//		<h2>"\{title}"</h2>
+//		return nil
+//	}
+//	-- Card.json --
+//	["Hello"]
+//	-- Card.html --
+//	<h2>Hello</h2>
+//
+// The package is type-checked and the components are rendered by the
+// interpreter of the interp package, the output is compared with the
+// expected output and the differences are reported. With the -update
+// flag, the expected outputs (and errors) in the archives are rewritten
+// with the actual ones instead.
+package tgotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/importer"
+	"github.com/mateusz834/tgoast/internal/diff"
+	"github.com/mateusz834/tgoast/internal/tgoimporter"
+	"github.com/mateusz834/tgoast/internal/txtar"
+	"github.com/mateusz834/tgoast/interp"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the expected outputs of the tgotest archives")
+
+// A Config configures the snapshot tests.
+type Config struct {
+	// Funcs maps the qualified names of the functions of other packages
+	// called by the components to their implementations, see interp.Config.
+	Funcs map[string]any
+}
+
+// Run runs the snapshot tests of the archives matching pattern (see
+// filepath.Glob), each archive in a subtest named after the archive.
+func Run(t *testing.T, pattern string, conf *Config) {
+	t.Helper()
+	archives, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) == 0 {
+		t.Fatalf("no archives match %s", pattern)
+	}
+	for _, archive := range archives {
+		t.Run(strings.TrimSuffix(filepath.Base(archive), ".txtar"), func(t *testing.T) {
+			RunArchive(t, archive, conf)
+		})
+	}
+}
+
+// RunArchive runs the snapshot tests of the archive file, each component
+// in a subtest named after the component.
+func RunArchive(t *testing.T, file string, conf *Config) {
+	t.Helper()
+	runArchive(t, file, conf, *update)
+}
+
+// runArchive is RunArchive, the archive is rewritten when update is set.
+func runArchive(t *testing.T, file string, conf *Config, update bool) {
+	t.Helper()
+	ar, err := txtar.ParseFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := newInterpreter(ar, conf)
+	if err != nil {
+		t.Fatalf("%s: %v", file, err)
+	}
+
+	results := make(map[string]string) // actual contents of the .html and .err files
+	for _, f := range ar.Files {
+		name, ok := strings.CutSuffix(f.Name, ".html")
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			html, errText := render(t, ar, in, name)
+			results[name+".html"] = html
+			if errText != "" {
+				results[name+".err"] = errText
+			}
+			if update {
+				return
+			}
+			if d := diff.Diff(name+".html", f.Data, "got", []byte(html)); d != nil {
+				t.Errorf("%s: output differs:\n%s", file, d)
+			}
+			if want := archiveFile(ar, name+".err"); !bytes.Equal(want, []byte(errText)) {
+				t.Errorf("%s: %s: error = %q; want %q", file, name, strings.TrimSuffix(errText, "\n"), strings.TrimSuffix(string(want), "\n"))
+			}
+		})
+	}
+	if update {
+		updateArchive(t, file, ar, results)
+	}
+}
+
+// render renders the component name, it returns its output and error,
+// each followed by a newline, as the files of an archive.
+func render(t *testing.T, ar *txtar.Archive, in *interp.Interpreter, name string) (html, errText string) {
+	t.Helper()
+	argTypes, err := in.ArgTypes(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []any
+	if data := archiveFile(ar, name+".json"); data != nil {
+		var raw []json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("%s.json: %v", name, err)
+		}
+		if len(raw) != len(argTypes) {
+			t.Fatalf("%s.json: %d arguments; want %d", name, len(raw), len(argTypes))
+		}
+		for i, data := range raw {
+			v := reflect.New(argTypes[i])
+			if err := json.Unmarshal(data, v.Interface()); err != nil {
+				t.Fatalf("%s.json: argument %d: %v", name, i+1, err)
+			}
+			args = append(args, v.Elem().Interface())
+		}
+	}
+
+	var b strings.Builder
+	err = in.Render(&b, name, args...)
+	if b.Len() > 0 {
+		b.WriteString("\n")
+	}
+	if err != nil {
+		errText = err.Error() + "\n"
+	}
+	return b.String(), errText
+}
+
+// archiveFile returns the data of the file name of ar; or nil.
+func archiveFile(ar *txtar.Archive, name string) []byte {
+	for _, f := range ar.Files {
+		if f.Name == name {
+			return f.Data
+		}
+	}
+	return nil
+}
+
+// updateArchive rewrites the .html and .err files of the archive file
+// with their actual contents results. The .err files of the components
+// without an error are removed, the missing ones are added after the
+// .html file of their components.
+func updateArchive(t *testing.T, file string, ar *txtar.Archive, results map[string]string) {
+	t.Helper()
+	var files []txtar.File
+	for _, f := range ar.Files {
+		data, ok := results[f.Name]
+		switch {
+		case ok:
+			f.Data = []byte(data)
+		case strings.HasSuffix(f.Name, ".err") && archiveFile(ar, strings.TrimSuffix(f.Name, ".err")+".html") != nil:
+			continue // no error
+		}
+		files = append(files, f)
+		if name, ok := strings.CutSuffix(f.Name, ".html"); ok {
+			if data, ok := results[name+".err"]; ok && archiveFile(ar, name+".err") == nil {
+				files = append(files, txtar.File{Name: name + ".err", Data: []byte(data)})
+			}
+		}
+	}
+	ar.Files = files
+	if err := os.WriteFile(file, txtar.Format(ar), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newInterpreter type-checks the package of the .tgo and .go files
+// of the archive and returns its interpreter.
+func newInterpreter(ar *txtar.Archive, conf *Config) (*interp.Interpreter, error) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, f := range ar.Files {
+		mode := parser.ParseComments | parser.SkipObjectResolution
+		switch filepath.Ext(f.Name) {
+		case ".tgo":
+			mode |= parser.TgoSyntax
+		case ".go":
+			mode |= parser.GoOnly
+		default:
+			continue
+		}
+		file, err := parser.ParseFile(fset, f.Name, f.Data, mode)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no .tgo or .go files")
+	}
+
+	tconf := types.Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(types.ImporterFrom)},
+		Interpolators: types.DefaultInterpolators(),
+	}
+	info := &types.Info{
+		Types:          make(map[ast.Expr]types.TypeAndValue),
+		Defs:           make(map[*ast.Ident]types.Object),
+		Uses:           make(map[*ast.Ident]types.Object),
+		Selections:     make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:      make(map[*ast.Ident]types.Instance),
+		Interpolations: make(map[*ast.TemplateLiteralPart]types.Interpolation),
+	}
+	pkg, err := tconf.Check(files[0].Name.Name, fset, files, info)
+	if err != nil {
+		return nil, fmt.Errorf("type-checking: %v", err)
+	}
+	var iconf interp.Config
+	if conf != nil {
+		iconf.Funcs = conf.Funcs
+	}
+	return interp.New(fset, pkg, info, files, &iconf)
+}
@@ -0,0 +1,58 @@
+package tgotest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mateusz834/tgoast/internal/txtar"
+)
+
+var conf = &Config{Funcs: map[string]any{
+	"errors.New":      errors.New,
+	"strings.ToUpper": strings.ToUpper,
+}}
+
+func TestRun(t *testing.T) {
+	Run(t, filepath.Join("testdata", "*.txtar"), conf)
+}
+
+func TestUpdate(t *testing.T) {
+	ar, err := txtar.ParseFile(filepath.Join("testdata", "components.txtar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(txtar.Format(ar))
+
+	// Break the expected outputs: change the output of Card, remove
+	// the error of Fail and add an error to Empty.
+	var files []txtar.File
+	for _, f := range ar.Files {
+		switch f.Name {
+		case "Card.html":
+			f.Data = []byte("<div></div>\n")
+		case "Fail.err":
+			continue
+		}
+		files = append(files, f)
+		if f.Name == "Empty.html" {
+			files = append(files, txtar.File{Name: "Empty.err", Data: []byte("error\n")})
+		}
+	}
+	ar.Files = files
+	file := filepath.Join(t.TempDir(), "components.txtar")
+	if err := os.WriteFile(file, txtar.Format(ar), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	runArchive(t, file, conf, true)
+	got, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("updated archive:\n%s\nwant:\n%s", got, want)
+	}
+}
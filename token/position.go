@@ -71,9 +71,10 @@ type File struct {
 	size int    // file size as provided to AddFile
 
 	// lines and infos are protected by mutex
-	mutex sync.Mutex
-	lines []int // lines contains the offset of the first character for each line (the first entry is always 0)
-	infos []lineInfo
+	mutex    sync.Mutex
+	lines    []int // lines contains the offset of the first character for each line (the first entry is always 0)
+	infos    []lineInfo
+	mappings []Mapping // see AddSourceMapping
 }
 
 // Name returns the file name of file f as registered with AddFile.
@@ -6,11 +6,12 @@ package token
 
 type serializedFile struct {
 	// fields correspond 1:1 to fields with same (lower-case) name in File
-	Name  string
-	Base  int
-	Size  int
-	Lines []int
-	Infos []lineInfo
+	Name     string
+	Base     int
+	Size     int
+	Lines    []int
+	Infos    []lineInfo
+	Mappings []Mapping
 }
 
 type serializedFileSet struct {
@@ -31,11 +32,12 @@ func (s *FileSet) Read(decode func(any) error) error {
 	for i := 0; i < len(ss.Files); i++ {
 		f := &ss.Files[i]
 		files[i] = &File{
-			name:  f.Name,
-			base:  f.Base,
-			size:  f.Size,
-			lines: f.Lines,
-			infos: f.Infos,
+			name:     f.Name,
+			base:     f.Base,
+			size:     f.Size,
+			lines:    f.Lines,
+			infos:    f.Infos,
+			mappings: f.Mappings,
 		}
 	}
 	s.files = files
@@ -55,11 +57,12 @@ func (s *FileSet) Write(encode func(any) error) error {
 	for i, f := range s.files {
 		f.mutex.Lock()
 		files[i] = serializedFile{
-			Name:  f.name,
-			Base:  f.base,
-			Size:  f.size,
-			Lines: append([]int(nil), f.lines...),
-			Infos: append([]lineInfo(nil), f.infos...),
+			Name:     f.name,
+			Base:     f.base,
+			Size:     f.size,
+			Lines:    append([]int(nil), f.lines...),
+			Infos:    append([]lineInfo(nil), f.infos...),
+			Mappings: append([]Mapping(nil), f.mappings...),
 		}
 		f.mutex.Unlock()
 	}
@@ -0,0 +1,326 @@
+package token
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A Mapping maps the bytes of a generated file, from Offset up to the
+// Offset of the next mapping, to the position Source in an original
+// file. An invalid Source marks the bytes as not originating from any
+// original file.
+type Mapping struct {
+	Offset int      // byte offset in the generated file
+	Source Position // position in the original file
+	Name   string   // name of the original identifier; or ""
+}
+
+// A SourceMap maps the byte offsets of a generated file (e.g. Go or
+// HTML generated from a .tgo file) to the positions in the original
+// files they originate from.
+//
+// The mappings are usually recorded while the generated file is written,
+// before its size is known; the line offsets of the generated file,
+// needed for the Source Map v3 format, can be set at any time before
+// the source map is marshaled.
+//
+// The columns of the Source Map v3 format are measured in UTF-16 code
+// units, the columns of a [Position] in bytes. The columns are converted
+// with the contents of the files, when they are set (see
+// [SourceMap.SetLinesForContent] and [SourceMap.SetSourceContent]).
+type SourceMap struct {
+	file string
+
+	mutex    sync.Mutex
+	lines    []int  // offsets of the first bytes of the lines of the generated file
+	content  []byte // content of the generated file; or nil
+	sources  map[string][]byte
+	mappings []Mapping
+}
+
+// NewSourceMap returns an empty source map of the generated file.
+func NewSourceMap(file string) *SourceMap {
+	return &SourceMap{file: file, lines: []int{0}}
+}
+
+// File returns the name of the generated file.
+func (m *SourceMap) File() string {
+	return m.file
+}
+
+// AddLine adds the line offset for a new line of the generated file.
+// The line offset must be larger than the offset for the previous
+// line; otherwise the line offset is ignored.
+func (m *SourceMap) AddLine(offset int) {
+	m.mutex.Lock()
+	if i := len(m.lines); m.lines[i-1] < offset {
+		m.lines = append(m.lines, offset)
+	}
+	m.mutex.Unlock()
+}
+
+// SetLinesForContent sets the line offsets for the given content
+// of the generated file. The content is also used to convert the
+// columns of the generated file to UTF-16 code units.
+func (m *SourceMap) SetLinesForContent(content []byte) {
+	lines := []int{0}
+	for offset, b := range content {
+		if b == '\n' && offset+1 < len(content) {
+			lines = append(lines, offset+1)
+		}
+	}
+	m.mutex.Lock()
+	m.lines = lines
+	m.content = content
+	m.mutex.Unlock()
+}
+
+// SetSourceContent sets the content of the original file filename,
+// used to convert the columns of its positions to UTF-16 code units.
+func (m *SourceMap) SetSourceContent(filename string, content []byte) {
+	m.mutex.Lock()
+	if m.sources == nil {
+		m.sources = make(map[string][]byte)
+	}
+	m.sources[filename] = content
+	m.mutex.Unlock()
+}
+
+// Add maps the bytes of the generated file starting at offset to the
+// position src, name is the name of the original identifier; or "".
+// The offset must be larger than the offset of the previously added
+// mapping; otherwise the mapping is ignored.
+func (m *SourceMap) Add(offset int, src Position, name string) {
+	m.mutex.Lock()
+	if i := len(m.mappings); i == 0 || m.mappings[i-1].Offset < offset {
+		m.mappings = append(m.mappings, Mapping{offset, src, name})
+	}
+	m.mutex.Unlock()
+}
+
+// Mappings returns the mappings of m, sorted by their offsets.
+func (m *SourceMap) Mappings() []Mapping {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return slices.Clone(m.mappings)
+}
+
+// Lookup returns the original position of the byte at the offset of
+// the generated file, i.e. the position of the mapping of the byte.
+// The position is invalid if the byte is not mapped.
+func (m *SourceMap) Lookup(offset int) Position {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if i := mappingIndex(m.mappings, offset); i >= 0 {
+		return m.mappings[i].Source
+	}
+	return Position{}
+}
+
+// mappingIndex returns the index of the mapping of the
+// byte at the offset in mappings; or -1 if there is none.
+func mappingIndex(mappings []Mapping, offset int) int {
+	i, found := slices.BinarySearchFunc(mappings, offset, func(m Mapping, offset int) int {
+		return m.Offset - offset
+	})
+	if !found {
+		i--
+	}
+	return i
+}
+
+// Compose returns the source map of the generated file of m, that maps
+// through inner: the positions of m in the generated file of inner are
+// replaced with the positions they originate from, as mapped by inner.
+// E.g. if m maps HTML to the Go code it was generated from and inner
+// maps that Go code to a .tgo file, the result maps the HTML to the
+// .tgo file. The other positions of m are kept.
+//
+// The bytes mapped by a mapping of m are assumed to be copied from the
+// bytes starting at its position in the generated file of inner, the
+// mapping is split where these bytes are mapped by different mappings
+// of inner. The last mapping of m is only split when the content of the
+// generated file of m is set (see [SourceMap.SetLinesForContent]).
+func (m *SourceMap) Compose(inner *SourceMap) *SourceMap {
+	inner.mutex.Lock()
+	innerFile, innerMappings := inner.file, slices.Clone(inner.mappings)
+	inner.mutex.Unlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res := &SourceMap{file: m.file, lines: slices.Clone(m.lines), content: m.content}
+	for i, mp := range m.mappings {
+		if !mp.Source.IsValid() || mp.Source.Filename != innerFile {
+			res.mappings = append(res.mappings, mp)
+			continue
+		}
+		start := mp.Source.Offset
+		j := mappingIndex(innerMappings, start)
+		if j >= 0 {
+			in := innerMappings[j]
+			mp.Source = in.Source
+			if mp.Name == "" {
+				mp.Name = in.Name
+			}
+		} else {
+			mp.Source = Position{}
+		}
+		res.mappings = append(res.mappings, mp)
+
+		// Split the range of mp at the following mappings of inner.
+		size := len(m.content) - mp.Offset // size of the range of mp
+		if i+1 < len(m.mappings) {
+			size = m.mappings[i+1].Offset - mp.Offset
+		}
+		for _, in := range innerMappings[j+1:] {
+			if in.Offset >= start+size {
+				break
+			}
+			res.mappings = append(res.mappings, Mapping{mp.Offset + in.Offset - start, in.Source, in.Name})
+		}
+	}
+	return res
+}
+
+// jsonSourceMap is the Source Map v3 format.
+type jsonSourceMap struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file,omitempty"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// MarshalJSON returns the source map in the Source Map v3 format. The
+// columns are converted to UTF-16 code units with the contents of the
+// files; the columns of the files without contents are kept in bytes.
+func (m *SourceMap) MarshalJSON() ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sm := jsonSourceMap{Version: 3, File: m.file, Sources: []string{}, Names: []string{}}
+	sources := make(map[string]int)
+	names := make(map[string]int)
+	index := func(list *[]string, indices map[string]int, s string) int {
+		i, ok := indices[s]
+		if !ok {
+			i = len(*list)
+			indices[s] = i
+			*list = append(*list, s)
+		}
+		return i
+	}
+
+	// The fields of the segments are relative to the previous segment,
+	// the generated column to the previous segment of the line.
+	var (
+		b                                     strings.Builder
+		line, col, src, srcLine, srcCol, name int
+		first                                 = true // first segment of the line
+	)
+	for _, mp := range m.mappings {
+		l := searchInts(m.lines, mp.Offset)
+		if l < 0 {
+			continue
+		}
+		for ; line < l; line++ {
+			b.WriteByte(';')
+			col = 0
+			first = true
+		}
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+
+		c := utf16Column(m.content, m.lines[l], mp.Offset)
+		writeVLQ(&b, c-col)
+		col = c
+		if !mp.Source.IsValid() {
+			continue
+		}
+		s := index(&sm.Sources, sources, mp.Source.Filename)
+		writeVLQ(&b, s-src)
+		writeVLQ(&b, mp.Source.Line-1-srcLine)
+		sc := utf16Column(m.sources[mp.Source.Filename], mp.Source.Offset-(mp.Source.Column-1), mp.Source.Offset)
+		writeVLQ(&b, sc-srcCol)
+		src, srcLine, srcCol = s, mp.Source.Line-1, sc
+		if mp.Name != "" {
+			n := index(&sm.Names, names, mp.Name)
+			writeVLQ(&b, n-name)
+			name = n
+		}
+	}
+	sm.Mappings = b.String()
+	return json.Marshal(sm)
+}
+
+// utf16Column returns the (0-based) column of the byte at the offset,
+// in the line starting at lineStart of content, in UTF-16 code units.
+// The column is measured in bytes when the line is not in content.
+func utf16Column(content []byte, lineStart, offset int) int {
+	if lineStart < 0 || lineStart > offset || offset > len(content) {
+		return offset - lineStart
+	}
+	n := 0
+	for b := content[lineStart:offset]; len(b) > 0; {
+		r, size := utf8.DecodeRune(b)
+		if r >= 0x10000 {
+			n += 2 // surrogate pair
+		} else {
+			n++
+		}
+		b = b[size:]
+	}
+	return n
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes v as a Base64 VLQ, as used by the
+// mappings of the Source Map v3 format.
+func writeVLQ(b *strings.Builder, v int) {
+	u := uint(v) << 1
+	if v < 0 {
+		u = uint(-v)<<1 | 1
+	}
+	for {
+		digit := u & 0x1f
+		u >>= 5
+		if u != 0 {
+			digit |= 0x20
+		}
+		b.WriteByte(base64Digits[digit])
+		if u == 0 {
+			return
+		}
+	}
+}
+
+// AddSourceMapping maps the bytes of the generated file f starting at
+// offset to the position src, name is the name of the original
+// identifier; or "". The offset must be larger than the offset of the
+// previously added mapping and smaller than the file size; otherwise
+// the mapping is ignored.
+//
+// AddSourceMapping is typically used to record the origin of the
+// pieces of generated code, as they are printed.
+func (f *File) AddSourceMapping(offset int, src Position, name string) {
+	f.mutex.Lock()
+	if i := len(f.mappings); (i == 0 || f.mappings[i-1].Offset < offset) && offset < f.size {
+		f.mappings = append(f.mappings, Mapping{offset, src, name})
+	}
+	f.mutex.Unlock()
+}
+
+// SourceMap returns the source map of the generated file f, with
+// the lines of f and the mappings added by [File.AddSourceMapping].
+func (f *File) SourceMap() *SourceMap {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return &SourceMap{file: f.name, lines: slices.Clone(f.lines), mappings: slices.Clone(f.mappings)}
+}
@@ -0,0 +1,167 @@
+package token
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func pos(filename string, offset, line, column int) Position {
+	return Position{Filename: filename, Offset: offset, Line: line, Column: column}
+}
+
+func TestSourceMapJSON(t *testing.T) {
+	m := NewSourceMap("out.go")
+	m.Add(0, pos("a.tgo", 0, 1, 1), "")
+	m.Add(1, Position{}, "")
+	m.Add(3, pos("a.tgo", 20, 2, 5), "x")
+	m.Add(4, pos("b.tgo", 0, 1, 1), "")
+	m.Add(4, pos("b.tgo", 7, 1, 8), "") // ignored, same offset
+	m.Add(40, pos("b.tgo", 7, 1, 17), "x")
+	m.SetLinesForContent([]byte("ab\ncd" + strings.Repeat(" ", 40) + "\n"))
+
+	got, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"version":3,"file":"out.go","sources":["a.tgo","b.tgo"],"names":["x"],"mappings":"AAAA,C;AACIA,CCDJ,oCAAgBA"}`
+	if string(got) != want {
+		t.Errorf("json.Marshal(m) =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSourceMapLookup(t *testing.T) {
+	m := NewSourceMap("out.go")
+	m.Add(2, pos("a.tgo", 10, 2, 3), "")
+	m.Add(5, Position{}, "")
+	m.Add(8, pos("a.tgo", 30, 4, 1), "")
+
+	for _, test := range []struct {
+		offset int
+		want   Position
+	}{
+		{0, Position{}},
+		{2, pos("a.tgo", 10, 2, 3)},
+		{4, pos("a.tgo", 10, 2, 3)},
+		{5, Position{}},
+		{8, pos("a.tgo", 30, 4, 1)},
+		{100, pos("a.tgo", 30, 4, 1)},
+	} {
+		if got := m.Lookup(test.offset); got != test.want {
+			t.Errorf("Lookup(%d) = %v; want %v", test.offset, got, test.want)
+		}
+	}
+}
+
+func TestSourceMapCompose(t *testing.T) {
+	// goMap maps out.go to page.tgo.
+	goMap := NewSourceMap("out.go")
+	goMap.Add(0, Position{}, "")
+	goMap.Add(10, pos("page.tgo", 4, 1, 5), "Title")
+	goMap.Add(20, pos("page.tgo", 15, 2, 3), "")
+
+	// htmlMap maps out.html to out.go and to style.css.
+	htmlMap := NewSourceMap("out.html")
+	htmlMap.Add(0, pos("out.go", 12, 2, 3), "")
+	htmlMap.Add(6, pos("style.css", 0, 1, 1), "")
+	htmlMap.Add(9, pos("out.go", 25, 3, 6), "title")
+	htmlMap.Add(12, pos("out.go", 5, 1, 6), "")
+
+	got := htmlMap.Compose(goMap)
+	if got.File() != "out.html" {
+		t.Errorf("File() = %q; want out.html", got.File())
+	}
+	want := []Mapping{
+		{0, pos("page.tgo", 4, 1, 5), "Title"},
+		{6, pos("style.css", 0, 1, 1), ""},
+		{9, pos("page.tgo", 15, 2, 3), "title"},
+		{12, Position{}, ""},
+	}
+	mappings := got.Mappings()
+	if len(mappings) != len(want) {
+		t.Fatalf("Mappings() = %v; want %v", mappings, want)
+	}
+	for i := range want {
+		if mappings[i] != want[i] {
+			t.Errorf("Mappings()[%d] = %v; want %v", i, mappings[i], want[i])
+		}
+	}
+}
+
+func TestFileSourceMap(t *testing.T) {
+	const src = "package p\n\nvar x = 1\n"
+	fset := NewFileSet()
+	f := fset.AddFile("p.go", -1, len(src))
+	f.SetLinesForContent([]byte(src))
+	f.AddSourceMapping(0, pos("p.tgo", 0, 1, 1), "")
+	f.AddSourceMapping(15, pos("p.tgo", 30, 4, 5), "x")
+	f.AddSourceMapping(len(src), pos("p.tgo", 40, 5, 1), "") // ignored, out of range
+
+	got, err := json.Marshal(f.SourceMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"version":3,"file":"p.go","sources":["p.tgo"],"names":["x"],"mappings":"AAAA;;IAGIA"}`
+	if string(got) != want {
+		t.Errorf("json.Marshal(f.SourceMap()) =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSourceMapComposeSplit(t *testing.T) {
+	// goMap maps out.go to page.tgo.
+	goMap := NewSourceMap("out.go")
+	goMap.Add(10, pos("page.tgo", 4, 1, 5), "")
+	goMap.Add(14, pos("page.tgo", 20, 2, 1), "x")
+	goMap.Add(16, Position{}, "")
+	goMap.Add(30, pos("page.tgo", 40, 3, 1), "")
+
+	// htmlMap maps out.html to out.go, the bytes 2-9 are copied from the
+	// bytes 12-19 of out.go and the bytes 9-20 from the bytes 28-39.
+	htmlMap := NewSourceMap("out.html")
+	htmlMap.Add(2, pos("out.go", 12, 1, 13), "")
+	htmlMap.Add(9, pos("out.go", 28, 1, 29), "")
+	htmlMap.SetLinesForContent([]byte(strings.Repeat("x", 20)))
+
+	want := []Mapping{
+		{2, pos("page.tgo", 4, 1, 5), ""},
+		{4, pos("page.tgo", 20, 2, 1), "x"},
+		{6, Position{}, ""},
+		{9, Position{}, ""},
+		{11, pos("page.tgo", 40, 3, 1), ""},
+	}
+	for _, got := range []*SourceMap{htmlMap.Compose(goMap), htmlMap.Compose(goMap)} {
+		mappings := got.Mappings()
+		if len(mappings) != len(want) {
+			t.Fatalf("Mappings() = %v; want %v", mappings, want)
+		}
+		for i := range want {
+			if mappings[i] != want[i] {
+				t.Errorf("Mappings()[%d] = %v; want %v", i, mappings[i], want[i])
+			}
+		}
+	}
+
+	// Composing a map with itself must not deadlock.
+	goMap.Compose(goMap)
+}
+
+func TestSourceMapJSONUTF16(t *testing.T) {
+	const (
+		gen = "é𝄞x\n"        // x at byte 6, UTF-16 column 3
+		src = "ab\n\"é𝄞\" y" // y at byte 12, UTF-16 column 6
+	)
+	m := NewSourceMap("out.html")
+	m.Add(0, pos("a.tgo", 3, 2, 1), "")
+	m.Add(6, pos("a.tgo", 12, 2, 10), "")
+	m.SetLinesForContent([]byte(gen))
+	m.SetSourceContent("a.tgo", []byte(src))
+
+	got, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"version":3,"file":"out.html","sources":["a.tgo"],"names":[],"mappings":"AACA,GAAM"}`
+	if string(got) != want {
+		t.Errorf("json.Marshal(m) =\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -133,6 +133,7 @@ const (
 	END_TAG         Token = 0xffffff // </
 	STRING_TEMPLATE Token = 0xffffff + 1
 	AT              Token = 0xffffff + 2 // @
+	TEMPLATE_LBRACE Token = 0xffffff + 3 // \{
 )
 
 var tokens = [...]string{
@@ -239,6 +240,7 @@ var tokens = [...]string{
 	END_TAG:         "</",
 	STRING_TEMPLATE: "STRING_TEMPLATE",
 	AT:              "@",
+	TEMPLATE_LBRACE: "\\{",
 }
 
 // String returns the string corresponding to the token tok.
@@ -177,6 +177,13 @@ type Config struct {
 	// for unused imports.
 	DisableUnusedImportCheck bool
 
+	// Interpolators lists, in order of preference, the interfaces whose
+	// implementations may be interpolated in template literals, in addition
+	// to the types of tgo.DynamicWriteAllowed and the implementations of
+	// tgo.Renderer. See DefaultInterpolators for fmt.Stringer and
+	// encoding.TextMarshaler.
+	Interpolators []Interpolator
+
 	// If a non-empty _ErrorURL format string is provided, it is used
 	// to format an error URL link that is appended to the first line
 	// of an error message. ErrorURL must be a format string containing
@@ -320,6 +327,17 @@ type Info struct {
 	// Version strings begin with “go”, like “go1.21”, and
 	// are suitable for use with the [go/version] package.
 	FileVersions map[*ast.File]string
+
+	// Interpolations maps the parts of template literals to the
+	// way their values are written. Dynamic tag names are omitted.
+	Interpolations map[*ast.TemplateLiteralPart]Interpolation
+
+	// ConstantParts maps the constant parts of template literals to
+	// their escaped output. Tools may write the output of these parts
+	// together with the surrounding text. For a template literal whose
+	// parts are all constant, the escaped output of the whole literal
+	// is recorded in Types as an untyped string constant.
+	ConstantParts map[*ast.TemplateLiteralPart]string
 }
 
 func (info *Info) recordTypes() bool {
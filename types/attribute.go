@@ -0,0 +1,112 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	. "github.com/mateusz834/tgoast/internal/types/errors"
+)
+
+// An attrKind describes how the value of an attribute is interpreted.
+type attrKind uint8
+
+const (
+	attrPlain attrKind = iota // text
+	attrURL                   // URL, e.g. href
+	attrJS                    // JavaScript, event handlers
+	attrCSS                   // CSS, style
+	numAttrKinds
+)
+
+// safeTypeNames are the names of the tgo types whose values are
+// written unescaped in the attributes of the corresponding kind.
+var safeTypeNames = [numAttrKinds]string{
+	attrPlain: "SafeAttr",
+	attrURL:   "SafeURL",
+	attrJS:    "SafeJS",
+	attrCSS:   "SafeCSS",
+}
+
+var attrKindNames = [numAttrKinds]string{
+	attrPlain: "text",
+	attrURL:   "URL",
+	attrJS:    "event handler",
+	attrCSS:   "style",
+}
+
+func (k attrKind) String() string { return attrKindNames[k] }
+
+// urlAttrs are the attributes that hold URLs.
+var urlAttrs = map[string]bool{
+	"action":     true,
+	"background": true,
+	"cite":       true,
+	"codebase":   true,
+	"data":       true,
+	"formaction": true,
+	"href":       true,
+	"icon":       true,
+	"longdesc":   true,
+	"manifest":   true,
+	"ping":       true,
+	"poster":     true,
+	"src":        true,
+	"srcset":     true,
+	"usemap":     true,
+}
+
+// attributeKind returns the kind of the attribute named name.
+func attributeKind(name string) attrKind {
+	name = strings.ToLower(name)
+	switch {
+	case urlAttrs[name]:
+		return attrURL
+	case strings.HasPrefix(name, "on"):
+		return attrJS
+	case name == "style":
+		return attrCSS
+	}
+	return attrPlain
+}
+
+// attributeName returns the name of attr, or "" if it is invalid.
+func attributeName(attr *ast.AttributeStmt) string {
+	if id, _ := attr.AttrName.(*ast.Ident); id != nil {
+		return id.Name
+	}
+	return ""
+}
+
+// mayBeUnsafeHTML reports whether a value of type t might be of
+// type tgo.UnsafeHTML.
+func (check *Checker) mayBeUnsafeHTML(t Type) bool {
+	if check.tgoUnsafeHTML == nil {
+		return false
+	}
+	if tpar, _ := Unalias(t).(*TypeParam); tpar != nil {
+		return !tpar.iface().typeSet().is(func(t *term) bool {
+			return t != nil && !t.includes(check.tgoUnsafeHTML)
+		})
+	}
+	return Identical(t, check.tgoUnsafeHTML)
+}
+
+// safeAttribute checks the value x of type t, interpolated in the value
+// of attr, that is of a tgo safe type. It reports whether t is a safe
+// type and whether it is permitted in attr, in that case the
+// interpolation has been recorded.
+func (check *Checker) safeAttribute(part *ast.TemplateLiteralPart, x *operand, t Type, attr *ast.AttributeStmt) (safe, ok bool) {
+	for kind, safe := range check.tgoSafeTypes {
+		if safe == nil || !Identical(t, safe) {
+			continue
+		}
+		name := attributeName(attr)
+		if attributeKind(name) != attrKind(kind) {
+			check.errorf(x, MismatchedSafeAttributeType, "cannot use %s in attribute %s (tgo.%s is permitted only in %s attributes)", x, name, safeTypeNames[kind], attrKind(kind))
+			return true, false
+		}
+		check.recordInterpolation(part, InterpolateSafe, nil)
+		return true, true
+	}
+	return false, false
+}
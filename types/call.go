@@ -169,6 +169,9 @@ func (check *Checker) instantiateSignature(pos token.Pos, expr ast.Expr, typ *Si
 }
 
 func (check *Checker) callExpr(x *operand, call *ast.CallExpr) exprKind {
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		check.calledFuncLit(lit, check.markup, check.tagCtxt)
+	}
 	ix := typeparams.UnpackIndexExpr(call.Fun)
 	if ix != nil {
 		if check.indexExpr(x, ix) {
@@ -296,6 +299,9 @@ func (check *Checker) callExpr(x *operand, call *ast.CallExpr) exprKind {
 
 	// evaluate arguments
 	args, atargs, atxlist := check.genericExprList(call.Args)
+	if call == check.goCall {
+		check.goArgs(args)
+	}
 	sig = check.arguments(call, sig, targs, xlist, args, atargs, atxlist)
 
 	if wasGeneric && sig.TypeParams().Len() == 0 {
@@ -82,6 +82,8 @@ type environment struct {
 	isPanic       map[*ast.CallExpr]bool // set of panic call expressions (used for termination check)
 	hasLabel      bool                   // set if a function makes use of labels (only ~1% of functions); unused outside functions
 	hasCallOrRecv bool                   // set if an expression contains a function call or channel receive operation
+	markup        markupContext          // whether markup is permitted in the current function
+	tagCtxt       stmtContext            // inOpenTag and inElementBody bits of the statement list being checked
 }
 
 // lookup looks up name in the current environment and returns the matching object, or nil.
@@ -183,6 +185,12 @@ type Checker struct {
 
 	tgoCtx                 Type
 	tgoDynamicWriteAllowed Type
+	tgoTagName             Type
+	tgoRenderer            *Interface
+	tgoUnsafeHTML          Type
+	tgoSafeTypes           [numAttrKinds]Type             // indexed by attrKind
+	funcLitMarkup          map[*ast.FuncLit]litContext    // contexts of function literals that are called
+	goCall                 *ast.CallExpr                  // call of the go statement being checked; or nil
 }
 
 // addDeclDep adds the dependency edge (check.decl -> to) if check.decl exists
@@ -718,3 +726,17 @@ func (check *Checker) recordScope(node ast.Node, scope *Scope) {
 		m[node] = scope
 	}
 }
+
+func (check *Checker) recordConstantPart(part *ast.TemplateLiteralPart, out string) {
+	assert(part != nil)
+	if m := check.ConstantParts; m != nil {
+		m[part] = out
+	}
+}
+
+func (check *Checker) recordInterpolation(part *ast.TemplateLiteralPart, strategy InterpolationStrategy, iface *Interface) {
+	assert(part != nil)
+	if m := check.Interpolations; m != nil {
+		m[part] = Interpolation{strategy, iface}
+	}
+}
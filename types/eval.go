@@ -56,6 +56,29 @@ func Eval(fset *token.FileSet, pkg *Package, pos token.Pos, expr string) (_ Type
 // untyped type rather than the respective context-specific type.
 func CheckExpr(fset *token.FileSet, pkg *Package, pos token.Pos, expr ast.Expr, info *Info) (err error) {
 	// determine scope
+	scope, pos, err := scopeAt(fset, pkg, pos)
+	if err != nil {
+		return err
+	}
+
+	// initialize checker
+	check := NewChecker(nil, fset, pkg, info)
+	check.scope = scope
+	check.pos = pos
+	defer check.handleBailout(&err)
+
+	// evaluate node
+	var x operand
+	check.rawExpr(nil, &x, expr, nil, true) // allow generic expressions
+	check.processDelayed(0)                 // incl. all functions
+	check.recordUntyped()
+
+	return nil
+}
+
+// scopeAt returns the scope of pkg at position pos, as described
+// by [CheckExpr], and the position to use for the scope lookups.
+func scopeAt(fset *token.FileSet, pkg *Package, pos token.Pos) (*Scope, token.Pos, error) {
 	var scope *Scope
 	if pkg == nil {
 		scope = Universe
@@ -79,21 +102,126 @@ func CheckExpr(fset *token.FileSet, pkg *Package, pos token.Pos, expr ast.Expr,
 			}
 			// s == nil || s == pkg.scope
 			if s == nil {
-				return fmt.Errorf("no position %s found in package %s", fset.Position(pos), pkg.name)
+				return nil, nopos, fmt.Errorf("no position %s found in package %s", fset.Position(pos), pkg.name)
 			}
 		}
 	}
+	return scope, pos, nil
+}
+
+// EvalInterpolation is like [Eval], but it evaluates expr as if it were
+// written inside \{...} at position pos of package pkg, i.e. as the
+// template literal part \{expr}. The expression may be followed by a
+// format verb or a layout, e.g. "price:%.2f". It returns the type and,
+// if constant, the value of the expression and the interpolation that
+// writes its value.
+//
+// The meaning of the parameters is the same as in [CheckInterpolation],
+// the attribute named attr is used if it is not empty. The positions of
+// the errors refer to the file "eval" holding the template literal
+// "\{expr}".
+func EvalInterpolation(conf *Config, fset *token.FileSet, pkg *Package, pos token.Pos, expr, attr string) (_ TypeAndValue, _ Interpolation, err error) {
+	// parse the part as a template literal statement
+	src := `"\{` + expr + `}"`
+	list, err := parser.ParseStmtListFrom(fset.AddFile("eval", -1, len(src)), src, 0)
+	if err != nil {
+		return TypeAndValue{}, Interpolation{}, err
+	}
+	var lit *ast.TemplateLiteralExpr
+	if len(list) == 1 {
+		if s, _ := list[0].(*ast.ExprStmt); s != nil {
+			lit, _ = s.X.(*ast.TemplateLiteralExpr)
+		}
+	}
+	if lit == nil || len(lit.Parts) != 1 || len(lit.Strings) != 2 || lit.Strings[0] != `"` || lit.Strings[1] != `"` {
+		return TypeAndValue{}, Interpolation{}, fmt.Errorf("invalid interpolation %q", expr)
+	}
+	part := lit.Parts[0]
+
+	var a *ast.AttributeStmt
+	if attr != "" {
+		a = &ast.AttributeStmt{AttrName: &ast.Ident{Name: attr}}
+	}
+	info := &Info{
+		Types:          make(map[ast.Expr]TypeAndValue),
+		Interpolations: make(map[*ast.TemplateLiteralPart]Interpolation),
+	}
+	err = CheckInterpolation(conf, fset, pkg, pos, part, a, info)
+	return info.Types[part.X], info.Interpolations[part], err
+}
+
+// CheckInterpolation type checks the template literal part as if it had
+// appeared at position pos of package pkg, which must import the tgo
+// package. As in [Config.Check], the value of the part must be writable:
+// it must satisfy tgo.DynamicWriteAllowed or be written by one of the
+// interpolators of conf (conf may be nil), unless the part has a format.
+// [Type] information about the expression of the part and the
+// [Interpolation] of the part are recorded in info.
+//
+// The position pos must be inside of a function that permits markup: a
+// tgo function, or a function literal called synchronously from one.
+// The innermost tag scope at pos determines where the part is written:
+// inside an open tag (an "OpenTag" scope) the part is checked as in the
+// value of attr, which must not be nil; elsewhere attr must be nil.
+//
+// The position pos has the same meaning as in [CheckExpr], except
+// that pkg must not be nil.
+func CheckInterpolation(conf *Config, fset *token.FileSet, pkg *Package, pos token.Pos, part *ast.TemplateLiteralPart, attr *ast.AttributeStmt, info *Info) (err error) {
+	if pkg == nil {
+		return fmt.Errorf("no package provided for interpolation")
+	}
+	scope, pos, err := scopeAt(fset, pkg, pos)
+	if err != nil {
+		return err
+	}
+
+	// the enclosing function must permit markup
+	fn := scope
+	for fn != nil && !fn.isFunc {
+		fn = fn.parent
+	}
+	switch {
+	case fn == nil || fn.markup == markupNone:
+		return fmt.Errorf("position %s is not inside a tgo function", fset.Position(pos))
+	case fn.markup == markupEscaping:
+		return fmt.Errorf("position %s is inside a function literal that is not called synchronously", fset.Position(pos))
+	case fn.markup == markupInGo:
+		return fmt.Errorf("position %s is inside a go statement", fset.Position(pos))
+	}
+
+	// find the innermost tag scope
+	tag := noTag
+	for s := scope; s != nil && tag == noTag; s = s.parent {
+		tag = s.tag
+	}
+	switch {
+	case tag == openTagScope && attr == nil:
+		return fmt.Errorf("position %s is inside an open tag, an attribute is required", fset.Position(pos))
+	case tag != openTagScope && attr != nil:
+		return fmt.Errorf("position %s is not inside an open tag, attribute %s is not permitted", fset.Position(pos), attributeName(attr))
+	}
 
 	// initialize checker
-	check := NewChecker(nil, fset, pkg, info)
+	check := NewChecker(conf, fset, pkg, info)
+	for _, imp := range pkg.imports {
+		if imp.path == tgoPath && imp.Complete() {
+			check.initTgo(imp)
+		}
+	}
+	if check.tgoDynamicWriteAllowed == nil {
+		return fmt.Errorf("package %s does not import %s", pkg.name, tgoPath)
+	}
 	check.scope = scope
 	check.pos = pos
 	defer check.handleBailout(&err)
 
-	// evaluate node
+	// evaluate part
 	var x operand
-	check.rawExpr(nil, &x, expr, nil, true) // allow generic expressions
-	check.processDelayed(0)                 // incl. all functions
+	check.expr(nil, &x, part.X)
+	if x.mode != invalid {
+		check.templateLiteralPart(part, &x, attr)
+	}
+	check.processDelayed(0)
 	check.recordUntyped()
 
 	return nil
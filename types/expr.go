@@ -1113,12 +1113,14 @@ func (check *Checker) exprInternal(T *target, x *operand, e ast.Expr, hint Type)
 				// them: use existing package-level declaration info.
 				decl := check.decl // capture for use in closure below
 				iota := check.iota // capture for use in closure below (go.dev/issue/22345)
+				// The markup context depends on how e is called, determine it now.
+				markup, tagCtxt := check.funcLitContext(e)
 				// Don't type-check right away because the function may
 				// be part of a type definition to which the function
 				// body refers. Instead, type-check as soon as possible,
 				// but before the enclosing scope contents changes (go.dev/issue/22992).
 				check.later(func() {
-					check.funcBody(decl, "<function literal>", sig, e.Body, iota)
+					check.funcBody(decl, "<function literal>", sig, e.Body, iota, markup, tagCtxt)
 				}).describef(e, "func literal")
 			}
 			x.mode = value
@@ -0,0 +1,150 @@
+package types
+
+import (
+	"github.com/mateusz834/tgoast/ast"
+	. "github.com/mateusz834/tgoast/internal/types/errors"
+)
+
+// An InterpolationStrategy describes how the value of a template
+// literal part is written.
+type InterpolationStrategy int
+
+const (
+	// InterpolateDynamic: the value satisfies tgo.DynamicWriteAllowed,
+	// it is written with tgo.DynamicWrite.
+	InterpolateDynamic InterpolationStrategy = iota
+
+	// InterpolateFormat: the part has a format verb or layout
+	// (see ast.TemplateLiteralPart), the formatted string is escaped.
+	InterpolateFormat
+
+	// InterpolateString: the string returned by the only method
+	// of the interface (e.g. fmt.Stringer) is escaped.
+	InterpolateString
+
+	// InterpolateText: the text returned by the only method of the
+	// interface (e.g. encoding.TextMarshaler) is escaped, a non-nil
+	// error is returned from the enclosing tgo function.
+	InterpolateText
+
+	// InterpolateRender: the only method of the interface (e.g.
+	// tgo.Renderer) writes the value itself, the output is not escaped.
+	// A non-nil error is returned from the enclosing tgo function.
+	// Not permitted in attributes.
+	InterpolateRender
+
+	// InterpolateSafe: the value is of a tgo safe attribute type
+	// (tgo.SafeURL, tgo.SafeAttr, tgo.SafeJS or tgo.SafeCSS) matching
+	// the attribute, it is written unescaped.
+	InterpolateSafe
+)
+
+var interpolationStrategies = [...]string{
+	InterpolateDynamic: "dynamic",
+	InterpolateFormat:  "format",
+	InterpolateString:  "string",
+	InterpolateText:    "text",
+	InterpolateRender:  "render",
+	InterpolateSafe:    "safe",
+}
+
+func (s InterpolationStrategy) String() string {
+	if 0 <= s && int(s) < len(interpolationStrategies) {
+		return interpolationStrategies[s]
+	}
+	return "invalid"
+}
+
+// An Interpolator permits the implementations of Interface to be
+// interpolated in template literals. Interface must have exactly one
+// method, it is called as described by Strategy, which must not be
+// InterpolateDynamic or InterpolateFormat.
+type Interpolator struct {
+	Interface *Interface
+	Strategy  InterpolationStrategy
+}
+
+// An Interpolation describes how the value of a template literal
+// part is written. Interface is the interface implemented by the
+// value, it is nil for InterpolateDynamic and InterpolateFormat.
+type Interpolation struct {
+	Strategy  InterpolationStrategy
+	Interface *Interface
+}
+
+// DefaultInterpolators returns the interpolators for the
+// implementations of fmt.Stringer and encoding.TextMarshaler,
+// in this order.
+func DefaultInterpolators() []Interpolator {
+	method := func(name string, results ...Type) *Func {
+		vars := make([]*Var, len(results))
+		for i, t := range results {
+			vars[i] = NewVar(nopos, nil, "", t)
+		}
+		return NewFunc(nopos, nil, name, NewSignatureType(nil, nil, nil, nil, NewTuple(vars...), false))
+	}
+	stringer := NewInterfaceType([]*Func{method("String", Typ[String])}, nil).Complete()
+	marshaler := NewInterfaceType([]*Func{method("MarshalText", NewSlice(universeByte), universeError)}, nil).Complete()
+	return []Interpolator{
+		{stringer, InterpolateString},
+		{marshaler, InterpolateText},
+	}
+}
+
+// interpolation checks that the value x of the template literal part
+// can be written and records the strategy. In attributes (attr != nil)
+// tgo.UnsafeHTML and interpolators that write unescaped output are not
+// permitted, instead the tgo safe attribute types are. It returns the
+// strategy and the type of the written value; ok is false if x cannot
+// be written.
+func (check *Checker) interpolation(part *ast.TemplateLiteralPart, x *operand, attr *ast.AttributeStmt) (strategy InterpolationStrategy, t Type, ok bool) {
+	tp := NewTypeParam(NewTypeName(nopos, check.pkg, "T", nil), check.tgoDynamicWriteAllowed)
+	err := check.newError(InvalidTemplateLiteralType)
+	targs := check.infer(part, []*TypeParam{tp}, nil, NewTuple(NewVar(nopos, check.pkg, "t", tp)), []*operand{x}, false, err)
+	if targs == nil {
+		if !err.empty() {
+			// TODO: is this reachable? Figure a case out and add a test case, otherwise panic.
+			err.report()
+		}
+		return 0, nil, false
+	}
+
+	cause := ""
+	if check.implements(part.Pos(), targs[0], check.tgoDynamicWriteAllowed, true, &cause) {
+		if attr != nil && check.mayBeUnsafeHTML(targs[0]) {
+			check.errorf(x, UnsafeHTMLInAttribute, "cannot use %s in attribute %s (tgo.UnsafeHTML is not permitted in attributes)", x, attributeName(attr))
+			return 0, nil, false
+		}
+		check.recordInterpolation(part, InterpolateDynamic, nil)
+		return InterpolateDynamic, targs[0], true
+	}
+	if attr != nil {
+		if safe, ok := check.safeAttribute(part, x, targs[0], attr); safe {
+			return InterpolateSafe, targs[0], ok
+		}
+	}
+
+	interpolators := check.conf.Interpolators
+	if check.tgoRenderer != nil {
+		interpolators = append([]Interpolator{{check.tgoRenderer, InterpolateRender}}, interpolators...)
+	}
+	render := false
+	for _, ip := range interpolators {
+		if ip.Interface == nil || !check.implements(part.Pos(), targs[0], ip.Interface, false, nil) {
+			continue
+		}
+		if attr != nil && ip.Strategy == InterpolateRender {
+			render = true
+			continue
+		}
+		check.recordInterpolation(part, ip.Strategy, ip.Interface)
+		return ip.Strategy, targs[0], true
+	}
+
+	if render {
+		check.errorf(x, InvalidTemplateLiteralType, "%s writes unescaped output and cannot be interpolated in an attribute", x)
+		return 0, nil, false
+	}
+	check.errorf(x, InvalidTemplateLiteralType, "%s", cause)
+	return 0, nil, false
+}
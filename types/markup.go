@@ -0,0 +1,169 @@
+package types
+
+import (
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/constant"
+	. "github.com/mateusz834/tgoast/internal/types/errors"
+)
+
+// A markupContext describes whether markup (tags, attributes and
+// template literals) is permitted in a function body.
+type markupContext uint8
+
+const (
+	// markupNone: not a tgo function, markup is not permitted.
+	markupNone markupContext = iota
+	// markupOk: a tgo function, or a function literal called
+	// synchronously from one, markup is permitted.
+	markupOk
+	// markupEscaping: a function literal of a tgo function,
+	// that is not called synchronously.
+	markupEscaping
+	// markupInGo: a function literal started by a go statement
+	// (directly or through enclosing function literals).
+	markupInGo
+)
+
+// tgoPath is the import path of the tgo package.
+const tgoPath = "github.com/mateusz834/tgo"
+
+// initTgo looks up the types of the tgo package imp
+// used by the checker.
+func (check *Checker) initTgo(imp *Package) {
+	check.tgoCtx = imp.Scope().Lookup("Ctx").Type()
+	check.tgoDynamicWriteAllowed = imp.Scope().Lookup("DynamicWriteAllowed").Type()
+	if obj := imp.Scope().Lookup("TagName"); obj != nil {
+		check.tgoTagName = obj.Type()
+	}
+	if obj := imp.Scope().Lookup("UnsafeHTML"); obj != nil {
+		check.tgoUnsafeHTML = obj.Type()
+	}
+	for kind, name := range safeTypeNames {
+		if obj := imp.Scope().Lookup(name); obj != nil {
+			check.tgoSafeTypes[kind] = obj.Type()
+		}
+	}
+	if obj := imp.Scope().Lookup("Renderer"); obj != nil {
+		check.tgoRenderer, _ = obj.Type().Underlying().(*Interface)
+	}
+}
+
+// isTgoSignature reports whether sig is a signature of a tgo function,
+// i.e. func(tgo.Ctx, ...) error.
+func (check *Checker) isTgoSignature(sig *Signature) bool {
+	return check.tgoCtx != nil && sig.params.Len() > 0 && sig.results.Len() == 1 &&
+		Identical(sig.params.At(0).Type(), check.tgoCtx) &&
+		Identical(sig.results.At(0).Type(), Universe.Lookup("error").Type())
+}
+
+// A litContext describes how a function literal is called.
+type litContext struct {
+	markup  markupContext
+	tagCtxt stmtContext // inOpenTag and inElementBody bits of the call
+}
+
+// calledFuncLit records that lit is called in the markup context markup
+// and in the tag context tagCtxt. The first call wins, so that the go and
+// defer statements can record their function literals before the call
+// expression is checked.
+func (check *Checker) calledFuncLit(lit *ast.FuncLit, markup markupContext, tagCtxt stmtContext) {
+	if _, ok := check.funcLitMarkup[lit]; ok {
+		return
+	}
+	if check.funcLitMarkup == nil {
+		check.funcLitMarkup = make(map[*ast.FuncLit]litContext)
+	}
+	check.funcLitMarkup[lit] = litContext{markup: markup, tagCtxt: tagCtxt}
+}
+
+// funcLitContext returns the markup and tag contexts for the body of lit,
+// that appears in the current function. A tgo function literal permits
+// markup on its own, unless started by a go statement (see funcBody).
+func (check *Checker) funcLitContext(lit *ast.FuncLit) (markupContext, stmtContext) {
+	if c, ok := check.funcLitMarkup[lit]; ok {
+		delete(check.funcLitMarkup, lit)
+		return c.markup, c.tagCtxt
+	}
+	switch check.markup {
+	case markupNone, markupInGo:
+		return check.markup, 0
+	default:
+		return markupEscaping, 0
+	}
+}
+
+// goArgs reports the arguments args of the call of a go statement that
+// would let the started function write to a tgo.Ctx concurrently: the
+// values of type tgo.Ctx and the tgo function literals.
+func (check *Checker) goArgs(args []*operand) {
+	if check.tgoCtx == nil {
+		return
+	}
+	for _, x := range args {
+		if x.mode == invalid {
+			continue
+		}
+		if Identical(x.typ, check.tgoCtx) {
+			check.errorf(x, MarkupInGoStmt, "cannot pass %s to a go statement", x)
+			continue
+		}
+		if _, ok := ast.Unparen(x.expr).(*ast.FuncLit); ok {
+			if sig, ok := under(x.typ).(*Signature); ok && check.isTgoSignature(sig) {
+				check.error(x, MarkupInGoStmt, "cannot pass tgo function literal to a go statement")
+			}
+		}
+	}
+}
+
+// misplacedMarkup reports the markup at (described by what) that is
+// not permitted in the current function. The code is used for markup
+// in non-tgo functions.
+func (check *Checker) misplacedMarkup(at positioner, code Code, what string) {
+	switch check.markup {
+	case markupInGo:
+		check.errorf(at, MarkupInGoStmt, "%s is not allowed inside a go statement", what)
+	case markupEscaping:
+		check.errorf(at, EscapingMarkup, "%s is not allowed inside a function literal that is not called synchronously", what)
+	default:
+		check.errorf(at, code, "%s is not allowed inside a non-tgo function", what)
+	}
+}
+
+// dynamicTagName checks the dynamic tag name n. It must be of type
+// tgo.TagName or a constant string that is a valid tag name.
+func (check *Checker) dynamicTagName(n *ast.TemplateLiteralPart) {
+	var x operand
+	check.expr(nil, &x, n.X)
+	if x.mode == invalid {
+		return
+	}
+
+	if x.mode == constant_ && isString(x.typ) {
+		if name := constant.StringVal(x.val); !isValidTagName(name) {
+			check.errorf(&x, InvalidTagName, "invalid tag name %q", name)
+		}
+		check.assignment(&x, nil, "tag name")
+		return
+	}
+
+	if check.tgoTagName == nil || !Identical(x.typ, check.tgoTagName) {
+		check.errorf(&x, InvalidTagName, "cannot use %s as tag name (must be tgo.TagName or a constant string)", &x)
+	}
+}
+
+// isValidTagName reports whether name is a valid tag name: an ASCII
+// letter, followed by ASCII letters, digits and hyphens.
+func isValidTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -194,9 +194,8 @@ func (check *Checker) importPackage(at positioner, path, dir string) *Package {
 		}
 	}
 
-	if path == "github.com/mateusz834/tgo" && imp.Complete() {
-		check.tgoCtx = imp.Scope().Lookup("Ctx").Type()
-		check.tgoDynamicWriteAllowed = imp.Scope().Lookup("DynamicWriteAllowed").Type()
+	if path == tgoPath && imp.Complete() {
+		check.initTgo(imp)
 	}
 
 	// package should be complete or marked fake, but be cautious
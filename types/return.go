@@ -21,8 +21,7 @@ func (check *Checker) isTerminating(s ast.Stmt, label string) bool {
 
 	case *ast.BadStmt, *ast.DeclStmt, *ast.EmptyStmt, *ast.SendStmt,
 		*ast.IncDecStmt, *ast.AssignStmt, *ast.GoStmt, *ast.DeferStmt,
-		*ast.RangeStmt, *ast.ElementBlockStmt, *ast.OpenTag, *ast.EndTag,
-		*ast.AttributeStmt:
+		*ast.RangeStmt, *ast.OpenTag, *ast.EndTag, *ast.AttributeStmt:
 		// no chance
 
 	case *ast.LabeledStmt:
@@ -58,6 +57,11 @@ func (check *Checker) isTerminating(s ast.Stmt, label string) bool {
 	case *ast.TypeSwitchStmt:
 		return check.isTerminatingSwitch(s.Body, label)
 
+	case *ast.ElementBlockStmt:
+		// The end tag is never reached after a terminating body,
+		// a return writes it implicitly (see ast.ElementBlockStmt).
+		return check.isTerminatingList(s.Body, "")
+
 	case *ast.SelectStmt:
 		for _, s := range s.Body.List {
 			cc := s.(*ast.CommClause)
@@ -31,12 +31,23 @@ type Scope struct {
 	pos, end token.Pos         // scope extent; may be invalid
 	comment  string            // for debugging only
 	isFunc   bool              // set if this is a function scope (internal use only)
+	markup   markupContext     // for function scopes: whether markup is permitted in the function
+	tag      tagKind           // for tag scopes: the kind of the tag scope
 }
 
+// A tagKind describes a tag scope.
+type tagKind uint8
+
+const (
+	noTag        tagKind = iota // not a tag scope
+	openTagScope                // the body of an open tag
+	elementScope                // the body of an element
+)
+
 // NewScope returns a new, empty scope contained in the given parent
 // scope, if any. The comment is for debugging only.
 func NewScope(parent *Scope, pos, end token.Pos, comment string) *Scope {
-	s := &Scope{parent, nil, 0, nil, pos, end, comment, false}
+	s := &Scope{parent, nil, 0, nil, pos, end, comment, false, markupNone, noTag}
 	// don't add children to Universe scope!
 	if parent != nil && parent != Universe {
 		parent.children = append(parent.children, s)
@@ -7,7 +7,11 @@
 package types
 
 import (
+	"fmt"
+	"html"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/mateusz834/tgoast/ast"
 	"github.com/mateusz834/tgoast/constant"
@@ -16,7 +20,7 @@ import (
 	"github.com/mateusz834/tgoast/token"
 )
 
-func (check *Checker) funcBody(decl *declInfo, name string, sig *Signature, body *ast.BlockStmt, iota constant.Value) {
+func (check *Checker) funcBody(decl *declInfo, name string, sig *Signature, body *ast.BlockStmt, iota constant.Value, markup markupContext, tagCtxt stmtContext) {
 	if check.conf.IgnoreFuncBodies {
 		panic("function body not ignored")
 	}
@@ -31,20 +35,24 @@ func (check *Checker) funcBody(decl *declInfo, name string, sig *Signature, body
 		check.environment = env
 		check.indent = indent
 	}(check.environment, check.indent)
+	if markup != markupInGo && check.isTgoSignature(sig) {
+		markup = markupOk
+	}
+	sig.scope.markup = markup // see CheckInterpolation
 	check.environment = environment{
-		decl:  decl,
-		scope: sig.scope,
-		iota:  iota,
-		sig:   sig,
+		decl:   decl,
+		scope:  sig.scope,
+		iota:   iota,
+		sig:    sig,
+		markup: markup,
 	}
 	check.indent = 0
 
+	// A function literal called synchronously inside of an open
+	// tag or an element body is in the tag context of the call.
 	var ctxt stmtContext
-	if sig.params.Len() > 0 && sig.results.Len() == 1 {
-		if sig.params.At(0).Type() == check.tgoCtx &&
-			sig.results.At(0).Type() == Universe.Lookup("error").Type() {
-			ctxt |= inTgoFunc
-		}
+	if markup == markupOk {
+		ctxt |= inTgoFunc | tagCtxt
 	}
 
 	check.stmtList(ctxt, body.List)
@@ -128,6 +136,11 @@ func trimTrailingEmptyStmts(list []ast.Stmt) []ast.Stmt {
 }
 
 func (check *Checker) stmtList(ctxt stmtContext, list []ast.Stmt) {
+	defer func(tagCtxt stmtContext) {
+		check.tagCtxt = tagCtxt
+	}(check.tagCtxt)
+	check.tagCtxt = ctxt & (inOpenTag | inElementBody)
+
 	ok := ctxt&fallthroughOk != 0
 	inner := ctxt &^ fallthroughOk
 	list = trimTrailingEmptyStmts(list) // trailing empty statements are "invisible" to fallthrough analysis
@@ -381,29 +394,162 @@ L:
 // 	return
 // }
 
-func (check *Checker) templateLiteralExpr(v *ast.TemplateLiteralExpr) {
-	for _, v := range v.Parts {
-		var o operand
-		check.expr(nil, &o, v.X)
-		if check.tgoDynamicWriteAllowed != nil {
-			tp := NewTypeParam(NewTypeName(nopos, check.pkg, "T", nil), check.tgoDynamicWriteAllowed)
-			err := check.newError(InvalidTemplateLiteralType)
-			targs := check.infer(v, []*TypeParam{tp}, nil, NewTuple(NewVar(nopos, check.pkg, "t", tp)), []*operand{&o}, false, err)
-			if targs == nil {
-				if !err.empty() {
-					// TODO: is this reachable? Figure a case out and add a test case, otherwise panic.
-					err.report()
-					return
-				}
-				return
+// templateLiteralExpr checks the parts of the template literal v,
+// attr is not nil when v is the value of an attribute. The escaped
+// output of a template literal with only constant parts is recorded
+// as its untyped string constant value.
+func (check *Checker) templateLiteralExpr(v *ast.TemplateLiteralExpr, attr *ast.AttributeStmt) {
+	var out strings.Builder // escaped output, while all parts are constant
+	folded := true
+	for i, part := range v.Parts {
+		var x operand
+		check.expr(nil, &x, part.X)
+		s, ok := check.templateLiteralPart(part, &x, attr)
+		if ok {
+			check.recordConstantPart(part, s)
+		}
+		if folded {
+			str, strOk := templateLiteralString(v, i)
+			folded = ok && strOk
+			out.WriteString(html.EscapeString(str))
+			out.WriteString(s)
+		}
+	}
+	if str, ok := templateLiteralString(v, len(v.Parts)); folded && ok {
+		out.WriteString(html.EscapeString(str))
+		check.recordTypeAndValue(v, constant_, Typ[UntypedString], constant.MakeString(out.String()))
+	}
+}
+
+// templateLiteralPart checks the template literal part p with the value x.
+// It returns the escaped output of p, if it is constant.
+func (check *Checker) templateLiteralPart(p *ast.TemplateLiteralPart, x *operand, attr *ast.AttributeStmt) (string, bool) {
+	if p.Format != nil {
+		check.templateLiteralFormat(x, p.Format)
+		check.recordInterpolation(p, InterpolateFormat, nil)
+		verb, ok := p.Format.(*ast.FormatVerb)
+		if !ok || x.mode != constant_ {
+			return "", false
+		}
+		// Named types might change the output with their methods.
+		t, _ := Unalias(x.typ).(*Basic)
+		if t == nil {
+			return "", false
+		}
+		val, ok := constantInterface(x.val, t)
+		if !ok {
+			return "", false
+		}
+		return html.EscapeString(fmt.Sprintf(verb.Verb, val)), true
+	}
+	if check.tgoDynamicWriteAllowed == nil {
+		return "", false
+	}
+	strategy, t, ok := check.interpolation(p, x, attr)
+	if !ok || x.mode != constant_ {
+		return "", false
+	}
+	switch strategy {
+	case InterpolateDynamic:
+		switch x.val.Kind() {
+		case constant.String:
+			if check.tgoUnsafeHTML != nil && Identical(t, check.tgoUnsafeHTML) {
+				return constant.StringVal(x.val), true
 			}
-			cause := ""
-			implements := check.implements(v.Pos(), targs[0], check.tgoDynamicWriteAllowed, true, &cause)
-			if !implements {
-				check.errorf(&o, InvalidTemplateLiteralType, "%s", cause)
+			return html.EscapeString(constant.StringVal(x.val)), true
+		case constant.Int:
+			if u, _ := under(t).(*Basic); u != nil && u.kind == Int32 {
+				r, _ := constant.Int64Val(x.val)
+				return html.EscapeString(string(rune(r))), true
 			}
+			return x.val.ExactString(), true
+		}
+	case InterpolateSafe:
+		if x.val.Kind() == constant.String {
+			return constant.StringVal(x.val), true
 		}
 	}
+	return "", false
+}
+
+// templateLiteralString returns the unquoted i-th string of the template
+// literal v, i.e. the text before its i-th part.
+func templateLiteralString(v *ast.TemplateLiteralExpr, i int) (string, bool) {
+	if i >= len(v.Strings) {
+		return "", false
+	}
+	raw := v.Strings[i]
+	if i == 0 {
+		raw = strings.TrimPrefix(raw, `"`)
+	}
+	if i == len(v.Strings)-1 {
+		raw = strings.TrimSuffix(raw, `"`)
+	}
+	s, err := strconv.Unquote(`"` + raw + `"`)
+	return s, err == nil
+}
+
+// constantInterface returns the Go value of type t of the constant val.
+func constantInterface(val constant.Value, t *Basic) (any, bool) {
+	switch {
+	case t.info&IsBoolean != 0:
+		return constant.BoolVal(val), true
+	case t.info&IsString != 0:
+		return constant.StringVal(val), true
+	case t.info&IsUnsigned != 0:
+		u, ok := constant.Uint64Val(constant.ToInt(val))
+		if !ok {
+			return nil, false
+		}
+		switch t.kind {
+		case Uint:
+			return uint(u), true
+		case Uint8:
+			return uint8(u), true
+		case Uint16:
+			return uint16(u), true
+		case Uint32:
+			return uint32(u), true
+		case Uint64:
+			return u, true
+		case Uintptr:
+			return uintptr(u), true
+		}
+	case t.info&IsInteger != 0:
+		i, ok := constant.Int64Val(constant.ToInt(val))
+		if !ok {
+			return nil, false
+		}
+		switch t.kind {
+		case Int:
+			return int(i), true
+		case Int8:
+			return int8(i), true
+		case Int16:
+			return int16(i), true
+		case Int32:
+			return int32(i), true
+		case Int64:
+			return i, true
+		}
+	case t.kind == Float32:
+		f, _ := constant.Float32Val(constant.ToFloat(val))
+		return f, true
+	case t.kind == Float64:
+		f, _ := constant.Float64Val(constant.ToFloat(val))
+		return f, true
+	case t.kind == Complex64:
+		c := constant.ToComplex(val)
+		re, _ := constant.Float32Val(constant.Real(c))
+		im, _ := constant.Float32Val(constant.Imag(c))
+		return complex(re, im), true
+	case t.kind == Complex128:
+		c := constant.ToComplex(val)
+		re, _ := constant.Float64Val(constant.Real(c))
+		im, _ := constant.Float64Val(constant.Imag(c))
+		return complex(re, im), true
+	}
+	return nil, false
 }
 
 // stmt typechecks statement s.
@@ -439,12 +585,12 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 	case *ast.ExprStmt:
 		if v, ok := s.X.(*ast.TemplateLiteralExpr); ok {
 			if ctxt&inTgoFunc == 0 {
-				check.error(s, MisplacedTemplateLiteral, "template literal is not allowed inside a non-tgo function")
+				check.misplacedMarkup(s, MisplacedTemplateLiteral, "template literal")
 			}
 			if ctxt&inOpenTag != 0 {
 				check.error(s, MisplacedTemplateLiteral, "template literal inside of an tag")
 			}
-			check.templateLiteralExpr(v)
+			check.templateLiteralExpr(v, nil)
 			return
 		}
 
@@ -561,18 +707,28 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 		}
 
 	case *ast.GoStmt:
+		if lit, ok := ast.Unparen(s.Call.Fun).(*ast.FuncLit); ok && check.markup != markupNone {
+			check.calledFuncLit(lit, markupInGo, 0)
+		}
+		// The arguments are checked by callExpr (see goArgs).
+		check.goCall = s.Call
 		check.suspendedCall("go", s.Call)
+		check.goCall = nil
 
 	case *ast.DeferStmt:
+		// A deferred function literal is called when the function returns,
+		// after the end tags of the enclosing elements are written.
+		if lit, ok := ast.Unparen(s.Call.Fun).(*ast.FuncLit); ok {
+			check.calledFuncLit(lit, check.markup, 0)
+		}
 		check.suspendedCall("defer", s.Call)
 
 	case *ast.ReturnStmt:
+		// A return in an element body is permitted, the end tags of all
+		// enclosing elements are written before returning (see ast.ElementBlockStmt).
 		if ctxt&inOpenTag != 0 {
 			check.error(s, MisplacedReturn, "invalid return in open tag")
 		}
-		if ctxt&inElementBody != 0 {
-			check.error(s, MisplacedReturn, "invalid return in element body")
-		}
 		res := check.sig.results
 		// Return with implicit results allowed for function with named results.
 		// (If one is named, all are named.)
@@ -918,38 +1074,46 @@ func (check *Checker) stmt(ctxt stmtContext, s ast.Stmt) {
 	case *ast.ElementBlockStmt:
 		check.stmt(inner, s.OpenTag)
 		check.openScope(s, "ElementBlockStmt")
+		check.scope.tag = elementScope
 		check.stmtList(inner|inElementBody|breakNotOkElementBlockStmt|continueNotOkElementBlockStmt, s.Body)
 		check.closeScope()
 		check.stmt(inner, s.EndTag)
 	case *ast.OpenTag:
 		if ctxt&inTgoFunc == 0 {
-			check.error(s, MisplacedTag, "open tag is not allowed inside a non-tgo function")
+			check.misplacedMarkup(s, MisplacedTag, "open tag")
 		}
 		if ctxt&inOpenTag != 0 {
 			check.error(s, MisplacedTag, "tag is not allowed inside a tag")
 		}
+		if s.DynamicName != nil {
+			check.dynamicTagName(s.DynamicName)
+		}
 
 		check.openScope(s, "OpenTag")
+		check.scope.tag = openTagScope
 		defer check.closeScope()
 
 		check.stmtList(inner|inOpenTag|breakNotOkOpenTag|continueNotOkOpenTag, s.Body)
 	case *ast.EndTag:
 		if ctxt&inTgoFunc == 0 {
-			check.error(s, MisplacedTag, "end tag is not allowed inside a non-tgo function")
+			check.misplacedMarkup(s, MisplacedTag, "end tag")
 		}
 		if ctxt&inOpenTag != 0 {
 			check.error(s, MisplacedTag, "end tag is not allowed inside a tag")
 		}
+		if s.DynamicName != nil {
+			check.dynamicTagName(s.DynamicName)
+		}
 	case *ast.AttributeStmt:
 		if ctxt&inTgoFunc == 0 {
-			check.error(s, MisplacedAttribute, "attribute is not allowed inside a non-tgo function")
+			check.misplacedMarkup(s, MisplacedAttribute, "attribute")
 		}
 		if ctxt&inOpenTag == 0 {
 			check.error(s, MisplacedAttribute, "attribute is not allowed outside a tag")
 		}
 		switch v := s.Value.(type) {
 		case *ast.TemplateLiteralExpr:
-			check.templateLiteralExpr(v)
+			check.templateLiteralExpr(v, s)
 		case *ast.BasicLit:
 			if v.Kind != token.STRING {
 				check.error(s, InvalidSyntaxTree, "invalid TemplateLiteralExpr value")
@@ -1,10 +1,14 @@
 package types_test
 
 import (
+	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/constant"
 	"github.com/mateusz834/tgoast/importer"
 	"github.com/mateusz834/tgoast/internal/tgoimporter"
 	"github.com/mateusz834/tgoast/parser"
@@ -52,6 +56,44 @@ func TestTgo(t *testing.T) {
 	testDirFiles(t, "../internal/types/testdata/tgo", false)
 }
 
+func TestTgoFormatVerbErrors(t *testing.T) {
+	const src = `package test
+
+import "github.com/mateusz834/tgo"
+
+func _(_ tgo.Ctx, n int) error {
+	"\{n:%y}"
+	"\{n:%#d}"
+	"\{n:%.2c}"
+	"\{n:%-5d}"
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	cfg := Config{
+		Error: func(err error) {
+			got = append(got, err.Error())
+		},
+		Importer: &tgoimporter.TgoDefaultImporter{I: importer.Default().(ImporterFrom)},
+	}
+	cfg.Check("test", fset, []*ast.File{f}, nil)
+
+	want := []string{
+		"test.tgo:6:7: format %y has unknown verb y",
+		"test.tgo:7:7: format %#d has unrecognized flag #",
+		"test.tgo:8:7: format %.2c has unrecognized flag .",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("errors = %q; want = %q", got, want)
+	}
+}
+
 func TestTgoInfos(t *testing.T) {
 	const src = `package pkg
 
@@ -134,6 +176,17 @@ func test(tgo.Ctx) error {
 		}
 	})
 
+	t.Run("constants", func(t *testing.T) {
+		if tv := infos.Types[divTemplateLit3]; tv.Value == nil || constant.StringVal(tv.Value) != "1 3 ab a" {
+			t.Errorf("value of %#v = %v; want = %q", divTemplateLit3, tv.Value, "1 3 ab a")
+		}
+		for _, lit := range []*ast.TemplateLiteralExpr{articleOpenTagAttrTemplateLit, articleTemplateLit, divTemplateLit1, divTemplateLit2} {
+			if tv, ok := infos.Types[lit]; ok {
+				t.Errorf("unexpected value for %#v: %v", lit, tv.Value)
+			}
+		}
+	})
+
 	t.Run("instances", func(t *testing.T) {
 		if len(infos.Instances) != 0 {
 			t.Errorf("len(info.Instances) = %v; want = 0", len(infos.Instances))
@@ -223,3 +276,243 @@ func test(tgo.Ctx) error {
 
 	_ = pkg
 }
+
+func TestTgoInterpolations(t *testing.T) {
+	const src = `package test
+
+import "github.com/mateusz834/tgo"
+
+type userID int
+
+func (userID) String() string { return "" }
+
+type money struct{}
+
+func (money) MarshalText() ([]byte, error) { return nil, nil }
+
+type both struct{ money }
+
+func (both) String() string { return "" }
+
+type component struct{}
+
+func (component) Render(tgo.Ctx) error { return nil }
+
+func _(_ tgo.Ctx, id userID, m money, b both, c component) error {
+	"\{1} \{"a"}"
+	"\{id} \{m} \{b} \{c}"
+	"\{1:%05d} \{id:%v}"
+	<div @attr="\{id}"></div>
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(ImporterFrom)},
+		Interpolators: DefaultInterpolators(),
+	}
+	info := Info{Interpolations: make(map[*ast.TemplateLiteralPart]Interpolation)}
+	if _, err := cfg.Check("test", fset, []*ast.File{f}, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, part := range slices.SortedFunc(maps.Keys(info.Interpolations), func(a, b *ast.TemplateLiteralPart) int {
+		return int(a.Pos() - b.Pos())
+	}) {
+		ip := info.Interpolations[part]
+		s := fmt.Sprintf("%v: %v", fset.Position(part.Pos()), ip.Strategy)
+		if ip.Interface != nil {
+			s += " " + ip.Interface.Method(0).Name()
+		}
+		got = append(got, s)
+	}
+	want := []string{
+		"test.tgo:22:4: dynamic",
+		"test.tgo:22:9: dynamic",
+		"test.tgo:23:4: string String",
+		"test.tgo:23:10: text MarshalText",
+		"test.tgo:23:15: string String",
+		"test.tgo:23:20: render Render",
+		"test.tgo:24:4: format",
+		"test.tgo:24:14: format",
+		"test.tgo:25:15: string String",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("interpolations:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestTgoConstantTemplateLiterals(t *testing.T) {
+	const src = `package test
+
+import "github.com/mateusz834/tgo"
+
+type userID int
+
+func (userID) String() string { return "" }
+
+const unsafe tgo.UnsafeHTML = "<b>"
+
+const url tgo.SafeURL = "/?a=1&b=2"
+
+func _(_ tgo.Ctx, s string) error {
+	"\{1} \{1+2} \{"a"+"b"} \{'x'}"
+	"<\{"&"}>\n"
+	"\{unsafe} \{2.5:%.2f} \{userID(1)}"
+	"\{s} \{1}"
+	<a @href="\{url}" @title="\{"\"q\""}"></a>
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(ImporterFrom)},
+		Interpolators: DefaultInterpolators(),
+	}
+	info := Info{
+		Types:         make(map[ast.Expr]TypeAndValue),
+		ConstantParts: make(map[*ast.TemplateLiteralPart]string),
+	}
+	if _, err := cfg.Check("test", fset, []*ast.File{f}, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TemplateLiteralExpr:
+			if tv, ok := info.Types[n]; ok {
+				got = append(got, fmt.Sprintf("%v: %v %s", fset.Position(n.Pos()), tv.Type, tv.Value))
+			}
+		case *ast.TemplateLiteralPart:
+			if s, ok := info.ConstantParts[n]; ok {
+				got = append(got, fmt.Sprintf("%v: %q", fset.Position(n.Pos()), s))
+			}
+		}
+		return true
+	})
+	want := []string{
+		`test.tgo:14:2: untyped string "1 3 ab x"`,
+		`test.tgo:14:4: "1"`,
+		`test.tgo:14:9: "3"`,
+		`test.tgo:14:16: "ab"`,
+		`test.tgo:14:27: "x"`,
+		`test.tgo:15:2: untyped string "&lt;&amp;&gt;\n"`,
+		`test.tgo:15:5: "&amp;"`,
+		`test.tgo:16:4: "<b>"`,
+		`test.tgo:16:14: "2.50"`,
+		`test.tgo:17:9: "1"`,
+		`test.tgo:18:11: untyped string "/?a=1&b=2"`,
+		`test.tgo:18:13: "/?a=1&b=2"`,
+		`test.tgo:18:27: untyped string "&#34;q&#34;"`,
+		`test.tgo:18:29: "&#34;q&#34;"`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("constants:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestTgoEvalInterpolation(t *testing.T) {
+	const src = `package test
+
+import "github.com/mateusz834/tgo"
+
+type userID int
+
+func (userID) String() string { return "" }
+
+func _(_ tgo.Ctx, id userID, u tgo.SafeURL, h tgo.UnsafeHTML) error {
+	<a @href="\{u}">
+		"\{id}"
+	</a>
+	return nil
+}
+
+func plain(id userID) userID { return id }
+
+func _(_ tgo.Ctx, id userID) error {
+	func() {
+		<p>"\{id}" /* sync */</p>
+	}()
+	f := func() {
+		_ = id /* escaping */
+	}
+	go func() {
+		_ = id /* go */
+	}()
+	f()
+	return nil
+}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.tgo", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(ImporterFrom)},
+		Interpolators: DefaultInterpolators(),
+	}
+	pkg, err := cfg.Check("test", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos := func(s string) token.Pos {
+		return fset.File(f.Pos()).Pos(strings.Index(src, s))
+	}
+	cases := []struct {
+		pos        token.Pos
+		expr, attr string
+		want       string
+	}{
+		{pos(`"\{id}"`), "id", "", "string String test.userID"},
+		{pos(`"\{id}"`), "1 + 2", "", "dynamic untyped int 3"},
+		{pos(`"\{id}"`), "id:%05d", "", "format test.userID"},
+		{pos(`"\{id}"`), "h", "", "dynamic github.com/mateusz834/tgo.UnsafeHTML"},
+		{pos(`return nil`), "id", "", "string String test.userID"},
+		{pos(`\{u}`), "u", "href", "safe github.com/mateusz834/tgo.SafeURL"},
+		{pos(`\{u}`), "id", "title", "string String test.userID"},
+		{pos(`\{u}`), "u", "title", "error: eval:1:4: cannot use u (variable of type tgo.SafeURL) in attribute title (tgo.SafeURL is permitted only in URL attributes)"},
+		{pos(`\{u}`), "h", "title", "error: eval:1:4: cannot use h (variable of type tgo.UnsafeHTML) in attribute title (tgo.UnsafeHTML is not permitted in attributes)"},
+		{pos(`\{u}`), "id", "", "error: position test.tgo:10:12 is inside an open tag, an attribute is required"},
+		{pos(`"\{id}"`), "id", "title", "error: position test.tgo:11:3 is not inside an open tag, attribute title is not permitted"},
+		{pos(`"\{id}"`), "x", "", "error: eval:1:4: undefined: x"},
+		{pos(`return id`), "id", "", "error: position test.tgo:16:32 is not inside a tgo function"},
+		{pos(`type userID`), "1", "", "error: position test.tgo:5:1 is not inside a tgo function"},
+		{pos(`/* sync */`), "id", "", "string String test.userID"},
+		{pos(`/* escaping */`), "id", "", "error: position test.tgo:23:10 is inside a function literal that is not called synchronously"},
+		{pos(`/* go */`), "id", "", "error: position test.tgo:26:10 is inside a go statement"},
+		{pos(`"\{id}"`), "id +", "", "error: eval:1:8: expected operand, found '}' (and 2 more errors)"},
+	}
+	for _, tt := range cases {
+		tv, ip, err := EvalInterpolation(&cfg, fset, pkg, tt.pos, tt.expr, tt.attr)
+		var got string
+		if err != nil {
+			got = "error: " + err.Error()
+		} else {
+			got = ip.Strategy.String()
+			if ip.Interface != nil {
+				got += " " + ip.Interface.Method(0).Name()
+			}
+			got += " " + tv.Type.String()
+			if tv.Value != nil {
+				got += " " + tv.Value.String()
+			}
+		}
+		if got != tt.want {
+			t.Errorf("EvalInterpolation(%q, %q) = %q; want %q", tt.expr, tt.attr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	. "github.com/mateusz834/tgoast/internal/types/errors"
+)
+
+// This file implements the checks of formats in template literal
+// parts (\{x:%.2f} and \{t:layout}). The checks of format verbs
+// follow the printf check of the vet tool.
+
+// A printfArgType is the set of types accepted by a format verb.
+type printfArgType int
+
+const (
+	argBool printfArgType = 1 << iota
+	argInt
+	argRune
+	argString
+	argFloat
+	argComplex
+	argPointer
+	anyType printfArgType = ^0
+)
+
+// A printVerb describes a format verb.
+type printVerb struct {
+	verb  byte
+	flags string // known flags are all ASCII; "." means precision is allowed
+	typ   printfArgType
+}
+
+const (
+	noFlag       = ""
+	numFlag      = " -+.0"
+	sharpNumFlag = " -+.0#"
+	allFlags     = " -+.0#"
+)
+
+var printVerbs = []printVerb{
+	{'b', sharpNumFlag, argInt | argFloat | argComplex | argPointer},
+	{'c', "-", argRune | argInt},
+	{'d', numFlag, argInt | argPointer},
+	{'e', sharpNumFlag, argFloat | argComplex},
+	{'E', sharpNumFlag, argFloat | argComplex},
+	{'f', sharpNumFlag, argFloat | argComplex},
+	{'F', sharpNumFlag, argFloat | argComplex},
+	{'g', sharpNumFlag, argFloat | argComplex},
+	{'G', sharpNumFlag, argFloat | argComplex},
+	{'o', sharpNumFlag, argInt | argPointer},
+	{'O', sharpNumFlag, argInt | argPointer},
+	{'p', "-#", argPointer},
+	{'q', " -+.0#", argRune | argInt | argString},
+	{'s', " -+.0", argString},
+	{'t', "-", argBool},
+	{'T', "-", anyType},
+	{'U', "-#", argRune | argInt},
+	{'v', allFlags, anyType},
+	{'x', sharpNumFlag, argRune | argInt | argString | argPointer | argFloat | argComplex},
+	{'X', sharpNumFlag, argRune | argInt | argString | argPointer | argFloat | argComplex},
+}
+
+// templateLiteralFormat checks the format of a template literal part,
+// x is the operand of the part.
+func (check *Checker) templateLiteralFormat(x *operand, format ast.Expr) {
+	if v, ok := format.(*ast.FormatVerb); ok {
+		if x.mode != invalid {
+			check.assignment(x, nil, "template literal part")
+		}
+		if x.mode != invalid {
+			check.formatVerb(x, v)
+		}
+		return
+	}
+
+	var layout operand
+	check.expr(nil, &layout, format)
+	if layout.mode != invalid {
+		check.assignment(&layout, Typ[String], "layout")
+	}
+	if x.mode == invalid {
+		return
+	}
+
+	obj, _, _ := lookupFieldOrMethod(x.typ, x.mode == variable, check.pkg, "Format", false)
+	if fn, _ := obj.(*Func); fn != nil {
+		sig := fn.Type().(*Signature)
+		if sig.Params().Len() == 1 && Identical(sig.Params().At(0).Type(), Typ[String]) &&
+			sig.Results().Len() == 1 && Identical(sig.Results().At(0).Type(), Typ[String]) {
+			return
+		}
+	}
+	check.errorf(x, InvalidTemplateLiteralFormat, "cannot format %s with a layout (missing method Format(string) string)", x)
+}
+
+// formatVerb checks that the format verb v accepts the operand x,
+// x.mode is set to invalid if it does not.
+func (check *Checker) formatVerb(x *operand, v *ast.FormatVerb) {
+	verb := v.Verb[len(v.Verb)-1]
+	var pv *printVerb
+	for i := range printVerbs {
+		if printVerbs[i].verb == verb {
+			pv = &printVerbs[i]
+			break
+		}
+	}
+	if pv == nil {
+		check.errorf(v, InvalidTemplateLiteralFormat, "format %s has unknown verb %c", v.Verb, verb)
+		x.mode = invalid
+		return
+	}
+
+	// The scanner guarantees the %[flags][width][.precision]verb form.
+	spec := v.Verb[1 : len(v.Verb)-1]
+	flags := spec[:len(spec)-len(strings.TrimLeft(spec, " -+#0"))]
+	if strings.Contains(spec, ".") {
+		flags += "."
+	}
+	for _, c := range flags {
+		if !strings.ContainsRune(pv.flags, c) {
+			check.errorf(v, InvalidTemplateLiteralFormat, "format %s has unrecognized flag %c", v.Verb, c)
+			x.mode = invalid
+			return
+		}
+	}
+
+	if !check.matchArgType(pv.typ, x.typ, make(map[Type]bool)) {
+		check.errorf(x, InvalidTemplateLiteralFormat, "format %s has arg %s of wrong type %s", v.Verb, x.expr, x.typ)
+		x.mode = invalid
+	}
+}
+
+// matchArgType reports whether typ is an acceptable argument for a verb
+// that accepts the types t. The seen map is used to avoid infinite
+// recursion on recursive types.
+func (check *Checker) matchArgType(t printfArgType, typ Type, seen map[Type]bool) bool {
+	if t == anyType {
+		return true
+	}
+	// %s, %q, %v, %x and %X accept errors and Stringers.
+	if t&argString != 0 && check.isStringer(typ) {
+		return true
+	}
+	if seen[typ] {
+		return true
+	}
+	seen[typ] = true
+
+	return underIs(typ, func(u Type) bool {
+		switch u := u.(type) {
+		case nil:
+			return true // type parameter without specific types
+		case *Basic:
+			switch {
+			case u.kind == UnsafePointer:
+				return t&argPointer != 0
+			case u.info&IsBoolean != 0:
+				return t&argBool != 0
+			case u.info&IsInteger != 0:
+				return t&(argInt|argRune) != 0
+			case u.info&IsFloat != 0:
+				return t&argFloat != 0
+			case u.info&IsComplex != 0:
+				return t&argComplex != 0
+			case u.info&IsString != 0:
+				return t&argString != 0
+			}
+			return false
+		case *Slice:
+			// []byte is printed as a string.
+			if b, ok := under(u.elem).(*Basic); ok && b.kind == Byte && t&argString != 0 {
+				return true
+			}
+			return t&argPointer != 0 || check.matchArgType(t, u.elem, seen)
+		case *Array:
+			return check.matchArgType(t, u.elem, seen)
+		case *Map:
+			return t&argPointer != 0 ||
+				check.matchArgType(t, u.key, seen) && check.matchArgType(t, u.elem, seen)
+		case *Struct:
+			for _, f := range u.fields {
+				if !check.matchArgType(t, f.typ, seen) {
+					return false
+				}
+			}
+			return true
+		case *Pointer, *Chan, *Signature:
+			return t&argPointer != 0
+		case *Interface:
+			return true // the dynamic type is not known
+		}
+		return false
+	})
+}
+
+// isStringer reports whether typ has an Error() string
+// or a String() string method.
+func (check *Checker) isStringer(typ Type) bool {
+	for _, name := range [...]string{"Error", "String"} {
+		obj, _, _ := lookupFieldOrMethod(typ, false, check.pkg, name, false)
+		if fn, _ := obj.(*Func); fn != nil {
+			sig := fn.Type().(*Signature)
+			if sig.Params().Len() == 0 && sig.Results().Len() == 1 &&
+				Identical(sig.Results().At(0).Type(), Typ[String]) {
+				return true
+			}
+		}
+	}
+	return false
+}
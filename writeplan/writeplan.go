@@ -0,0 +1,409 @@
+// Package writeplan computes the write plans of tgo functions.
+//
+// The markup of a tgo function is written piece by piece: an open tag
+// "<div", each of its attributes, the ">" closing the open tag, the text
+// nodes and the end tag are separate statements. A write plan lists the
+// output of a function in source order, as static chunks and dynamic
+// holes, where all statically known output between two dynamic points is
+// merged into a single chunk, so that a code generator can issue a single
+// write for it.
+//
+// The plan is control-flow aware, static output is never merged across
+// the boundaries of if, for, switch and select statements, nor across
+// return and branch statements. A return statement writes the end tags
+// of the elements that it leaves, they are part of the chunks before its
+// boundary. Statements other than markup are assumed
+// not to write, unless they refer to a value of type tgo.Ctx (e.g. they
+// call another tgo function), in which case they are dynamic holes.
+package writeplan
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/constant"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+)
+
+// tgoPath is the import path of the tgo package.
+const tgoPath = "github.com/mateusz834/tgo"
+
+// A Kind describes a chunk of a plan.
+type Kind int
+
+const (
+	// Static: output known statically, see Chunk.Static.
+	Static Kind = iota
+
+	// Text: a template literal part of a text node.
+	Text
+
+	// AttrValue: a template literal part of an attribute value.
+	AttrValue
+
+	// TagName: the dynamic tag name of an open or end tag.
+	TagName
+
+	// Call: a statement that refers to the tgo.Ctx.
+	Call
+
+	// Branch: a control flow boundary, the beginning or the end of a
+	// control flow statement or of one of its blocks, or a return or
+	// branch statement.
+	Branch
+)
+
+var kinds = [...]string{
+	Static:    "static",
+	Text:      "text",
+	AttrValue: "attr",
+	TagName:   "tag",
+	Call:      "call",
+	Branch:    "branch",
+}
+
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return "invalid"
+}
+
+// A Chunk is a static chunk, a dynamic hole or a control flow boundary
+// of a plan.
+type Chunk struct {
+	Kind Kind
+	Pos  token.Pos // position of the first merged piece, of the hole or of the boundary
+
+	// Static is the escaped output of a Static chunk, Pieces are the
+	// outputs of the markup statements merged into it, in order.
+	Static string
+	Pieces []string
+
+	// Node is the hole or the statement of the boundary: a
+	// *ast.TemplateLiteralPart for Text, AttrValue and TagName, an
+	// ast.Stmt (or the ast.Expr of a control flow statement) for Call,
+	// an ast.Stmt for Branch.
+	Node ast.Node
+}
+
+// Stats are the statistics of a plan.
+type Stats struct {
+	ASTWrites int // writes of a code generator that follows the AST
+	Writes    int // writes of the plan, i.e. Static + Holes
+	Static    int // static chunks
+	Holes     int // dynamic holes
+	Branches  int // control flow boundaries
+	Bytes     int // bytes of static output
+}
+
+// A Plan is the write plan of a tgo function.
+type Plan struct {
+	Func   ast.Node // *ast.FuncDecl or *ast.FuncLit
+	Chunks []Chunk
+}
+
+// Stats returns the statistics of the plan.
+func (p *Plan) Stats() Stats {
+	var s Stats
+	for _, c := range p.Chunks {
+		switch c.Kind {
+		case Static:
+			s.Static++
+			s.ASTWrites += len(c.Pieces)
+			s.Bytes += len(c.Static)
+		case Branch:
+			s.Branches++
+		default:
+			s.Holes++
+			s.ASTWrites++
+		}
+	}
+	s.Writes = s.Static + s.Holes
+	return s
+}
+
+// Compute returns the plans of the tgo functions (declarations and
+// function literals) of the files, in source order. The info must hold
+// the Types, Defs, Uses and ConstantParts recorded by the type checker.
+func Compute(info *types.Info, files []*ast.File) []*Plan {
+	var plans []*Plan
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			var body *ast.BlockStmt
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				if fn, ok := info.Defs[n.Name].(*types.Func); ok && isTgoSignature(fn.Type().(*types.Signature)) {
+					body = n.Body
+				}
+			case *ast.FuncLit:
+				if sig, ok := info.TypeOf(n).(*types.Signature); ok && isTgoSignature(sig) {
+					body = n.Body
+				}
+			}
+			if body != nil {
+				b := builder{info: info}
+				b.stmts(body.List)
+				plans = append(plans, &Plan{Func: n, Chunks: b.finish()})
+			}
+			return true
+		})
+	}
+	return plans
+}
+
+// isTgoSignature reports whether sig is a signature of a tgo function,
+// i.e. func(tgo.Ctx, ...) error.
+func isTgoSignature(sig *types.Signature) bool {
+	return sig.Params().Len() > 0 && sig.Results().Len() == 1 &&
+		isCtx(sig.Params().At(0).Type()) &&
+		types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type())
+}
+
+// isCtx reports whether t is tgo.Ctx.
+func isCtx(t types.Type) bool {
+	n, ok := types.Unalias(t).(*types.Named)
+	return ok && n.Obj().Pkg() != nil && n.Obj().Pkg().Path() == tgoPath && n.Obj().Name() == "Ctx"
+}
+
+// A builder builds the chunks of a plan.
+type builder struct {
+	info   *types.Info
+	chunks []Chunk
+
+	// closers append the end tags of the elements being built,
+	// innermost last.
+	closers []func()
+}
+
+// static appends the static output s of the piece at pos, it is merged
+// with the preceding static chunk. The outputs of the chunks are set by
+// finish.
+func (b *builder) static(pos token.Pos, s string) {
+	if n := len(b.chunks); n > 0 && b.chunks[n-1].Kind == Static {
+		c := &b.chunks[n-1]
+		c.Pieces = append(c.Pieces, s)
+		return
+	}
+	b.chunks = append(b.chunks, Chunk{Kind: Static, Pos: pos, Pieces: []string{s}})
+}
+
+// finish sets the outputs of the static chunks.
+func (b *builder) finish() []Chunk {
+	for i := range b.chunks {
+		if c := &b.chunks[i]; c.Kind == Static {
+			c.Static = strings.Join(c.Pieces, "")
+		}
+	}
+	return b.chunks
+}
+
+func (b *builder) hole(kind Kind, n ast.Node) {
+	b.chunks = append(b.chunks, Chunk{Kind: kind, Pos: n.Pos(), Node: n})
+}
+
+func (b *builder) branch(pos token.Pos, s ast.Stmt) {
+	b.chunks = append(b.chunks, Chunk{Kind: Branch, Pos: pos, Node: s})
+}
+
+func (b *builder) stmts(list []ast.Stmt) {
+	for _, s := range list {
+		b.stmt(s)
+	}
+}
+
+func (b *builder) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.ElementBlockStmt:
+		b.closers = append(b.closers, func() { b.endTag(s.OpenTag, s.EndTag) })
+		b.openTag(s.OpenTag)
+		b.stmts(s.Body)
+		b.closers = b.closers[:len(b.closers)-1]
+		b.endTag(s.OpenTag, s.EndTag)
+
+	case *ast.OpenTag:
+		// A void element, without an end tag.
+		b.openTag(s)
+
+	case *ast.AttributeStmt:
+		b.attribute(s)
+
+	case *ast.ExprStmt:
+		switch x := s.X.(type) {
+		case *ast.BasicLit:
+			if x.Kind == token.STRING {
+				if v, err := strconv.Unquote(x.Value); err == nil {
+					b.static(x.Pos(), html.EscapeString(v))
+				}
+				return
+			}
+		case *ast.TemplateLiteralExpr:
+			b.templateLiteral(x, Text)
+			return
+		}
+		b.other(s)
+
+	case *ast.BlockStmt:
+		b.stmts(s.List)
+
+	case *ast.LabeledStmt:
+		b.stmt(s.Stmt)
+
+	case *ast.IfStmt:
+		b.other(s.Init)
+		b.other(s.Cond)
+		b.branch(s.Pos(), s)
+		b.stmts(s.Body.List)
+		if s.Else != nil {
+			b.branch(s.Else.Pos(), s.Else)
+			b.stmt(s.Else)
+		}
+		b.branch(s.End(), s)
+
+	case *ast.ForStmt:
+		b.other(s.Init)
+		b.branch(s.Pos(), s)
+		b.other(s.Cond)
+		b.stmts(s.Body.List)
+		b.other(s.Post)
+		b.branch(s.End(), s)
+
+	case *ast.RangeStmt:
+		b.other(s.X)
+		b.branch(s.Pos(), s)
+		b.stmts(s.Body.List)
+		b.branch(s.End(), s)
+
+	case *ast.SwitchStmt:
+		b.other(s.Init)
+		b.clauses(s, s.Body)
+
+	case *ast.TypeSwitchStmt:
+		b.other(s.Init)
+		b.clauses(s, s.Body)
+
+	case *ast.SelectStmt:
+		b.clauses(s, s.Body)
+
+	case *ast.ReturnStmt:
+		b.other(s)
+		// The elements are closed before the function returns.
+		for i := len(b.closers) - 1; i >= 0; i-- {
+			b.closers[i]()
+		}
+		b.branch(s.Pos(), s)
+
+	case *ast.BranchStmt:
+		// The type checker rejects branch statements
+		// that leave an element.
+		b.branch(s.Pos(), s)
+
+	default:
+		b.other(s)
+	}
+}
+
+// clauses appends the clauses of the switch or select statement s.
+func (b *builder) clauses(s ast.Stmt, body *ast.BlockStmt) {
+	b.branch(s.Pos(), s)
+	for _, c := range body.List {
+		b.branch(c.Pos(), c)
+		switch c := c.(type) {
+		case *ast.CaseClause:
+			b.stmts(c.Body)
+		case *ast.CommClause:
+			b.stmts(c.Body)
+		}
+	}
+	b.branch(s.End(), s)
+}
+
+// other appends a Call hole for the statement or expression n,
+// when it refers to a value of type tgo.Ctx.
+func (b *builder) other(n ast.Node) {
+	if n == nil {
+		return
+	}
+	refersToCtx := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if v, ok := b.info.Uses[id].(*types.Var); ok && isCtx(v.Type()) {
+				refersToCtx = true
+			}
+		}
+		return !refersToCtx
+	})
+	if refersToCtx {
+		b.hole(Call, n)
+	}
+}
+
+func (b *builder) openTag(t *ast.OpenTag) {
+	if t.Name != nil {
+		b.static(t.OpenPos, "<"+t.Name.Name)
+	} else {
+		b.static(t.OpenPos, "<")
+		b.hole(TagName, t.DynamicName)
+	}
+	b.stmts(t.Body)
+	b.static(t.ClosePos, ">")
+}
+
+// endTag appends the end tag t of the open tag open,
+// the name of t is the name of open (t might be </>).
+func (b *builder) endTag(open *ast.OpenTag, t *ast.EndTag) {
+	if open.Name != nil {
+		b.static(t.OpenPos, "</"+open.Name.Name+">")
+		return
+	}
+	b.static(t.OpenPos, "</")
+	b.hole(TagName, open.DynamicName)
+	b.static(t.ClosePos, ">")
+}
+
+func (b *builder) attribute(s *ast.AttributeStmt) {
+	name := " " + s.AttrName.(*ast.Ident).Name
+	switch v := s.Value.(type) {
+	case nil:
+		b.static(s.Pos(), name)
+	case *ast.BasicLit:
+		if str, err := strconv.Unquote(v.Value); err == nil {
+			b.static(s.Pos(), name+`="`+html.EscapeString(str)+`"`)
+		}
+	case *ast.TemplateLiteralExpr:
+		b.static(s.Pos(), name+`="`)
+		b.templateLiteral(v, AttrValue)
+		b.static(v.End(), `"`)
+	}
+}
+
+// templateLiteral appends the template literal x, its parts
+// that are not constant are holes of the given kind.
+func (b *builder) templateLiteral(x *ast.TemplateLiteralExpr, kind Kind) {
+	if tv, ok := b.info.Types[x]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+		b.static(x.Pos(), constant.StringVal(tv.Value))
+		return
+	}
+	for i, raw := range x.Strings {
+		if i == 0 {
+			raw = strings.TrimPrefix(raw, `"`)
+		}
+		if i == len(x.Strings)-1 {
+			raw = strings.TrimSuffix(raw, `"`)
+		}
+		if s, err := strconv.Unquote(`"` + raw + `"`); err == nil && s != "" {
+			b.static(x.Pos(), html.EscapeString(s))
+		}
+		if i < len(x.Parts) {
+			p := x.Parts[i]
+			if s, ok := b.info.ConstantParts[p]; ok {
+				b.static(p.Pos(), s)
+			} else {
+				b.hole(kind, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package writeplan_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mateusz834/tgoast/ast"
+	"github.com/mateusz834/tgoast/importer"
+	"github.com/mateusz834/tgoast/internal/tgoimporter"
+	"github.com/mateusz834/tgoast/parser"
+	"github.com/mateusz834/tgoast/token"
+	"github.com/mateusz834/tgoast/types"
+	"github.com/mateusz834/tgoast/writeplan"
+)
+
+func compute(t testing.TB, src string) (*token.FileSet, []*writeplan.Plan) {
+	fset, info, files := check(t, src)
+	return fset, writeplan.Compute(info, files)
+}
+
+// check parses and type checks the file src.
+func check(t testing.TB, src string) (*token.FileSet, *types.Info, []*ast.File) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, parser.SkipObjectResolution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{
+		Importer:      &tgoimporter.TgoDefaultImporter{I: importer.Default().(types.ImporterFrom)},
+		Interpolators: types.DefaultInterpolators(),
+	}
+	info := &types.Info{
+		Types:         make(map[ast.Expr]types.TypeAndValue),
+		Defs:          make(map[*ast.Ident]types.Object),
+		Uses:          make(map[*ast.Ident]types.Object),
+		ConstantParts: make(map[*ast.TemplateLiteralPart]string),
+	}
+	if _, err := conf.Check("p", fset, []*ast.File{f}, info); err != nil {
+		t.Fatal(err)
+	}
+	return fset, info, []*ast.File{f}
+}
+
+// dump returns the chunks of the plan, one per line.
+func dump(fset *token.FileSet, p *writeplan.Plan) string {
+	var b strings.Builder
+	for _, c := range p.Chunks {
+		fmt.Fprintf(&b, "%d: %v", fset.Position(c.Pos).Line, c.Kind)
+		switch n := c.Node.(type) {
+		case nil:
+			fmt.Fprintf(&b, " %q (%d)", c.Static, len(c.Pieces))
+		case *ast.TemplateLiteralPart:
+			fmt.Fprintf(&b, " %s", types.ExprString(n.X))
+		case ast.Expr:
+			fmt.Fprintf(&b, " %s", types.ExprString(n))
+		default:
+			fmt.Fprintf(&b, " %T", n)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestCompute(t *testing.T) {
+	const src = `package p
+
+import "github.com/mateusz834/tgo"
+
+const title = "Home"
+
+func Page(ctx tgo.Ctx, user string, items []string) error {
+	<html @lang="en">
+		<head><title>"\{title} & more"</title></head>
+		<body @class="page" @hidden>
+			<h1 @id="\{user}-title">"Hello \{user}!"</h1>
+			if len(items) == 0 {
+				<p>"No items"</p>
+			} else {
+				<ul>
+					for _, it := range items {
+						<li>"\{it}"</li>
+					}
+				</ul>
+			}
+			Footer(ctx)
+		</body>
+	</html>
+	return nil
+}
+
+func Footer(tgo.Ctx) error {
+	<footer>"\{"©"} 2024"</footer>
+	return nil
+}
+
+func Heading(ctx tgo.Ctx, heading tgo.TagName) error {
+	<\{heading}>"x"</>
+	<b>"y"</>
+	return nil
+}
+
+func helper() {}
+`
+	fset, plans := compute(t, src)
+	if len(plans) != 3 {
+		t.Fatalf("len(plans) = %d; want 3", len(plans))
+	}
+
+	const want = `8: static "<html lang=\"en\"><head><title>Home &amp; more</title></head><body class=\"page\" hidden><h1 id=\"" (16)
+11: attr user
+11: static "-title\">Hello " (4)
+11: text user
+11: static "!</h1>" (2)
+12: branch *ast.IfStmt
+13: static "<p>No items</p>" (4)
+14: branch *ast.BlockStmt
+15: static "<ul>" (2)
+16: branch *ast.RangeStmt
+17: static "<li>" (2)
+17: text it
+17: static "</li>" (1)
+18: branch *ast.RangeStmt
+19: static "</ul>" (1)
+20: branch *ast.IfStmt
+21: call *ast.ExprStmt
+22: static "</body></html>" (2)
+24: branch *ast.ReturnStmt
+`
+	if got := dump(fset, plans[0]); got != want {
+		t.Errorf("plan of Page:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := dump(fset, plans[1]), "28: static \"<footer>© 2024</footer>\" (4)\n29: branch *ast.ReturnStmt\n"; got != want {
+		t.Errorf("plan of Footer:\n%s\nwant:\n%s", got, want)
+	}
+	if got, want := dump(fset, plans[2]), "33: static \"<\" (1)\n33: tag heading\n33: static \">x</\" (3)\n33: tag heading\n33: static \"><b>y</b>\" (5)\n35: branch *ast.ReturnStmt\n"; got != want {
+		t.Errorf("plan of Heading:\n%s\nwant:\n%s", got, want)
+	}
+
+	got := plans[0].Stats()
+	wantStats := writeplan.Stats{ASTWrites: 38, Writes: 13, Static: 9, Holes: 4, Branches: 6, Bytes: 160}
+	if got != wantStats {
+		t.Errorf("Stats() = %+v; want %+v", got, wantStats)
+	}
+}
+
+func TestComputeReturn(t *testing.T) {
+	const src = `package p
+
+import "github.com/mateusz834/tgo"
+
+func Early(ctx tgo.Ctx, ok bool) error {
+	<div>
+		if !ok {
+			return nil
+		}
+		"x"
+	</div>
+	return nil
+}
+`
+	fset, plans := compute(t, src)
+	const want = `6: static "<div>" (2)
+7: branch *ast.IfStmt
+11: static "</div>" (1)
+8: branch *ast.ReturnStmt
+9: branch *ast.IfStmt
+10: static "x</div>" (2)
+12: branch *ast.ReturnStmt
+`
+	if got := dump(fset, plans[0]); got != want {
+		t.Errorf("plan of Early:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// page is a realistic page: a layout with a navigation, a list of
+// articles and a footer.
+const page = `package p
+
+import "github.com/mateusz834/tgo"
+
+type Link struct {
+	URL, Text string
+	Active    bool
+}
+
+type Article struct {
+	ID                  int
+	Title, Author, Body string
+	Tags                []string
+}
+
+func Layout(ctx tgo.Ctx, title string, nav []Link, articles []Article) error {
+	<html @lang="en">
+		<head>
+			<meta @charset="utf-8">
+			<meta @name="viewport" @content="width=device-width, initial-scale=1">
+			<title>"\{title} | Blog"</title>
+			<link @rel="stylesheet" @href="/static/style.css">
+		</head>
+		<body>
+			<header @class="site-header">
+				<a @class="logo" @href="/">"Blog"</a>
+				<nav @title="Main">
+					<ul @class="nav">
+						for _, l := range nav {
+							<li @class="nav-item">
+								if l.Active {
+									<a @class="active" @href="\{l.URL}">"\{l.Text}"</a>
+								} else {
+									<a @href="\{l.URL}">"\{l.Text}"</a>
+								}
+							</li>
+						}
+					</ul>
+				</nav>
+			</header>
+			<main @id="content">
+				<h1 @class="title">"\{title}"</h1>
+				for _, a := range articles {
+					ArticleCard(ctx, a)
+				}
+			</main>
+			<footer @class="site-footer">
+				<p>"Copyright 2024 The Blog Authors. All rights reserved."</p>
+				<p>
+					<a @href="/privacy">"Privacy"</a>
+					" | "
+					<a @href="/terms">"Terms"</a>
+				</p>
+			</footer>
+		</body>
+	</html>
+	return nil
+}
+
+func ArticleCard(ctx tgo.Ctx, a Article) error {
+	<article @class="card" @id="article-\{a.ID}">
+		<header @class="card-header">
+			<h2 @class="card-title"><a @href="/articles/\{a.ID}">"\{a.Title}"</a></h2>
+			<p @class="byline">
+				"by "
+				<span @class="author">"\{a.Author}"</span>
+			</p>
+		</header>
+		<div @class="card-body"><p>"\{a.Body}"</p></div>
+		<footer @class="card-footer">
+			<ul @class="tags">
+				for _, t := range a.Tags {
+					<li @class="tag"><a @href="/tags/\{t}">"#\{t}"</a></li>
+				}
+			</ul>
+			<a @class="more" @href="/articles/\{a.ID}">"Read more"</a>
+		</footer>
+	</article>
+	return nil
+}
+`
+
+func TestComputePage(t *testing.T) {
+	_, plans := compute(t, page)
+	if len(plans) != 2 {
+		t.Fatalf("len(plans) = %d; want 2", len(plans))
+	}
+	const head = `<html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>`
+	if got := plans[0].Chunks[0].Static; got != head {
+		t.Errorf("first chunk of Layout = %q; want %q", got, head)
+	}
+	got := plans[0].Stats()
+	want := writeplan.Stats{ASTWrites: 95, Writes: 20, Static: 13, Holes: 7, Branches: 8, Bytes: 607}
+	if got != want {
+		t.Errorf("Stats() = %+v; want %+v", got, want)
+	}
+}
+
+func BenchmarkCompute(b *testing.B) {
+	_, info, files := check(b, page)
+	b.ReportAllocs()
+	for range b.N {
+		writeplan.Compute(info, files)
+	}
+}
+
+// lockedWriter is an unbuffered writer with a cost per write,
+// like the writers of network connections.
+type lockedWriter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.n += len(p)
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// BenchmarkWrite replays the writes of the page (with a single iteration
+// of each loop), as issued by a code generator that follows the AST and
+// by one that follows the plan.
+func BenchmarkWrite(b *testing.B) {
+	_, plans := compute(b, page)
+	for _, bench := range []struct {
+		name   string
+		writes func(writeplan.Chunk) []string
+	}{
+		{"ast", func(c writeplan.Chunk) []string { return c.Pieces }},
+		{"plan", func(c writeplan.Chunk) []string { return []string{c.Static} }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var writes [][]byte
+			for _, p := range plans {
+				for _, c := range p.Chunks {
+					switch c.Kind {
+					case writeplan.Static:
+						for _, s := range bench.writes(c) {
+							writes = append(writes, []byte(s))
+						}
+					case writeplan.Branch:
+					default:
+						writes = append(writes, []byte("dynamic"))
+					}
+				}
+			}
+			var w io.Writer = &lockedWriter{}
+			b.ReportMetric(float64(len(writes)), "writes/op")
+			for range b.N {
+				for _, p := range writes {
+					w.Write(p)
+				}
+			}
+		})
+	}
+}